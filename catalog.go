@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The season catalog records the games each season page lists, so targeted
+// scrapes can find a game's season, show number and air date without
+// fetching every season page again. Past seasons do not change and are read
+// from season_games; the current season is fetched again on every run.
+
+func (s *sqliteStore) Catalog() (map[string][]catalogGame, error) {
+	rows, err := s.db.Query(`
+		SELECT game_id, season_id, COALESCE(show_num, 0), COALESCE(air_date, '')
+		FROM season_games ORDER BY season_id, game_id;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	catalog := make(map[string][]catalogGame)
+	for rows.Next() {
+		var game catalogGame
+		if err := rows.Scan(&game.GameID, &game.SeasonID, &game.ShowNum, &game.AirDate); err != nil {
+			return nil, err
+		}
+		catalog[game.SeasonID] = append(catalog[game.SeasonID], game)
+	}
+	return catalog, rows.Err()
+}
+
+func (s *sqliteStore) SaveCatalogSeason(seasonID string, games []catalogGame) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM season_games WHERE season_id = ?;`, seasonID); err != nil {
+		return err
+	}
+	insert, err := tx.Prepare(`
+		INSERT OR REPLACE INTO season_games (game_id, season_id, show_num, air_date, listed_at)
+		VALUES (?, ?, ?, ?, ?);`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	listedAt := time.Now().UTC()
+	for _, game := range games {
+		if _, err := insert.Exec(game.GameID, seasonID, nullInt(game.ShowNum), nullString(game.AirDate), listedAt); err != nil {
+			return fmt.Errorf("failed to insert game %d into season_games table: %v", game.GameID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// newestFirst orders season IDs with the numbered seasons first, newest
+// first, followed by the special seasons in their original order
+func newestFirst(seasons []string) []string {
+	ordered := append([]string(nil), seasons...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, aErr := strconv.Atoi(ordered[i])
+		b, bErr := strconv.Atoi(ordered[j])
+		if aErr == nil && bErr == nil {
+			return a > b
+		}
+		return aErr == nil && bErr != nil
+	})
+	return ordered
+}
+
+// seasonCatalog reads season listings from the store, fetching the seasons
+// it does not have
+type seasonCatalog struct {
+	store   PipelineStore
+	pages   *fetcher
+	seasons []string // newest first
+	current string   // the season J-Archive is airing, which keeps gaining games
+	games   map[string][]catalogGame
+	fetched map[string]bool
+}
+
+func newSeasonCatalog(store PipelineStore, pages *fetcher, seasons []string, current string) (*seasonCatalog, error) {
+	games, err := store.Catalog()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read season catalog: %v", err)
+	}
+	return &seasonCatalog{
+		store:   store,
+		pages:   pages,
+		seasons: newestFirst(seasons),
+		current: current,
+		games:   games,
+		fetched: make(map[string]bool),
+	}, nil
+}
+
+// fetch reads a season page and records its games in the catalog
+func (c *seasonCatalog) fetch(ctx context.Context, seasonID string) ([]catalogGame, error) {
+	seasonHTML, err := c.pages.Season(ctx, seasonID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch game list for season %s: %v", seasonID, err)
+	}
+	games, err := GetSeasonCatalog(seasonID, seasonHTML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse game list for season %s: %v", seasonID, err)
+	}
+	if err := c.store.SaveCatalogSeason(seasonID, games); err != nil {
+		return nil, fmt.Errorf("failed to save game list for season %s: %v", seasonID, err)
+	}
+	c.games[seasonID] = games
+	c.fetched[seasonID] = true
+	return games, nil
+}
+
+// load makes sure the catalog covers every season, newest first, fetching
+// the current season and any season it has never seen, or every season if
+// refresh is set. It stops early once done reports true or the context is
+// cancelled. A season that cannot be fetched is logged and skipped.
+func (c *seasonCatalog) load(ctx context.Context, refresh bool, done func() bool) {
+	for _, seasonID := range c.seasons {
+		if ctx.Err() != nil || (done != nil && done()) {
+			return
+		}
+		_, known := c.games[seasonID]
+		if c.fetched[seasonID] || (known && !refresh && seasonID != c.current) {
+			continue
+		}
+		if _, err := c.fetch(ctx, seasonID); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// all returns every catalogued game of the catalog's seasons
+func (c *seasonCatalog) all() []catalogGame {
+	var games []catalogGame
+	for _, seasonID := range c.seasons {
+		games = append(games, c.games[seasonID]...)
+	}
+	return games
+}
+
+// intList is a flag holding comma-separated integers; it may be repeated
+type intList []int
+
+func (list *intList) String() string {
+	parts := make([]string, len(*list))
+	for i, value := range *list {
+		parts[i] = strconv.Itoa(value)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (list *intList) Set(text string) error {
+	for _, part := range strings.Split(text, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return fmt.Errorf("invalid number %q", part)
+		}
+		*list = append(*list, value)
+	}
+	return nil
+}
+
+// scrapeTargets picks games to scrape instead of whole seasons. A game is
+// scraped if any target selects it.
+type scrapeTargets struct {
+	GameIDs intList
+	Shows   string // show number or range, e.g. 8000-8010
+	Since   string // air date, YYYY-MM-DD, inclusive
+	Until   string
+	Latest  int
+
+	showFrom, showTo int
+}
+
+// register adds the target flags to the scrape command
+func (t *scrapeTargets) register(flags *flag.FlagSet) {
+	flags.Var(&t.GameIDs, "game", "J-Archive game IDs to scrape, comma-separated (repeatable)")
+	flags.StringVar(&t.Shows, "shows", "", "show number or range to scrape, e.g. 8000-8010")
+	flags.StringVar(&t.Since, "since", "", "scrape games aired on or after this date (YYYY-MM-DD)")
+	flags.StringVar(&t.Until, "until", "", "scrape games aired on or before this date (YYYY-MM-DD)")
+	flags.IntVar(&t.Latest, "latest", 0, "scrape the N most recently aired games")
+}
+
+// empty reports whether no target was given, meaning whole seasons are scraped
+func (t scrapeTargets) empty() bool {
+	return len(t.GameIDs) == 0 && t.Shows == "" && t.Since == "" && t.Until == "" && t.Latest == 0
+}
+
+// validate parses the show range and checks the dates
+func (t *scrapeTargets) validate() error {
+	if t.Shows != "" {
+		from, to, isRange := strings.Cut(t.Shows, "-")
+		var err error
+		if t.showFrom, err = strconv.Atoi(strings.TrimSpace(from)); err != nil {
+			return fmt.Errorf("invalid show number %q", from)
+		}
+		t.showTo = t.showFrom
+		if isRange {
+			if t.showTo, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+				return fmt.Errorf("invalid show number %q", to)
+			}
+		}
+		if t.showTo < t.showFrom {
+			return fmt.Errorf("show range %s ends before it starts", t.Shows)
+		}
+	}
+	for _, date := range []string{t.Since, t.Until} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+		}
+	}
+	if t.Latest < 0 {
+		return fmt.Errorf("-latest must be positive")
+	}
+	return nil
+}
+
+// selects reports whether the show or air date targets select a game
+func (t scrapeTargets) selects(game catalogGame) bool {
+	if t.Shows != "" && game.ShowNum >= t.showFrom && game.ShowNum <= t.showTo {
+		return true
+	}
+	if (t.Since != "" || t.Until != "") && game.AirDate != "" &&
+		(t.Since == "" || game.AirDate >= t.Since) && (t.Until == "" || game.AirDate <= t.Until) {
+		return true
+	}
+	return false
+}
+
+// resolveTargets turns targets into the games to scrape, using the catalog
+// and fetching the season pages it lacks. Game IDs that no season lists are
+// returned as missing.
+func resolveTargets(ctx context.Context, catalog *seasonCatalog, targets scrapeTargets, refresh bool) (selected []catalogGame, missing []int) {
+	wanted := make(map[int]bool)
+	for _, gameID := range targets.GameIDs {
+		wanted[gameID] = true
+	}
+	listed := func() map[int]catalogGame {
+		byID := make(map[int]catalogGame)
+		for _, game := range catalog.all() {
+			byID[game.GameID] = game
+		}
+		return byID
+	}
+
+	if len(targets.GameIDs) > 0 && targets.Shows == "" && targets.Since == "" && targets.Until == "" && targets.Latest == 0 {
+		// Game IDs alone only need the seasons up to the one listing the last
+		// of them; when refreshing, the listing must be fetched by this run
+		catalog.load(ctx, refresh, func() bool {
+			byID := listed()
+			for gameID := range wanted {
+				game, ok := byID[gameID]
+				if !ok || (refresh && !catalog.fetched[game.SeasonID]) {
+					return false
+				}
+			}
+			return true
+		})
+	} else {
+		catalog.load(ctx, refresh, nil)
+	}
+
+	chosen := make(map[int]bool)
+	byID := listed()
+	for _, gameID := range targets.GameIDs {
+		if game, ok := byID[gameID]; ok {
+			chosen[game.GameID] = true
+		} else {
+			missing = append(missing, gameID)
+		}
+	}
+
+	games := catalog.all()
+	for _, game := range games {
+		if targets.selects(game) {
+			chosen[game.GameID] = true
+		}
+	}
+
+	if targets.Latest > 0 {
+		var aired []catalogGame
+		for _, game := range games {
+			if game.AirDate != "" {
+				aired = append(aired, game)
+			}
+		}
+		sort.Slice(aired, func(i, j int) bool {
+			if aired[i].AirDate != aired[j].AirDate {
+				return aired[i].AirDate > aired[j].AirDate
+			}
+			return aired[i].GameID > aired[j].GameID
+		})
+		for i := 0; i < targets.Latest && i < len(aired); i++ {
+			chosen[aired[i].GameID] = true
+		}
+	}
+
+	for gameID := range chosen {
+		selected = append(selected, byID[gameID])
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		if selected[i].AirDate != selected[j].AirDate {
+			return selected[i].AirDate < selected[j].AirDate
+		}
+		return selected[i].GameID < selected[j].GameID
+	})
+	return selected, missing
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestNewestFirst(t *testing.T) {
+	got := newestFirst([]string{"9", "superjeopardy", "40", "10", "trebekpilots"})
+	want := []string{"40", "10", "9", "superjeopardy", "trebekpilots"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newestFirst = %v, want %v", got, want)
+	}
+}
+
+func TestIntListSet(t *testing.T) {
+	var list intList
+	if err := list.Set("1, 2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Set("3"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(list, intList{1, 2, 3}) || list.String() != "1,2,3" {
+		t.Errorf("list = %v", list)
+	}
+	if err := list.Set("4,x"); err == nil {
+		t.Error("Set accepted a non-number")
+	}
+}
+
+func TestScrapeTargetsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		targets  scrapeTargets
+		from, to int
+		wantErr  bool
+	}{
+		{"single show", scrapeTargets{Shows: "8000"}, 8000, 8000, false},
+		{"show range", scrapeTargets{Shows: "8000 - 8010"}, 8000, 8010, false},
+		{"reversed range", scrapeTargets{Shows: "8010-8000"}, 0, 0, true},
+		{"not a show", scrapeTargets{Shows: "latest"}, 0, 0, true},
+		{"dates", scrapeTargets{Since: "2024-01-01", Until: "2024-12-31"}, 0, 0, false},
+		{"bad date", scrapeTargets{Since: "01/01/2024"}, 0, 0, true},
+		{"negative latest", scrapeTargets{Latest: -1}, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.targets.validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validate() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && (tt.targets.showFrom != tt.from || tt.targets.showTo != tt.to) {
+				t.Errorf("show range = %d-%d, want %d-%d", tt.targets.showFrom, tt.targets.showTo, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+// catalogSeasons are listed by the test archive; season 34 is also in the
+// store's catalog, as if an earlier run had fetched it
+var catalogSeasons = map[string][]testGame{
+	"36": {{ID: 7, ShowNum: 8101, AirDate: "2020-01-02"}, {ID: 8, ShowNum: 8102, AirDate: "2020-01-03"}},
+	"35": {{ID: 4, ShowNum: 8001, AirDate: "2019-05-01"}, {ID: 5, ShowNum: 8002, AirDate: "2019-05-02"}},
+	"34": {{ID: 1, ShowNum: 7901, AirDate: "2018-05-01"}, {ID: 2, ShowNum: 7902, AirDate: "2018-05-02"}},
+}
+
+func TestResolveTargets(t *testing.T) {
+	tests := []struct {
+		name        string
+		targets     scrapeTargets
+		refresh     bool
+		want        []int
+		missing     []int
+		wantFetched []string // season pages requested
+	}{
+		{"game in the current season", scrapeTargets{GameIDs: intList{8}}, false, []int{8}, nil, []string{"36"}},
+		{"game ids stop at the season listing the last of them", scrapeTargets{GameIDs: intList{5, 7}}, false, []int{5, 7}, nil, []string{"36", "35"}},
+		{"catalogued game needs no season page", scrapeTargets{GameIDs: intList{1}}, false, []int{1}, nil, nil},
+		{"catalogued season is not fetched again", scrapeTargets{Shows: "7901"}, false, []int{1}, nil, []string{"36", "35"}},
+		{"unlisted game", scrapeTargets{GameIDs: intList{99, 2}}, false, []int{2}, []int{99}, []string{"36", "35"}},
+		{"show range across seasons", scrapeTargets{Shows: "8002-8101"}, false, []int{5, 7}, nil, []string{"36", "35"}},
+		{"air dates", scrapeTargets{Since: "2018-05-02", Until: "2019-05-01"}, false, []int{2, 4}, nil, []string{"36", "35"}},
+		{"latest", scrapeTargets{Latest: 3}, false, []int{5, 7, 8}, nil, []string{"36", "35"}},
+		{"targets combine", scrapeTargets{GameIDs: intList{1}, Latest: 1}, false, []int{1, 8}, nil, []string{"36", "35"}},
+		{"refresh fetches every season", scrapeTargets{GameIDs: intList{1}}, true, []int{1}, nil, []string{"36", "35", "34"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, server := newTestArchive(t, []string{"36", "35", "34"}, catalogSeasons)
+			store := newMemoryStore()
+			var catalogued []catalogGame
+			for _, game := range catalogSeasons["34"] {
+				catalogued = append(catalogued, catalogGame{GameID: game.ID, SeasonID: "34", ShowNum: game.ShowNum, AirDate: game.AirDate})
+			}
+			if err := store.SaveCatalogSeason("34", catalogued); err != nil {
+				t.Fatal(err)
+			}
+			catalog, err := newSeasonCatalog(store, newFetcher(testConfig(t, server), nil), []string{"34", "35", "36"}, "36")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.targets.validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			selected, missing := resolveTargets(context.Background(), catalog, tt.targets, tt.refresh)
+
+			var got []int
+			for _, game := range selected {
+				got = append(got, game.GameID)
+			}
+			if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(missing, tt.missing) {
+				t.Errorf("resolveTargets = %v missing %v, want %v missing %v", got, missing, tt.want, tt.missing)
+			}
+			var fetched []string
+			for _, seasonID := range []string{"36", "35", "34"} {
+				if archive.requests("/showseason.php?season="+seasonID) > 0 {
+					fetched = append(fetched, seasonID)
+				}
+			}
+			if !reflect.DeepEqual(fetched, tt.wantFetched) {
+				t.Errorf("fetched seasons %v, want %v", fetched, tt.wantFetched)
+			}
+			// Fetched seasons are saved to the store's catalog
+			stored, _ := store.Catalog()
+			for _, seasonID := range fetched {
+				if len(stored[seasonID]) != 2 {
+					t.Errorf("season %s catalog = %+v", seasonID, stored[seasonID])
+				}
+			}
+		})
+	}
+}
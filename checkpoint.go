@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Scrape progress is checkpointed in the database. A game is complete once
+// its rows are committed to the games table, and a season once every game
+// its page lists is stored, which is recorded in completed_seasons. The
+// processing state file only mirrors that progress for people reading it.
+// scrapeGames holds a season's games in memory until every one has been
+// fetched and parsed, or the run is interrupted, and then writes them
+// gamesPerCommit to a transaction. A crash therefore loses the games of the
+// season in progress that were not committed yet; their pages are already
+// cached, so the next run parses them again without fetching.
+
+func (s *sqliteStore) CompleteSeason(seasonID string, games int) error {
+	_, err := s.db.Exec(`
+		INSERT INTO completed_seasons (season_id, games, completed_at) VALUES (?, ?, ?)
+		ON CONFLICT (season_id) DO UPDATE SET games = excluded.games, completed_at = excluded.completed_at;`,
+		seasonID, games, time.Now().UTC())
+	return err
+}
+
+func (s *sqliteStore) CompletedSeasons() (map[string]bool, error) {
+	rows, err := s.db.Query(`SELECT season_id FROM completed_seasons;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	completed := make(map[string]bool)
+	for rows.Next() {
+		var seasonID string
+		if err := rows.Scan(&seasonID); err != nil {
+			return nil, err
+		}
+		completed[seasonID] = true
+	}
+	return completed, rows.Err()
+}
+
+// writeFileAtomic replaces filename with data so readers, and the next run
+// after a crash, see either the old contents or the new ones in full
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, "."+base+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Removing fails harmlessly once the rename has happened
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// runLock is a lock file holding the process ID of the run that owns it
+type runLock struct {
+	path string
+}
+
+// acquireRunLock creates the lock file, failing if a running process holds
+// it. A lock left behind by a process that has exited is taken over.
+func acquireRunLock(path string) (*runLock, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(file, "%d\n", os.Getpid())
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			return &runLock{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		pid, _ := strconv.Atoi(strings.TrimSpace(string(content)))
+		if pid > 0 && processRunning(pid) {
+			return nil, fmt.Errorf("another run (process %d) holds %s; remove it if that process is not answer-there", pid, path)
+		}
+		log.Printf("Removing stale lock %s left by process %d", path, pid)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("could not acquire %s", path)
+}
+
+// processRunning reports whether a process with the ID exists
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
+
+// Release removes the lock file
+func (l *runLock) Release() {
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove lock %s: %v", l.path, err)
+	}
+}
+
+// lockRun keeps other runs from writing to the database and state file
+// until the returned lock is released
+func lockRun(cfg Config) *runLock {
+	lock, err := acquireRunLock(cfg.DB + ".lock")
+	if err != nil {
+		log.Fatalf("Cannot start: %v", err)
+	}
+	return lock
+}
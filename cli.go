@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// command is one subcommand of the binary
+type command struct {
+	Name    string
+	Summary string
+	Run     func(ctx context.Context, cfg Config, args []string)
+}
+
+// commands lists the subcommands in the order the usage message shows them
+var commands = []command{
+	{"scrape", "fetch seasons from J-Archive and store their games", runScrape},
+	{"retry-failed", "scrape the games that failed again", runRetryFailed},
+	{"reparse", "re-parse cached pages written by an older parser", func(ctx context.Context, cfg Config, args []string) {
+		lock := lockRun(cfg)
+		defer lock.Release()
+		store := openStore(cfg)
+		defer store.Close()
+		run := startRunLedger(store, "reparse", args)
+		defer run.finish(ctx)
+		runReparse(ctx, store, cfg.DataDir, run)
+	}},
+	{"search", "full-text search over clues and responses", func(ctx context.Context, cfg Config, args []string) {
+		store := openStore(cfg)
+		defer store.Close()
+		runSearch(store.db, args)
+	}},
+	{"stats", "summarize what the database holds", func(ctx context.Context, cfg Config, args []string) {
+		store := openStore(cfg)
+		defer store.Close()
+		runStats(store.db, args)
+	}},
+	{"quiz", "play clues from random categories", func(ctx context.Context, cfg Config, args []string) {
+		store := openStore(cfg)
+		defer store.Close()
+		runQuiz(store, args)
+	}},
+	{"serve", "serve the database as a read-only JSON API", func(ctx context.Context, cfg Config, args []string) {
+		store := openStore(cfg)
+		defer store.Close()
+		runServe(ctx, store, args)
+	}},
+	{"export", "export the database as CSV, JSON Lines or Parquet", func(ctx context.Context, cfg Config, args []string) {
+		runExport(cfg, args)
+	}},
+	{"import", "compare a community dataset with the scraped data", func(ctx context.Context, cfg Config, args []string) {
+		// Reports only read earlier imports
+		if len(args) == 0 || args[0] != "report" {
+			lock := lockRun(cfg)
+			defer lock.Release()
+		}
+		store := openStore(cfg)
+		defer store.Close()
+		runImport(ctx, store, args)
+	}},
+	{"doctor", "check the database and cache for problems", func(ctx context.Context, cfg Config, args []string) {
+		store := openStore(cfg)
+		defer store.Close()
+		runDoctorCommand(store.db, cfg.DataDir, args)
+	}},
+	{"history", "show the recorded changes to a game's clues", func(ctx context.Context, cfg Config, args []string) {
+		store := openStore(cfg)
+		defer store.Close()
+		runHistory(store, args)
+	}},
+	{"runs", "list the recorded runs of commands that change the dataset", func(ctx context.Context, cfg Config, args []string) {
+		store := openStore(cfg)
+		defer store.Close()
+		runRuns(store.db, args)
+	}},
+	// repair holds the lock even for a dry run, which reads the legacy tables
+	// a concurrent repair drops
+	{"repair", "rebuild games left in the legacy tables", func(ctx context.Context, cfg Config, args []string) {
+		lock := lockRun(cfg)
+		defer lock.Release()
+		store := openStore(cfg)
+		defer store.Close()
+		runRepair(ctx, store, args)
+	}},
+	// migrate opens the database without migrating it first, so pending
+	// migrations can be listed before they are applied
+	{"migrate", "apply or list schema migrations", func(ctx context.Context, cfg Config, args []string) {
+		db, err := openDatabase(cfg.DB)
+		if err != nil {
+			log.Fatalf("Failed to open SQLite database: %v", err)
+		}
+		defer db.Close()
+		runMigrate(db, args)
+	}},
+}
+
+// openStore opens the configured database, bringing its schema up to date
+func openStore(cfg Config) *sqliteStore {
+	store, err := newSQLiteStore(cfg.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return store
+}
+
+// printUsage lists the global flags and commands
+func printUsage(flags *flag.FlagSet) {
+	out := flags.Output()
+	fmt.Fprintf(out, "Usage: answer-there [global flags] <command> [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", c.Name, c.Summary)
+	}
+	fmt.Fprintf(out, "\nGlobal flags:\n")
+	flags.PrintDefaults()
+	fmt.Fprintf(out, "\nEvery global flag can also be set in the config file or with an %s* environment variable,\n"+
+		"e.g. %sDB or %sREQUEST_DELAY. Run answer-there <command> -h for a command's flags.\n", envPrefix, envPrefix, envPrefix)
+}
+
+// runCommand runs the command named by the first argument left after the
+// global flags
+func runCommand(ctx context.Context, cfg Config, flags *flag.FlagSet) {
+	args := flags.Args()
+	if len(args) == 0 || args[0] == "help" {
+		printUsage(flags)
+		if len(args) == 0 {
+			os.Exit(2)
+		}
+		return
+	}
+	for _, c := range commands {
+		if c.Name == args[0] {
+			c.Run(ctx, cfg, args[1:])
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", args[0])
+	printUsage(flags)
+	os.Exit(2)
+}
+
+// shutdownContext returns a context that is cancelled on the first SIGINT or
+// SIGTERM, so commands can finish the work in progress, save their state
+// and close the database. A second signal exits at once.
+func shutdownContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			log.Printf("Received %v, stopping after the work in progress; send it again to exit immediately", sig)
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+		<-signals
+		log.Printf("Exiting immediately")
+		os.Exit(130)
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Settings are resolved in order of precedence: command-line flags, then
+// ANSWER_THERE_* environment variables, then the config file, then the
+// defaults below.
+
+// defaultConfigFile is read when it exists and no other config file is given
+const defaultConfigFile = "answer-there.json"
+
+// envPrefix prefixes the environment variables that override settings
+const envPrefix = "ANSWER_THERE_"
+
+// Config holds the settings shared by every command
+type Config struct {
+	// DB is the SQLite database file
+	DB string `json:"db"`
+	// DataDir holds the cached J-Archive pages
+	DataDir string `json:"data_dir"`
+	// StateFile records scraping progress so an interrupted scrape can resume
+	StateFile string `json:"state_file"`
+	// SeasonsFile optionally lists the seasons to scrape, one per line
+	SeasonsFile string `json:"seasons_file"`
+	// Concurrency is how many games are fetched and parsed at once
+	Concurrency int `json:"concurrency"`
+	// RequestDelay is the minimum time between requests to J-Archive
+	RequestDelay duration `json:"request_delay"`
+	// SeasonDelay is an extra pause between seasons
+	SeasonDelay duration `json:"season_delay"`
+	// BaseURL is where J-Archive is served from
+	BaseURL string `json:"base_url"`
+}
+
+// duration is a time.Duration written as a string such as "1.5s" in the config file
+type duration time.Duration
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string such as \"2s\": %v", err)
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// String and Set let a duration be used as a flag
+func (d *duration) String() string {
+	return time.Duration(*d).String()
+}
+
+func (d *duration) Set(text string) error {
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// defaultConfig returns the settings used when nothing overrides them
+func defaultConfig() Config {
+	return Config{
+		DB:           "jeopardy.db",
+		DataDir:      "data",
+		StateFile:    "processing_state.json",
+		SeasonsFile:  "seasons.txt",
+		Concurrency:  5,
+		RequestDelay: duration(time.Second),
+		SeasonDelay:  duration(2 * time.Second),
+		BaseURL:      "https://j-archive.com",
+	}
+}
+
+// loadConfigFile overlays the settings in a JSON config file. A missing file
+// is only an error when it was asked for explicitly.
+func loadConfigFile(cfg *Config, path string, explicit bool) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// applyEnv overlays the ANSWER_THERE_* environment variables
+func applyEnv(cfg *Config, getenv func(string) string) error {
+	texts := map[string]*string{
+		"DB":           &cfg.DB,
+		"DATA_DIR":     &cfg.DataDir,
+		"STATE_FILE":   &cfg.StateFile,
+		"SEASONS_FILE": &cfg.SeasonsFile,
+		"BASE_URL":     &cfg.BaseURL,
+	}
+	for name, setting := range texts {
+		if value := getenv(envPrefix + name); value != "" {
+			*setting = value
+		}
+	}
+
+	if value := getenv(envPrefix + "CONCURRENCY"); value != "" {
+		concurrency, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %sCONCURRENCY %q", envPrefix, value)
+		}
+		cfg.Concurrency = concurrency
+	}
+	durations := map[string]*duration{
+		"REQUEST_DELAY": &cfg.RequestDelay,
+		"SEASON_DELAY":  &cfg.SeasonDelay,
+	}
+	for name, setting := range durations {
+		if value := getenv(envPrefix + name); value != "" {
+			if err := setting.Set(value); err != nil {
+				return fmt.Errorf("invalid %s%s %q: %v", envPrefix, name, value, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validate rejects settings no command can work with
+func (cfg Config) validate() error {
+	if cfg.Concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got %d", cfg.Concurrency)
+	}
+	if cfg.RequestDelay < 0 || cfg.SeasonDelay < 0 {
+		return fmt.Errorf("delays cannot be negative")
+	}
+	if cfg.DB == "" {
+		return fmt.Errorf("no database file set")
+	}
+	return nil
+}
+
+// parseGlobalFlags resolves the configuration from the global flags, which
+// come before the command name. The command and its arguments are left in
+// the returned flag set's Args.
+func parseGlobalFlags(args []string, getenv func(string) string) (Config, *flag.FlagSet, error) {
+	flags := flag.NewFlagSet("answer-there", flag.ExitOnError)
+	flags.Usage = func() { printUsage(flags) }
+	configPath := flags.String("config", "", "JSON config file (default "+defaultConfigFile+" if present, or $"+envPrefix+"CONFIG)")
+
+	// Flags are parsed into a separate Config so that only the flags actually
+	// given override the config file and environment
+	var given Config
+	flags.StringVar(&given.DB, "db", "", "SQLite database file (default jeopardy.db)")
+	flags.StringVar(&given.DataDir, "data", "", "directory for cached pages (default data)")
+	flags.StringVar(&given.StateFile, "state", "", "scrape progress file (default processing_state.json)")
+	flags.StringVar(&given.SeasonsFile, "seasons", "", "file listing seasons to scrape (default seasons.txt)")
+	flags.IntVar(&given.Concurrency, "concurrency", 0, "games fetched at once (default 5)")
+	flags.Var(&given.RequestDelay, "request-delay", "minimum time between requests to J-Archive (default 1s)")
+	flags.Var(&given.SeasonDelay, "season-delay", "pause between seasons (default 2s)")
+	flags.StringVar(&given.BaseURL, "base-url", "", "J-Archive base URL (default https://j-archive.com)")
+	flags.Parse(args)
+
+	cfg := defaultConfig()
+	path, explicit := *configPath, *configPath != ""
+	if !explicit {
+		if path = getenv(envPrefix + "CONFIG"); path != "" {
+			explicit = true
+		} else {
+			path = defaultConfigFile
+		}
+	}
+	if err := loadConfigFile(&cfg, path, explicit); err != nil {
+		return cfg, flags, err
+	}
+	if err := applyEnv(&cfg, getenv); err != nil {
+		return cfg, flags, err
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "db":
+			cfg.DB = given.DB
+		case "data":
+			cfg.DataDir = given.DataDir
+		case "state":
+			cfg.StateFile = given.StateFile
+		case "seasons":
+			cfg.SeasonsFile = given.SeasonsFile
+		case "concurrency":
+			cfg.Concurrency = given.Concurrency
+		case "request-delay":
+			cfg.RequestDelay = given.RequestDelay
+		case "season-delay":
+			cfg.SeasonDelay = given.SeasonDelay
+		case "base-url":
+			cfg.BaseURL = given.BaseURL
+		}
+	})
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return cfg, flags, cfg.validate()
+}
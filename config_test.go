@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseGlobalFlags(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	configFile := writeConfig("config.json", `{"db": "file.db", "data_dir": "file-data", "concurrency": 3, "request_delay": "500ms"}`)
+	unknownField := writeConfig("unknown.json", `{"database": "file.db"}`)
+	badDuration := writeConfig("duration.json", `{"season_delay": 5}`)
+	noWorkers := writeConfig("workers.json", `{"concurrency": 0}`)
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		want    func(cfg *Config)
+		wantErr bool
+	}{
+		{name: "defaults", args: []string{"stats"}},
+		{name: "file over default", args: []string{"-config", configFile, "stats"}, want: func(cfg *Config) {
+			cfg.DB, cfg.DataDir, cfg.Concurrency, cfg.RequestDelay = "file.db", "file-data", 3, duration(500*time.Millisecond)
+		}},
+		{name: "file from env", args: []string{"stats"}, env: map[string]string{"ANSWER_THERE_CONFIG": configFile}, want: func(cfg *Config) {
+			cfg.DB, cfg.DataDir, cfg.Concurrency, cfg.RequestDelay = "file.db", "file-data", 3, duration(500*time.Millisecond)
+		}},
+		{name: "env over file", args: []string{"-config", configFile, "stats"},
+			env: map[string]string{"ANSWER_THERE_DB": "env.db", "ANSWER_THERE_CONCURRENCY": "4", "ANSWER_THERE_SEASON_DELAY": "1m"},
+			want: func(cfg *Config) {
+				cfg.DB, cfg.DataDir, cfg.Concurrency, cfg.RequestDelay = "env.db", "file-data", 4, duration(500*time.Millisecond)
+				cfg.SeasonDelay = duration(time.Minute)
+			}},
+		{name: "flag over env", args: []string{"-config", configFile, "-db", "flag.db", "-concurrency", "8", "stats"},
+			env: map[string]string{"ANSWER_THERE_DB": "env.db", "ANSWER_THERE_CONCURRENCY": "4"},
+			want: func(cfg *Config) {
+				cfg.DB, cfg.DataDir, cfg.Concurrency, cfg.RequestDelay = "flag.db", "file-data", 8, duration(500*time.Millisecond)
+			}},
+		{name: "base URL slash trimmed", args: []string{"-base-url", "http://localhost:8080/", "stats"}, want: func(cfg *Config) {
+			cfg.BaseURL = "http://localhost:8080"
+		}},
+		{name: "missing config file", args: []string{"-config", filepath.Join(dir, "missing.json"), "stats"}, wantErr: true},
+		{name: "missing config file from env", args: []string{"stats"},
+			env: map[string]string{"ANSWER_THERE_CONFIG": filepath.Join(dir, "missing.json")}, wantErr: true},
+		{name: "unknown field", args: []string{"-config", unknownField, "stats"}, wantErr: true},
+		{name: "duration not a string", args: []string{"-config", badDuration, "stats"}, wantErr: true},
+		{name: "invalid env number", args: []string{"stats"}, env: map[string]string{"ANSWER_THERE_CONCURRENCY": "many"}, wantErr: true},
+		{name: "invalid env duration", args: []string{"stats"}, env: map[string]string{"ANSWER_THERE_REQUEST_DELAY": "soon"}, wantErr: true},
+		{name: "no workers", args: []string{"-config", noWorkers, "stats"}, wantErr: true},
+		{name: "negative delay", args: []string{"-request-delay", "-1s", "stats"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(name string) string { return tt.env[name] }
+			cfg, flags, err := parseGlobalFlags(tt.args, getenv)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseGlobalFlags(%q) error = %v, want error %v", tt.args, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			want := defaultConfig()
+			if tt.want != nil {
+				tt.want(&want)
+			}
+			if !reflect.DeepEqual(cfg, want) {
+				t.Errorf("parseGlobalFlags(%q) = %+v, want %+v", tt.args, cfg, want)
+			}
+			if got := flags.Args(); !reflect.DeepEqual(got, []string{"stats"}) {
+				t.Errorf("command args = %q, want [stats]", got)
+			}
+		})
+	}
+}
@@ -2,247 +2,529 @@ package main
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
-	"math/rand"
-	"time"
+	"strings"
 )
 
-func writeGameList(dbName string, season SeasonData) {
-	db, err := sql.Open("sqlite3", dbName)
+// gamesPerCommit is how many games are written in each transaction. Every
+// game is still all-or-nothing because it is written inside its own savepoint.
+const gamesPerCommit = 50
+
+// openDatabase opens the SQLite database in WAL mode so readers are not
+// blocked while a crawl is writing
+func openDatabase(dbName string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", dbName+"?_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=5000&_foreign_keys=on")
 	if err != nil {
-		log.Fatalf("Failed to open SQLite database: %v", err)
-	}
-	defer db.Close()
-
-	// Create the `gamelist` table if it doesn't exist
-	createGameListTableSQL := `
-		ATTACH DATABASE ? AS game_data;
-		CREATE TABLE IF NOT EXISTS game_data.gamelist (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			season_id TEXT NOT NULL,
-			game_id INTEGER NOT NULL UNIQUE,
-			show_num INTEGER NOT NULL UNIQUE,
-			air_date DATE NOT NULL,
-			tape_date DATE NOT NULL
-		);
-	`
-	if _, err := db.Exec(createGameListTableSQL, dbName); err != nil {
-		log.Fatalf("Failed to create gamelist table: %v", err)
-	}
-
-	// Insert games into the `gamelist` table
-	insertGameSQL := `
-		INSERT OR IGNORE INTO game_data.gamelist (
-			season_id, game_id, show_num, air_date, tape_date
-		) VALUES (?, ?, ?, ?, ?);
-	`
-
-	for _, game := range season.Games {
-		_, err := db.Exec(
-			insertGameSQL,
-			season.ID,
-			game.ID,
-			game.ShowNum,
-			game.AirDate,
-			game.TapeDate,
-		)
+		return nil, err
+	}
+	// A single connection keeps transactions and savepoints on the same session
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// nullString stores an unavailable (empty) value as NULL
+func nullString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// nullInt stores an unavailable (zero) value as NULL
+func nullInt(value int) interface{} {
+	if value == 0 {
+		return nil
+	}
+	return value
+}
+
+// gameStatements are the prepared statements used to write one game
+type gameStatements struct {
+	upsertSeason        *sql.Stmt
+	upsertGame          *sql.Stmt
+	deleteRounds        *sql.Stmt
+	insertRound         *sql.Stmt
+	insertCategory      *sql.Stmt
+	insertClue          *sql.Stmt
+	insertResponse      *sql.Stmt
+	deleteFinalJeopardy *sql.Stmt
+	insertFinalJeopardy *sql.Stmt
+	upsertPlayer        *sql.Stmt
+	deleteAppearances   *sql.Stmt
+	insertAppearance    *sql.Stmt
+	deleteWarnings      *sql.Stmt
+	insertWarning       *sql.Stmt
+	deleteScoreTimeline *sql.Stmt
+	insertScoreEvent    *sql.Stmt
+	selectParserVersion *sql.Stmt
+	selectClues         *sql.Stmt
+	selectResponses     *sql.Stmt
+	selectFinalJeopardy *sql.Stmt
+	insertRevision      *sql.Stmt
+	// Only set when the full-text index exists
+	deleteSearch *sql.Stmt
+	insertSearch *sql.Stmt
+}
+
+func prepareGameStatements(tx *sql.Tx) (*gameStatements, error) {
+	var stmts gameStatements
+	for _, s := range []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&stmts.upsertSeason, `INSERT INTO seasons (season_id) VALUES (?) ON CONFLICT (season_id) DO NOTHING;`},
+		// Games are keyed by their J-Archive game_id
+		{&stmts.upsertGame, `
+			INSERT INTO games (
+				game_id, season_id, show_num, air_date, tape_date, era, format, unavailable_fields, parser_version
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (game_id) DO UPDATE SET
+				season_id = excluded.season_id,
+				show_num = excluded.show_num,
+				air_date = excluded.air_date,
+				tape_date = excluded.tape_date,
+				era = excluded.era,
+				format = excluded.format,
+				unavailable_fields = excluded.unavailable_fields,
+				parser_version = excluded.parser_version;`},
+		// Deleting rounds cascades to their categories, clues and responses
+		{&stmts.deleteRounds, `DELETE FROM rounds WHERE game_id = ?;`},
+		{&stmts.insertRound, `
+			INSERT INTO rounds (
+				round_id, game_id, round_code, name, ordinal
+			) VALUES (?, ?, ?, ?, ?);`},
+		{&stmts.insertCategory, `
+			INSERT INTO categories (
+				category_id, game_id, round_id, column_num, name, name_html
+			) VALUES (?, ?, ?, ?, ?, ?);`},
+		{&stmts.insertClue, `
+			INSERT INTO clues (
+				clue_id, game_id, round_id, category_id, position, row_num, value, order_number,
+				daily_double, wager, triple_stumper, text, text_html, correct_response, correct_response_html
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`},
+		{&stmts.insertResponse, `
+			INSERT INTO responses (
+				clue_id, seq, game_id, nickname, player_id, correct
+			) VALUES (?, ?, ?, ?, ?, ?);`},
+		{&stmts.deleteFinalJeopardy, `DELETE FROM final_jeopardy WHERE game_id = ?;`},
+		{&stmts.insertFinalJeopardy, `
+			INSERT INTO final_jeopardy (
+				game_id, round_code, seq, nickname, player_id, response, wager, correct
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?);`},
+		{&stmts.upsertPlayer, `
+			INSERT INTO players (player_id, name) VALUES (?, ?)
+			ON CONFLICT (player_id) DO UPDATE SET name = excluded.name;`},
+		{&stmts.deleteAppearances, `DELETE FROM appearances WHERE game_id = ?;`},
+		{&stmts.insertAppearance, `
+			INSERT INTO appearances (
+				game_id, seat, player_id, name, nickname, bio
+			) VALUES (?, ?, ?, ?, ?, ?);`},
+		{&stmts.deleteWarnings, `DELETE FROM parse_warnings WHERE game_id = ?;`},
+		{&stmts.insertWarning, `
+			INSERT INTO parse_warnings (
+				season_id, game_id, code, message
+			) VALUES (?, ?, ?, ?);`},
+		{&stmts.deleteScoreTimeline, `DELETE FROM score_timeline WHERE game_id = ?;`},
+		{&stmts.insertScoreEvent, `
+			INSERT INTO score_timeline (
+				game_id, seq, round, position, order_number, nickname, delta, score, scores
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`},
+		// Read back before a game is replaced to record its revisions
+		{&stmts.selectParserVersion, `SELECT parser_version FROM games WHERE game_id = ?;`},
+		{&stmts.selectClues, `
+			SELECT clue_id, COALESCE(text, ''), COALESCE(correct_response, '')
+			FROM clues WHERE game_id = ?;`},
+		{&stmts.selectResponses, `
+			SELECT clue_id, nickname, correct FROM responses
+			WHERE game_id = ? ORDER BY clue_id, seq;`},
+		{&stmts.selectFinalJeopardy, `
+			SELECT round_code, nickname, correct, COALESCE(response, ''), COALESCE(wager, 0) FROM final_jeopardy
+			WHERE game_id = ? ORDER BY round_code, seq;`},
+		{&stmts.insertRevision, `
+			INSERT INTO clue_revisions (
+				game_id, clue_id, field, old_value, new_value, source, old_parser_version, parser_version, revised_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`},
+	} {
+		stmt, err := tx.Prepare(s.query)
 		if err != nil {
-			log.Fatalf("Failed to insert game into gamelist table: %v", err)
+			return nil, fmt.Errorf("failed to prepare statement: %v", err)
 		}
+		*s.stmt = stmt
 	}
 
-	log.Println("Successfully wrote gamelist data to SQLite database")
+	searchIndexed, err := prepareSearchIndexForWrite(tx)
+	if err != nil {
+		return nil, err
+	}
+	if searchIndexed {
+		if stmts.deleteSearch, err = tx.Prepare(`DELETE FROM clue_search WHERE game_id = ?;`); err != nil {
+			return nil, fmt.Errorf("failed to prepare statement: %v", err)
+		}
+		if stmts.insertSearch, err = tx.Prepare(`
+			INSERT INTO clue_search (clue_id, game_id, text, correct_response, category)
+			VALUES (?, ?, ?, ?, ?);`); err != nil {
+			return nil, fmt.Errorf("failed to prepare statement: %v", err)
+		}
+	}
+	return &stmts, nil
 }
 
-func writeClues(dbName string, season SeasonData) {
-	db, err := sql.Open("sqlite3", dbName)
+// writeSeason writes every game in a season, committing in batches of
+// gamesPerCommit. Re-writing a game replaces all of its rows. A game that
+// fails to write is rolled back on its own and reported in the returned error.
+func writeSeason(db *sql.DB, season SeasonData) error {
+	var gameErrors []error
+
+	for start := 0; start < len(season.Games); start += gamesPerCommit {
+		end := start + gamesPerCommit
+		if end > len(season.Games) {
+			end = len(season.Games)
+		}
+
+		batchErrors, err := writeGameBatch(db, season.ID, season.Games[start:end])
+		if err != nil {
+			return err
+		}
+		gameErrors = append(gameErrors, batchErrors...)
+	}
+
+	log.Printf("Wrote %d games for season %s to SQLite database", len(season.Games)-len(gameErrors), season.ID)
+	return errors.Join(gameErrors...)
+}
+
+// writeGameBatch writes games in a single transaction with one savepoint per game
+func writeGameBatch(db *sql.DB, seasonID string, games []GameData) ([]error, error) {
+	tx, err := db.Begin()
 	if err != nil {
-		log.Fatalf("Failed to open SQLite database: %v", err)
-	}
-	defer db.Close()
-
-	// Create the schema and table if it doesn't exist
-	createSchemaSQL := `
-		ATTACH DATABASE ? AS game_data;
-		CREATE TABLE IF NOT EXISTS game_data.clues (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			season_id TEXT NOT NULL,
-			game_id INTEGER NOT NULL,
-			round_name TEXT NOT NULL,
-			category TEXT NOT NULL,
-			position TEXT,
-			value TEXT,
-			order_number INTEGER,
-			text TEXT NOT NULL,
-			correct_response TEXT,
-			correct_contestant TEXT
-		);
-	`
-	if _, err := db.Exec(createSchemaSQL, dbName); err != nil {
-		log.Fatalf("Failed to create schema or table: %v", err)
-	}
-
-	// Insert clues into the table
-	insertClueSQL := `
-		INSERT INTO game_data.clues (
-			season_id, game_id, round_name, category, position, value, order_number, text, correct_response, correct_contestant
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
-	`
-
-	for _, game := range season.Games {
-		for _, round := range game.Rounds {
-			numCategories := len(round.Categories)
-			if numCategories == 0 {
-				log.Println("No categories found for round:", round.Name)
-				continue
-			}
+		return nil, err
+	}
+	defer tx.Rollback()
 
-			for clueIndex, clue := range round.Clues {
-				categoryIndex := clueIndex % numCategories // Assign clue to the correct category
-				category := round.Categories[categoryIndex]
-
-				_, err := db.Exec(
-					insertClueSQL,
-					season.ID,
-					game.ID,
-					round.Name,
-					category,
-					clue.Position,
-					clue.Value,
-					clue.OrderNumber,
-					clue.Text,
-					clue.CorrectResponse,
-					clue.CorrectContestant,
-				)
-				if err != nil {
-					log.Fatalf("Failed to insert clue into database: %v", err)
-				}
+	stmts, err := prepareGameStatements(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var gameErrors []error
+	for _, game := range games {
+		if _, err := tx.Exec(`SAVEPOINT game;`); err != nil {
+			return nil, err
+		}
+		if err := writeGame(stmts, seasonID, game); err != nil {
+			if _, rollbackErr := tx.Exec(`ROLLBACK TO game;`); rollbackErr != nil {
+				return nil, rollbackErr
 			}
+			gameErrors = append(gameErrors, &gameError{GameID: game.ID, SeasonID: seasonID, Stage: failureStore, Err: err})
+		}
+		if _, err := tx.Exec(`RELEASE game;`); err != nil {
+			return nil, err
 		}
 	}
 
-	log.Println("Successfully wrote clues to SQLite database")
+	return gameErrors, tx.Commit()
+}
+
+// writeGame replaces every row belonging to a game
+func writeGame(stmts *gameStatements, seasonID string, game GameData) error {
+	// Revisions are read before the game row records the new parser version
+	if err := writeRevisions(stmts, game); err != nil {
+		return err
+	}
+	if err := writeGameRow(stmts, seasonID, game); err != nil {
+		return err
+	}
+	// Players go first so responses can reference them
+	if err := writeContestants(stmts, game); err != nil {
+		return err
+	}
+	if err := writeRounds(stmts, game); err != nil {
+		return err
+	}
+	if err := writeScoreTimeline(stmts, game); err != nil {
+		return err
+	}
+	return writeParseWarnings(stmts, seasonID, game)
 }
 
-func writeContestants(dbName string, season SeasonData) {
-	db, err := sql.Open("sqlite3", dbName)
+func writeGameRow(stmts *gameStatements, seasonID string, game GameData) error {
+	if _, err := stmts.upsertSeason.Exec(seasonID); err != nil {
+		return fmt.Errorf("failed to write season into seasons table: %v", err)
+	}
+
+	_, err := stmts.upsertGame.Exec(
+		game.ID,
+		seasonID,
+		nullInt(game.ShowNum),
+		nullString(game.AirDate),
+		nullString(game.TapeDate),
+		game.Era,
+		game.Format,
+		strings.Join(game.Unavailable, ","),
+		game.ParserVersion,
+	)
 	if err != nil {
-		log.Fatalf("Failed to open SQLite database: %v", err)
-	}
-	defer db.Close()
-
-	// Create the `contestants` table if it doesn't exist
-	createGameRosterTableSQL := `
-		ATTACH DATABASE ? AS game_data;
-		CREATE TABLE IF NOT EXISTS game_data.game_roster (
-			player_id TEXT NOT NULL,
-			season_id TEXT NOT NULL,
-			game_id INTEGER NOT NULL,
-			name TEXT NOT NULL,
-			nickname TEXT,
-			bio TEXT
-		);
-	`
-
-	if _, err := db.Exec(createGameRosterTableSQL, dbName); err != nil {
-		log.Fatalf("Failed to create game_roster table: %v", err)
-	}
-
-	// Insert contestants into the `contestants` table
-	insertGameRosterSQL := `
-		INSERT OR IGNORE INTO game_data.game_roster (
-			player_id, season_id, game_id, name, nickname, bio
-		) VALUES (?, ?, ?, ?, ?, ?);
-	`
-
-	for _, game := range season.Games {
-		for _, contestant := range game.Contestants {
-			_, err := db.Exec(
-				insertGameRosterSQL,
-				contestant.PlayerID,
-				season.ID,
+		return fmt.Errorf("failed to write game into games table: %v", err)
+	}
+	return nil
+}
+
+func writeContestants(stmts *gameStatements, game GameData) error {
+	if _, err := stmts.deleteAppearances.Exec(game.ID); err != nil {
+		return fmt.Errorf("failed to clear appearances: %v", err)
+	}
+
+	for seat, contestant := range game.Contestants {
+		if contestant.PlayerID != "" {
+			if _, err := stmts.upsertPlayer.Exec(contestant.PlayerID, contestant.Name); err != nil {
+				return fmt.Errorf("failed to write player into players table: %v", err)
+			}
+		}
+
+		_, err := stmts.insertAppearance.Exec(
+			game.ID,
+			seat+1,
+			nullString(contestant.PlayerID),
+			contestant.Name,
+			contestant.Nickname,
+			contestant.Bio,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert contestant into appearances table: %v", err)
+		}
+	}
+	return nil
+}
+
+// writeRounds writes the rounds of a game with their categories, clues and responses
+func writeRounds(stmts *gameStatements, game GameData) error {
+	if _, err := stmts.deleteRounds.Exec(game.ID); err != nil {
+		return fmt.Errorf("failed to clear rounds: %v", err)
+	}
+	if _, err := stmts.deleteFinalJeopardy.Exec(game.ID); err != nil {
+		return fmt.Errorf("failed to clear final jeopardy: %v", err)
+	}
+	if stmts.deleteSearch != nil {
+		if _, err := stmts.deleteSearch.Exec(game.ID); err != nil {
+			return fmt.Errorf("failed to clear search index: %v", err)
+		}
+	}
+
+	players := playerIDsByNickname(game)
+
+	for ordinal, round := range game.Rounds {
+		rID := roundID(game.ID, round.Name)
+		if _, err := stmts.insertRound.Exec(rID, game.ID, roundCodes[round.Name], round.Name, ordinal+1); err != nil {
+			return fmt.Errorf("failed to insert round into rounds table: %v", err)
+		}
+
+		for i, category := range round.Categories {
+			column := i + 1
+			_, err := stmts.insertCategory.Exec(
+				categoryID(game.ID, round.Name, column),
 				game.ID,
-				contestant.Name,
-				contestant.Nickname,
-				contestant.Bio,
+				rID,
+				column,
+				category.Name,
+				category.NameHTML,
 			)
 			if err != nil {
-				log.Fatalf("Failed to insert contestant into contestants table: %v", err)
+				return fmt.Errorf("failed to insert category into categories table: %v", err)
+			}
+		}
+
+		for _, clue := range round.Clues {
+			if err := writeClue(stmts, game, round, rID, clue, players); err != nil {
+				return err
 			}
 		}
 	}
+	return nil
+}
+
+func writeClue(stmts *gameStatements, game GameData, round Round, rID string, clue Clue, players map[string]string) error {
+	category, column := clueCategory(round, clue)
+	cID := clueID(game.ID, clue.Position)
+
+	var value interface{}
+	if amount, err := parseMoney(clue.Value); err == nil {
+		value = amount
+	}
 
-	// Create or replace the `contestants` view
-	createContestantViewSQL := `
-		DROP VIEW IF EXISTS contestants;
-		CREATE VIEW contestants AS
-		SELECT DISTINCT player_id, name FROM game_roster;
-	`
-	if _, err := db.Exec(createContestantViewSQL); err != nil {
-		log.Fatalf("Failed to create contestants view: %v", err)
+	_, err := stmts.insertClue.Exec(
+		cID,
+		game.ID,
+		rID,
+		nullString(categoryID(game.ID, round.Name, column)),
+		clue.Position,
+		nullInt(clueRowNumber(clue.Position)),
+		value,
+		nullInt(clue.OrderNumber),
+		clue.DailyDouble,
+		nullInt(clue.Wager),
+		clue.TripleStumper,
+		clue.Text,
+		clue.TextHTML,
+		clue.CorrectResponse,
+		clue.CorrectResponseHTML,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert clue %s into clues table: %v", clue.Position, err)
 	}
 
+	if stmts.insertSearch != nil {
+		if _, err := stmts.insertSearch.Exec(cID, game.ID, clue.Text, clue.CorrectResponse, category.Name); err != nil {
+			return fmt.Errorf("failed to index clue %s for search: %v", clue.Position, err)
+		}
+	}
+
+	final := round.Name == roundFinalJeopardy || round.Name == roundTiebreaker
+	for seq, response := range clue.Responses {
+		playerID := nullString(players[response.Contestant])
+		if final {
+			_, err = stmts.insertFinalJeopardy.Exec(
+				game.ID,
+				roundCodes[round.Name],
+				seq+1,
+				response.Contestant,
+				playerID,
+				response.Text,
+				response.Wager,
+				response.Correct,
+			)
+		} else {
+			_, err = stmts.insertResponse.Exec(
+				cID,
+				seq+1,
+				game.ID,
+				response.Contestant,
+				playerID,
+				response.Correct,
+			)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to insert response to clue %s: %v", clue.Position, err)
+		}
+	}
+	return nil
 }
 
-func generateRandomString(n int) string {
-	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	rand.Seed(time.Now().UnixNano())
-	b := make([]byte, n)
-	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
+// playerIDsByNickname maps the nicknames used in responses to player ids
+func playerIDsByNickname(game GameData) map[string]string {
+	players := make(map[string]string)
+	for _, contestant := range game.Contestants {
+		if contestant.PlayerID != "" && contestant.Nickname != "" {
+			players[contestant.Nickname] = contestant.PlayerID
+		}
 	}
-	return string(b)
+	return players
 }
 
-func writeCategories(dbName string, season SeasonData) {
-	db, err := sql.Open("sqlite3", dbName)
-	if err != nil {
-		log.Fatalf("Failed to open SQLite database: %v", err)
-	}
-	defer db.Close()
-
-	// Create the `categories` table if it doesn't exist
-	createCategoriesTableSQL := `
-		ATTACH DATABASE ? AS game_data;
-		CREATE TABLE IF NOT EXISTS game_data.categories (
-			category_id TEXT PRIMARY KEY,
-			season_id TEXT NOT NULL,
-			game_id INTEGER NOT NULL,
-			round_name TEXT NOT NULL,
-			category_name TEXT NOT NULL
-		);
-	`
-	if _, err := db.Exec(createCategoriesTableSQL, dbName); err != nil {
-		log.Fatalf("Failed to create categories table: %v", err)
-	}
-
-	// Insert categories into the `categories` table
-	insertCategorySQL := `
-		INSERT OR IGNORE INTO game_data.categories (
-			category_id, season_id, game_id, round_name, category_name
-		) VALUES (?, ?, ?, ?, ?);
-	`
-
-	for _, game := range season.Games {
-		for _, round := range game.Rounds {
-			for _, categoryName := range round.Categories {
-				// Generate a unique random string for the categoryID
-				categoryID := generateRandomString(8)
-
-				_, err := db.Exec(
-					insertCategorySQL,
-					categoryID,
-					season.ID,
-					game.ID,
-					round.Name,
-					categoryName,
-				)
-				if err != nil {
-					log.Fatalf("Failed to insert category into categories table: %v", err)
-				}
+func writeParseWarnings(stmts *gameStatements, seasonID string, game GameData) error {
+	// Warnings describe the latest parse only, so replace any from earlier runs
+	if _, err := stmts.deleteWarnings.Exec(game.ID); err != nil {
+		return fmt.Errorf("failed to clear parse warnings: %v", err)
+	}
+
+	for _, warning := range game.Warnings {
+		_, err := stmts.insertWarning.Exec(
+			seasonID,
+			game.ID,
+			warning.Code,
+			warning.Message,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert parse warning into parse_warnings table: %v", err)
+		}
+	}
+	return nil
+}
+
+// writeScoreTimeline replaces the running scores of a game. Each event keeps
+// the score of the contestant it changed in its own column for querying, and
+// every contestant's score as JSON.
+func writeScoreTimeline(stmts *gameStatements, game GameData) error {
+	if _, err := stmts.deleteScoreTimeline.Exec(game.ID); err != nil {
+		return fmt.Errorf("failed to clear score timeline: %v", err)
+	}
+
+	for seq, event := range game.ScoreTimeline {
+		score := 0
+		for _, contestant := range event.Scores {
+			if contestant.Nickname == event.Contestant {
+				score = contestant.Score
 			}
 		}
+		scores, err := json.Marshal(event.Scores)
+		if err != nil {
+			return err
+		}
+		_, err = stmts.insertScoreEvent.Exec(
+			game.ID,
+			seq+1,
+			event.Round,
+			event.Position,
+			nullInt(event.OrderNumber),
+			event.Contestant,
+			event.Delta,
+			score,
+			string(scores),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert score event into score_timeline table: %v", err)
+		}
 	}
+	return nil
+}
 
-	log.Println("Successfully wrote categories data to SQLite database")
+// writePlayers upserts players outside of any game
+func writePlayers(db *sql.DB, players []Contestant) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO players (player_id, name) VALUES (?, ?)
+		ON CONFLICT (player_id) DO UPDATE SET name = excluded.name;`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %v", err)
+	}
+
+	for _, player := range players {
+		if player.PlayerID == "" {
+			continue
+		}
+		if _, err := stmt.Exec(player.PlayerID, player.Name); err != nil {
+			return fmt.Errorf("failed to write player %s into players table: %v", player.PlayerID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// readParserVersions returns the parser version stored for each game in the database
+func readParserVersions(db *sql.DB) (map[int]int, error) {
+	versions := make(map[int]int)
+
+	rows, err := db.Query(`SELECT game_id, parser_version FROM games;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var gameID, version int
+		if err := rows.Scan(&gameID, &version); err != nil {
+			return nil, err
+		}
+		versions[gameID] = version
+	}
+	return versions, rows.Err()
 }
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Severities of doctor findings, most severe first
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+var severities = []string{severityError, severityWarning, severityInfo}
+
+// doctorExamples is how many offending rows each finding lists
+const doctorExamples = 5
+
+// doctorFinding is the result of one check that found something
+type doctorFinding struct {
+	Check    string   `json:"check"`
+	Severity string   `json:"severity"`
+	Message  string   `json:"message"`
+	Count    int      `json:"count"`
+	Examples []string `json:"examples,omitempty"`
+}
+
+// doctorCheck looks for one kind of problem. Query returns one row per
+// problem with a single column identifying it.
+type doctorCheck struct {
+	Name     string
+	Severity string
+	Message  string
+	// Requires names a table that must exist for the check to run
+	Requires string
+	Query    string
+}
+
+var doctorChecks = []doctorCheck{
+	{
+		Name:     "game_without_clues",
+		Severity: severityError,
+		Message:  "games with no clues",
+		Query: `
+			SELECT 'game ' || g.game_id FROM games g
+			WHERE NOT EXISTS (SELECT 1 FROM clues c WHERE c.game_id = g.game_id)
+			ORDER BY g.game_id;`,
+	},
+	{
+		Name:     "missing_air_date",
+		Severity: severityError,
+		Message:  "games with no air date where the page should have one",
+		Query: `
+			SELECT 'game ' || game_id FROM games
+			WHERE air_date IS NULL AND ',' || COALESCE(unavailable_fields, '') || ',' NOT LIKE '%,air_date,%'
+			ORDER BY game_id;`,
+	},
+	{
+		Name:     "unavailable_air_date",
+		Severity: severityInfo,
+		Message:  "games whose page gives no air date, such as pilots",
+		Query: `
+			SELECT 'game ' || game_id FROM games
+			WHERE air_date IS NULL AND ',' || COALESCE(unavailable_fields, '') || ',' LIKE '%,air_date,%'
+			ORDER BY game_id;`,
+	},
+	{
+		Name:     "tape_after_air",
+		Severity: severityError,
+		Message:  "games taped after they aired",
+		Query: `
+			SELECT 'game ' || game_id || ' taped ' || tape_date || ', aired ' || air_date FROM games
+			WHERE tape_date > air_date ORDER BY game_id;`,
+	},
+	{
+		Name:     "future_air_date",
+		Severity: severityWarning,
+		Message:  "games with an air date in the future",
+		Query: `
+			SELECT 'game ' || game_id || ' aired ' || air_date FROM games
+			WHERE air_date > date('now') ORDER BY game_id;`,
+	},
+	{
+		Name:     "missing_show_number",
+		Severity: severityWarning,
+		Message:  "games with no show number where the page should have one",
+		Query: `
+			SELECT 'game ' || game_id FROM games
+			WHERE show_num IS NULL AND ',' || COALESCE(unavailable_fields, '') || ',' NOT LIKE '%,show_number,%'
+			ORDER BY game_id;`,
+	},
+	{
+		Name:     "duplicate_show_number",
+		Severity: severityWarning,
+		Message:  "show numbers shared by more than one game, as for some special and pilot games",
+		Query: `
+			SELECT 'show #' || show_num || ': games ' || group_concat(game_id, ', ') FROM (
+				SELECT show_num, game_id FROM games WHERE show_num IS NOT NULL ORDER BY game_id
+			)
+			GROUP BY show_num HAVING COUNT(*) > 1 ORDER BY show_num;`,
+	},
+	{
+		Name:     "missing_tape_date",
+		Severity: severityWarning,
+		Message:  "games with no tape date where the page should have one",
+		Query: `
+			SELECT 'game ' || game_id FROM games
+			WHERE tape_date IS NULL AND ',' || COALESCE(unavailable_fields, '') || ',' NOT LIKE '%,tape_date,%'
+			ORDER BY game_id;`,
+	},
+	{
+		Name:     "unavailable_tape_date",
+		Severity: severityInfo,
+		Message:  "games from before tape dates were recorded",
+		Query: `
+			SELECT 'game ' || game_id FROM games
+			WHERE tape_date IS NULL AND ',' || COALESCE(unavailable_fields, '') || ',' LIKE '%,tape_date,%'
+			ORDER BY game_id;`,
+	},
+	{
+		Name:     "clue_without_text",
+		Severity: severityError,
+		Message:  "clues with a correct response but no text",
+		Query: `
+			SELECT clue_id FROM clues
+			WHERE COALESCE(text, '') = '' AND COALESCE(correct_response, '') != ''
+			ORDER BY game_id, clue_id;`,
+	},
+	{
+		Name:     "clue_without_response",
+		Severity: severityWarning,
+		Message:  "clues with text but no correct response",
+		Query: `
+			SELECT clue_id FROM clues
+			WHERE COALESCE(text, '') != '' AND COALESCE(correct_response, '') = ''
+			ORDER BY game_id, clue_id;`,
+	},
+	{
+		Name:     "unrevealed_clue",
+		Severity: severityInfo,
+		Message:  "clues that were never revealed",
+		Query: `
+			SELECT clue_id FROM clues
+			WHERE COALESCE(text, '') = '' AND COALESCE(correct_response, '') = ''
+			ORDER BY game_id, clue_id;`,
+	},
+	{
+		Name:     "implausible_value",
+		Severity: severityWarning,
+		Message:  "clues whose value is not a board value",
+		Query: `
+			SELECT clue_id || ' ($' || value || ')' FROM clues
+			WHERE daily_double = 0 AND (value <= 0 OR value > 5000 OR value % 100 != 0)
+			ORDER BY game_id, clue_id;`,
+	},
+	{
+		Name:     "missing_category",
+		Severity: severityWarning,
+		Message:  "board clues not linked to a category",
+		Query: `
+			SELECT clue_id FROM clues
+			WHERE category_id IS NULL AND row_num IS NOT NULL
+			ORDER BY game_id, clue_id;`,
+	},
+	{
+		Name:     "outdated_parser",
+		Severity: severityWarning,
+		Message:  "games written by an older parser, run reparse",
+		Query: `
+			SELECT 'game ' || game_id || ' (v' || parser_version || ')' FROM games
+			WHERE parser_version < ` + strconv.Itoa(parserVersion) + `
+			ORDER BY game_id;`,
+	},
+	{
+		Name:     "parse_warning",
+		Severity: severityInfo,
+		Message:  "games with parse warnings",
+		Query: `
+			SELECT 'game ' || game_id || ': ' || group_concat(code, ', ') FROM parse_warnings
+			GROUP BY game_id ORDER BY game_id;`,
+	},
+	{
+		Name:     "foreign_key",
+		Severity: severityError,
+		Message:  "rows referencing a missing parent row",
+		Query:    `SELECT "table" || ' row ' || rowid || ' -> ' || parent FROM pragma_foreign_key_check;`,
+	},
+	{
+		Name:     "legacy_orphan_clues",
+		Severity: severityWarning,
+		Message:  "legacy clue rows whose game was dropped from legacy_gamelist, likely by a duplicate show number",
+		Requires: "legacy_clues",
+		Query: `
+			SELECT DISTINCT 'game ' || c.game_id FROM legacy_clues c
+			WHERE NOT EXISTS (SELECT 1 FROM legacy_gamelist g WHERE g.game_id = c.game_id)
+			ORDER BY c.game_id;`,
+	},
+	{
+		Name:     "legacy_duplicate_clues",
+		Severity: severityInfo,
+		Message:  "clues stored more than once in legacy_clues",
+		Requires: "legacy_clues",
+		Query: `
+			SELECT 'game ' || game_id || ' ' || position || ' x' || COUNT(*) FROM legacy_clues
+			GROUP BY game_id, position HAVING COUNT(*) > 1
+			ORDER BY game_id, position;`,
+	},
+}
+
+// runDoctorCheck runs one check and returns a finding if it matched anything
+func runDoctorCheck(db *sql.DB, check doctorCheck) (*doctorFinding, error) {
+	if check.Requires != "" {
+		exists, err := tableExists(db, check.Requires)
+		if err != nil || !exists {
+			return nil, err
+		}
+	}
+
+	rows, err := db.Query(check.Query)
+	if err != nil {
+		return nil, fmt.Errorf("check %s: %v", check.Name, err)
+	}
+	defer rows.Close()
+
+	finding := doctorFinding{Check: check.Name, Severity: check.Severity, Message: check.Message}
+	for rows.Next() {
+		var subject string
+		if err := rows.Scan(&subject); err != nil {
+			return nil, fmt.Errorf("check %s: %v", check.Name, err)
+		}
+		if finding.Count < doctorExamples {
+			finding.Examples = append(finding.Examples, subject)
+		}
+		finding.Count++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("check %s: %v", check.Name, err)
+	}
+	if finding.Count == 0 {
+		return nil, nil
+	}
+	return &finding, nil
+}
+
+// checkCache compares the cached game pages with the games in the database.
+// A cached game missing from the database usually failed to write.
+func checkCache(db *sql.DB, dataDir string) ([]doctorFinding, error) {
+	cached, err := findCachedGames(dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[int]bool)
+	rows, err := db.Query(`SELECT game_id FROM games;`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var gameID int
+		if err := rows.Scan(&gameID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stored[gameID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	missing := doctorFinding{Check: "cached_not_stored", Severity: severityWarning, Message: "cached games missing from the database"}
+	empty := doctorFinding{Check: "empty_cache_file", Severity: severityWarning, Message: "cached pages with no game in them"}
+	add := func(finding *doctorFinding, subject string) {
+		if finding.Count < doctorExamples {
+			finding.Examples = append(finding.Examples, subject)
+		}
+		finding.Count++
+	}
+
+	for _, entry := range cached {
+		if stored[entry.GameID] {
+			continue
+		}
+		content, err := os.ReadFile(entry.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.Contains(string(content), "<table") {
+			add(&empty, entry.Path)
+			continue
+		}
+		add(&missing, fmt.Sprintf("game %d (%s)", entry.GameID, entry.Path))
+	}
+
+	var findings []doctorFinding
+	for _, finding := range []doctorFinding{missing, empty} {
+		if finding.Count > 0 {
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+// runDoctor runs every check and returns the findings ordered by severity
+func runDoctor(db *sql.DB, dataDir string) ([]doctorFinding, error) {
+	var findings []doctorFinding
+	for _, check := range doctorChecks {
+		finding, err := runDoctorCheck(db, check)
+		if err != nil {
+			return nil, err
+		}
+		if finding != nil {
+			findings = append(findings, *finding)
+		}
+	}
+
+	cacheFindings, err := checkCache(db, dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check cache: %v", err)
+	}
+	findings = append(findings, cacheFindings...)
+
+	var ordered []doctorFinding
+	for _, severity := range severities {
+		for _, finding := range findings {
+			if finding.Severity == severity {
+				ordered = append(ordered, finding)
+			}
+		}
+	}
+	return ordered, nil
+}
+
+// runDoctorCommand implements the doctor command. It exits with status 1
+// when any error-level problem is found.
+//
+//	doctor [-json] [-data DIR]
+func runDoctorCommand(db *sql.DB, defaultDataDir string, args []string) {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print findings as JSON")
+	dataDir := flags.String("data", defaultDataDir, "directory holding the cached game pages")
+	flags.Parse(args)
+
+	findings, err := runDoctor(db, *dataDir)
+	if err != nil {
+		log.Fatalf("Doctor failed: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, finding := range findings {
+		counts[finding.Severity]++
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		report := struct {
+			Findings []doctorFinding `json:"findings"`
+			Counts   map[string]int  `json:"counts"`
+		}{findings, counts}
+		if report.Findings == nil {
+			report.Findings = []doctorFinding{}
+		}
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	} else {
+		for _, severity := range severities {
+			if counts[severity] == 0 {
+				continue
+			}
+			fmt.Printf("%s (%d)\n", strings.ToUpper(severity), counts[severity])
+			for _, finding := range findings {
+				if finding.Severity != severity {
+					continue
+				}
+				fmt.Printf("  %s: %d %s\n", finding.Check, finding.Count, finding.Message)
+				for _, example := range finding.Examples {
+					fmt.Printf("      %s\n", example)
+				}
+				if finding.Count > len(finding.Examples) {
+					fmt.Printf("      ... and %d more\n", finding.Count-len(finding.Examples))
+				}
+			}
+			fmt.Println()
+		}
+		if len(findings) == 0 {
+			fmt.Println("No problems found")
+		}
+	}
+
+	if counts[severityError] > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// testDoctorDatabase returns a migrated database holding season
+func testDoctorDatabase(t *testing.T, season SeasonData) *sql.DB {
+	t.Helper()
+	db := openTestDatabase(t)
+	if err := migrateDatabase(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSeason(db, season); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// doctorGame is a stored game with one revealed clue and nothing for doctor to report
+func doctorGame(id, showNum int) GameData {
+	return GameData{
+		ID: id, ShowNum: showNum, AirDate: "2019-05-01", TapeDate: "2019-03-01", ParserVersion: parserVersion,
+		Rounds: []Round{{
+			Name:       roundJeopardy,
+			Categories: []Category{{Name: "CATEGORY"}},
+			Clues:      []Clue{{Position: "J_1_1", Value: "$200", Text: "A clue", CorrectResponse: "an answer"}},
+		}},
+	}
+}
+
+// findingsFor runs one doctor check and returns what it found
+func findingsFor(t *testing.T, db *sql.DB, name string) []string {
+	t.Helper()
+	for _, check := range doctorChecks {
+		if check.Name != name {
+			continue
+		}
+		finding, err := runDoctorCheck(db, check)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if finding == nil {
+			return nil
+		}
+		return finding.Examples
+	}
+	t.Fatalf("no doctor check %s", name)
+	return nil
+}
+
+func TestDoctorDuplicateShowNumber(t *testing.T) {
+	tests := []struct {
+		name  string
+		games []GameData
+		want  []string
+	}{
+		{"distinct", []GameData{doctorGame(1, 100), doctorGame(2, 101), doctorGame(3, 0), doctorGame(4, 0)}, nil},
+		{"shared", []GameData{doctorGame(1, 100), doctorGame(3, 100), doctorGame(2, 101)}, []string{"show #100: games 1, 3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDoctorDatabase(t, SeasonData{ID: "35", Games: tt.games})
+			if got := findingsFor(t, db, "duplicate_show_number"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("duplicate_show_number found %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoctorChecks(t *testing.T) {
+	tests := []struct {
+		check string
+		// game changes the clean game before it is stored, and statements
+		// then run with foreign keys off
+		game       func(game *GameData)
+		statements []string
+		want       []string
+	}{
+		{check: "game_without_clues", game: func(game *GameData) { game.Rounds = nil }, want: []string{"game 1"}},
+		{check: "missing_air_date", game: func(game *GameData) { game.AirDate = "" }, want: []string{"game 1"}},
+		{check: "missing_air_date", game: func(game *GameData) {
+			game.AirDate, game.Format, game.Unavailable = "", formatPilot.Name, []string{fieldAirDate}
+		}},
+		{check: "unavailable_air_date", game: func(game *GameData) {
+			game.AirDate, game.Format, game.Unavailable = "", formatPilot.Name, []string{fieldAirDate}
+		}, want: []string{"game 1"}},
+		{check: "tape_after_air", game: func(game *GameData) { game.TapeDate = "2019-06-01" },
+			want: []string{"game 1 taped 2019-06-01, aired 2019-05-01"}},
+		{check: "future_air_date", game: func(game *GameData) { game.AirDate = "2999-01-01" }, want: []string{"game 1 aired 2999-01-01"}},
+		{check: "missing_show_number", game: func(game *GameData) { game.ShowNum = 0 }, want: []string{"game 1"}},
+		{check: "missing_show_number", game: func(game *GameData) {
+			game.ShowNum, game.Unavailable = 0, []string{fieldShowNumber}
+		}},
+		{check: "missing_show_number", game: func(game *GameData) { game.ShowNum = 0 },
+			statements: []string{`UPDATE games SET unavailable_fields = NULL;`}, want: []string{"game 1"}},
+		{check: "missing_tape_date", game: func(game *GameData) { game.TapeDate = "" }, want: []string{"game 1"}},
+		{check: "unavailable_tape_date", game: func(game *GameData) {
+			game.TapeDate, game.Unavailable = "", []string{fieldTapeDate}
+		}, want: []string{"game 1"}},
+		{check: "clue_without_text", game: func(game *GameData) { game.Rounds[0].Clues[0].Text = "" }, want: []string{"1-J_1_1"}},
+		{check: "clue_without_response", game: func(game *GameData) { game.Rounds[0].Clues[0].CorrectResponse = "" },
+			want: []string{"1-J_1_1"}},
+		{check: "unrevealed_clue", game: func(game *GameData) {
+			game.Rounds[0].Clues[0].Text, game.Rounds[0].Clues[0].CorrectResponse = "", ""
+		}, want: []string{"1-J_1_1"}},
+		{check: "implausible_value", game: func(game *GameData) { game.Rounds[0].Clues[0].Value = "$250" },
+			want: []string{"1-J_1_1 ($250)"}},
+		{check: "implausible_value", game: func(game *GameData) {
+			game.Rounds[0].Clues[0].Value, game.Rounds[0].Clues[0].DailyDouble, game.Rounds[0].Clues[0].Wager = "$250", true, 1234
+		}},
+		{check: "missing_category", statements: []string{`UPDATE clues SET category_id = NULL;`}, want: []string{"1-J_1_1"}},
+		{check: "outdated_parser", game: func(game *GameData) { game.ParserVersion = parserVersion - 1 },
+			want: []string{"game 1 (v" + strconv.Itoa(parserVersion-1) + ")"}},
+		{check: "parse_warning", game: func(game *GameData) {
+			game.Warnings = []ParseWarning{newParseWarning(warnBoardSize, "small board"), newParseWarning(warnScore, "no score")}
+		}, want: []string{"game 1: board_size, score"}},
+		{check: "foreign_key", statements: []string{`DELETE FROM games;`}, want: []string{"rounds row 1 -> games", "categories row 1 -> games", "clues row 1 -> games"}},
+		{check: "legacy_orphan_clues", statements: append(legacyDoctorTables,
+			`INSERT INTO legacy_clues (game_id, position) VALUES (1, 'J_1_1'), (7, 'J_1_1');`,
+		), want: []string{"game 7"}},
+		{check: "legacy_duplicate_clues", statements: append(legacyDoctorTables,
+			`INSERT INTO legacy_clues (game_id, position) VALUES (1, 'J_1_1'), (1, 'J_1_1'), (1, 'J_2_1');`,
+		), want: []string{"game 1 J_1_1 x2"}},
+	}
+	for _, tt := range tests {
+		name := tt.check
+		if tt.want == nil {
+			name += " not found"
+		}
+		t.Run(name, func(t *testing.T) {
+			game := doctorGame(1, 100)
+			if tt.game != nil {
+				tt.game(&game)
+			}
+			db := testDoctorDatabase(t, SeasonData{ID: "35", Games: []GameData{game}})
+			if len(tt.statements) > 0 {
+				if _, err := db.Exec(`PRAGMA foreign_keys = OFF;`); err != nil {
+					t.Fatal(err)
+				}
+				for _, statement := range tt.statements {
+					if _, err := db.Exec(statement); err != nil {
+						t.Fatalf("%s: %v", statement, err)
+					}
+				}
+			}
+			if got := findingsFor(t, db, tt.check); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s found %q, want %q", tt.check, got, tt.want)
+			}
+		})
+	}
+}
+
+// legacyDoctorTables are the legacy tables the legacy checks read, with the
+// clean game in legacy_gamelist
+var legacyDoctorTables = []string{
+	`CREATE TABLE legacy_gamelist (game_id INTEGER);`,
+	`CREATE TABLE legacy_clues (game_id INTEGER, position TEXT);`,
+	`INSERT INTO legacy_gamelist (game_id) VALUES (1);`,
+}
+
+// TestDoctorCleanDatabase runs every check over a database and cache with
+// nothing wrong in them
+func TestDoctorCleanDatabase(t *testing.T) {
+	db := testDoctorDatabase(t, SeasonData{ID: "35", Games: []GameData{doctorGame(1, 100), doctorGame(2, 101)}})
+	dataDir := t.TempDir()
+	writeCachedPage(t, dataDir, 1, "35", testGamePage(testGame{ID: 1, ShowNum: 100, AirDate: "2019-05-01"}))
+
+	findings, err := runDoctor(db, dataDir)
+	if err != nil {
+		t.Fatalf("runDoctor: %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("runDoctor found %+v, want nothing", findings)
+	}
+}
+
+// writeCachedPage saves a game page where the fetcher caches it
+func writeCachedPage(t *testing.T, dataDir string, gameID int, seasonID, page string) {
+	t.Helper()
+	dir := filepath.Join(dataDir, "season_"+seasonID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, cachedGameFilename(gameID, seasonID)), []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDoctorCache(t *testing.T) {
+	db := testDoctorDatabase(t, SeasonData{ID: "35", Games: []GameData{doctorGame(1, 100)}})
+	dataDir := t.TempDir()
+	writeCachedPage(t, dataDir, 1, "35", testGamePage(testGame{ID: 1, ShowNum: 100, AirDate: "2019-05-01"}))
+	writeCachedPage(t, dataDir, 2, "35", testGamePage(testGame{ID: 2, ShowNum: 101, AirDate: "2019-05-02"}))
+	writeCachedPage(t, dataDir, 3, "35", "<html><body>ERROR: No game 3 in database.</body></html>")
+
+	findings, err := checkCache(db, dataDir)
+	if err != nil {
+		t.Fatalf("checkCache: %v", err)
+	}
+	got := make(map[string][]string)
+	for _, finding := range findings {
+		got[finding.Check] = finding.Examples
+	}
+	want := map[string][]string{
+		"cached_not_stored": {"game 2 (" + filepath.Join(dataDir, "season_35", "2_35_j-archive.html") + ")"},
+		"empty_cache_file":  {filepath.Join(dataDir, "season_35", "3_35_j-archive.html")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("checkCache = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Round names
+const (
+	roundJeopardy       = "Jeopardy! Round"
+	roundDoubleJeopardy = "Double Jeopardy! Round"
+	roundFinalJeopardy  = "Final Jeopardy"
+	roundTiebreaker     = "Tiebreaker"
+)
+
+// Layout eras of J-Archive game pages
+const (
+	eraClassic = "classic" // no clue order numbers, rounds laid out as bare tables
+	eraModern  = "modern"
+)
+
+// Fields that may be missing from a game page. These are recorded in
+// GameData.Unavailable instead of being stored as zero values.
+const (
+	fieldShowNumber   = "show_number"
+	fieldAirDate      = "air_date"
+	fieldTapeDate     = "tape_date"
+	fieldOrderNumbers = "order_numbers"
+	fieldContestants  = "contestants"
+	fieldFullBoard    = "full_board"
+)
+
+// gameFormat describes the round structure expected for a kind of game.
+// Zero counts are not checked.
+type gameFormat struct {
+	Name        string
+	Rounds      int // Jeopardy!, Double Jeopardy! and Final Jeopardy, not counting tiebreakers
+	Contestants int
+}
+
+var (
+	formatStandard = gameFormat{Name: "standard", Rounds: 3, Contestants: 3}
+	// Super Jeopardy! player counts vary between stages
+	formatSuperJeopardy = gameFormat{Name: "super_jeopardy", Rounds: 3}
+	// Pilots were taped with experimental round structures
+	formatPilot                 = gameFormat{Name: "pilot"}
+	formatTournamentOfChampions = gameFormat{Name: "tournament_of_champions", Rounds: 3, Contestants: 3}
+	formatCollege               = gameFormat{Name: "college", Rounds: 3, Contestants: 3}
+	formatTeen                  = gameFormat{Name: "teen", Rounds: 3, Contestants: 3}
+	// Celebrity games have been played by teams as well as single players
+	formatCelebrity = gameFormat{Name: "celebrity", Rounds: 3}
+)
+
+var gameFormats = map[string]gameFormat{
+	formatStandard.Name:              formatStandard,
+	formatSuperJeopardy.Name:         formatSuperJeopardy,
+	formatPilot.Name:                 formatPilot,
+	formatTournamentOfChampions.Name: formatTournamentOfChampions,
+	formatCollege.Name:               formatCollege,
+	formatTeen.Name:                  formatTeen,
+	formatCelebrity.Name:             formatCelebrity,
+}
+
+// formatFor returns the named game format, defaulting to the standard format
+func formatFor(name string) gameFormat {
+	if format, ok := gameFormats[name]; ok {
+		return format
+	}
+	return formatStandard
+}
+
+// pageLayout is the structure of a game page that its era and format are
+// classified from
+type pageLayout struct {
+	Header        string // the game title heading, e.g. "Show #8000 - Wednesday, May 1, 2019"
+	Comments      string
+	RoundDivs     bool // regular rounds are inside jeopardy_round and double_jeopardy_round divs
+	OrderNumbers  bool // clues carry the order they were picked in
+	RegularRounds int
+	FinalRounds   int
+}
+
+// readPageLayout reads the layout of a game page
+func readPageLayout(doc *goquery.Document) pageLayout {
+	layout := pageLayout{
+		Header:   normalizeText(doc.Find("#game_title").Text()),
+		Comments: normalizeText(doc.Find("#game_comments").Text()),
+	}
+	// The heading is missing from some older pages, whose title holds the same text
+	if layout.Header == "" {
+		layout.Header = strings.TrimPrefix(normalizeText(doc.Find("title").Text()), "J! Archive - ")
+	}
+
+	regular := doc.Find("table.round")
+	layout.RegularRounds = regular.Length()
+	layout.FinalRounds = doc.Find("table.final_round").Length()
+	layout.RoundDivs = layout.RegularRounds > 0
+	regular.Each(func(_ int, roundHtml *goquery.Selection) {
+		switch roundHtml.Closest("div").AttrOr("id", "") {
+		case "jeopardy_round", "double_jeopardy_round":
+		default:
+			layout.RoundDivs = false
+		}
+	})
+	doc.Find("td.clue_order_number").EachWithBreak(func(_ int, orderHtml *goquery.Selection) bool {
+		layout.OrderNumbers = strings.TrimSpace(orderHtml.Text()) != ""
+		return !layout.OrderNumbers
+	})
+	return layout
+}
+
+// Headings of special games. Regular games are headed "Show #N - date".
+var (
+	superJeopardyHeaderRegex = regexp.MustCompile(`(?i)^super jeopardy!`)
+	pilotHeaderRegex         = regexp.MustCompile(`(?i)^(?:\w+ )?pilot\b`)
+)
+
+// Tournament games are named at the start of the game comments, e.g.
+// "Tournament of Champions quarterfinal game 1." Later mentions, such as a
+// player qualifying for a tournament, do not make a game part of it.
+var tournamentFormats = []struct {
+	regex  *regexp.Regexp
+	format gameFormat
+}{
+	{regexp.MustCompile(`(?i)^(?:\d{4} )?(?:jeopardy! )?tournament of champions\b`), formatTournamentOfChampions},
+	{regexp.MustCompile(`(?i)^(?:\d{4} )?(?:jeopardy! )?(?:national )?college (?:championship|tournament)\b`), formatCollege},
+	{regexp.MustCompile(`(?i)^(?:\d{4} )?(?:jeopardy! )?(?:high school )?teen (?:tournament|reunion tournament)\b`), formatTeen},
+	{regexp.MustCompile(`(?i)^(?:\d{4} )?(?:primetime )?celebrity jeopardy!|^(?:\d{4} )?(?:jeopardy! )?celebrity (?:championship|tournament|week)\b`), formatCelebrity},
+}
+
+// detectFormat identifies special game formats from the page heading, the
+// start of the game comments and the round structure
+func detectFormat(layout pageLayout) gameFormat {
+	switch {
+	case superJeopardyHeaderRegex.MatchString(layout.Header):
+		return formatSuperJeopardy
+	case pilotHeaderRegex.MatchString(layout.Header):
+		return formatPilot
+	}
+	for _, tournament := range tournamentFormats {
+		if tournament.regex.MatchString(layout.Comments) {
+			return tournament.format
+		}
+	}
+	// Pilots filed without a show number are recognisable by a board missing
+	// the Double Jeopardy! or Final Jeopardy! round
+	if !strings.HasPrefix(layout.Header, "Show #") && (layout.RegularRounds < 2 || layout.FinalRounds == 0) {
+		return formatPilot
+	}
+	return formatStandard
+}
+
+// detectEra returns the layout era of a game page. Modern pages number every
+// clue in the order it was picked and wrap each round in its own div.
+func detectEra(layout pageLayout) string {
+	if layout.OrderNumbers && layout.RoundDivs {
+		return eraModern
+	}
+	return eraClassic
+}
+
+// classifyGame records the era, format and unavailable fields of a parsed game
+func classifyGame(game *GameData, layout pageLayout) {
+	game.Era = detectEra(layout)
+	game.Format = detectFormat(layout).Name
+	game.Unavailable = nil
+
+	if game.ShowNum == 0 {
+		game.Unavailable = append(game.Unavailable, fieldShowNumber)
+	}
+	if game.AirDate == "" {
+		game.Unavailable = append(game.Unavailable, fieldAirDate)
+	}
+	if game.TapeDate == "" {
+		game.Unavailable = append(game.Unavailable, fieldTapeDate)
+	}
+	if len(game.Contestants) == 0 {
+		game.Unavailable = append(game.Unavailable, fieldContestants)
+	}
+
+	hasOrderNumbers := false
+	fullBoard := true
+	for _, round := range game.Rounds {
+		if round.Name != roundJeopardy && round.Name != roundDoubleJeopardy {
+			continue
+		}
+		for _, clue := range round.Clues {
+			if clue.OrderNumber > 0 {
+				hasOrderNumbers = true
+			}
+		}
+		if len(round.Categories) != expectedCategories || len(round.Clues) != expectedCategories*expectedCluesPerCat {
+			fullBoard = false
+		}
+	}
+	if !hasOrderNumbers {
+		game.Unavailable = append(game.Unavailable, fieldOrderNumbers)
+	}
+	// Incomplete boards are expected on classic pages, elsewhere they are reported as warnings
+	if !fullBoard && game.Era == eraClassic {
+		game.Unavailable = append(game.Unavailable, fieldFullBoard)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestDetectFormat(t *testing.T) {
+	standardRounds := pageLayout{RegularRounds: 2, FinalRounds: 1}
+	layout := func(header, comments string) pageLayout {
+		l := standardRounds
+		l.Header, l.Comments = header, comments
+		return l
+	}
+	tests := []struct {
+		name   string
+		layout pageLayout
+		want   gameFormat
+	}{
+		{"regular game", layout("Show #8000 - Wednesday, May 1, 2019", ""), formatStandard},
+		{"super jeopardy", layout("Super Jeopardy! game 3 - Saturday, June 16, 1990", ""), formatSuperJeopardy},
+		{"pilot", layout("Trebek pilot #1 - taped 1983-09-18", ""), formatPilot},
+		{"pilot mentioned in comments", layout("Show #8000 - Wednesday, May 1, 2019", "Alice is an airline pilot."), formatStandard},
+		{"pilot word in category", layout("Show #8000 - Wednesday, May 1, 2019", "Categories include PILOTS."), formatStandard},
+		{"tournament of champions", layout("Show #7000 - Monday, November 5, 2018", "Tournament of Champions quarterfinal game 1."), formatTournamentOfChampions},
+		{"dated tournament of champions", layout("Show #4000", "2001 Tournament of Champions final game 2."), formatTournamentOfChampions},
+		{"qualifying for a tournament", layout("Show #8000", "Alice qualifies for the Tournament of Champions."), formatStandard},
+		{"college championship", layout("Show #8100", "College Championship semifinal game 2."), formatCollege},
+		{"national college championship", layout("Show #8101", "Jeopardy! National College Championship final game 1."), formatCollege},
+		{"college tournament", layout("Show #3000", "College Tournament quarterfinal game 4."), formatCollege},
+		{"teen tournament", layout("Show #7900", "Teen Tournament quarterfinal game 3."), formatTeen},
+		{"high school teen tournament", layout("Show #8102", "Jeopardy! High School Teen Tournament final game 1."), formatTeen},
+		{"celebrity jeopardy", layout("Show #5000", "Celebrity Jeopardy! game 4."), formatCelebrity},
+		{"primetime celebrity", layout("Show #9000", "Primetime Celebrity Jeopardy! quarterfinal game 2."), formatCelebrity},
+		{"celebrity week", layout("Show #3500", "Celebrity Week game 1."), formatCelebrity},
+		{"celebrity in comments", layout("Show #8000", "Bob once met a celebrity."), formatStandard},
+		{"unnumbered game missing a round", pageLayout{Header: "Game #2 - taped 1983", RegularRounds: 1, FinalRounds: 1}, formatPilot},
+		{"unnumbered game with full rounds", layout("Game #2 - aired 1990-05-01", ""), formatStandard},
+		{"numbered game missing a round", pageLayout{Header: "Show #12", RegularRounds: 1, FinalRounds: 1}, formatStandard},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectFormat(tt.layout); got.Name != tt.want.Name {
+				t.Errorf("detectFormat(%+v) = %s, want %s", tt.layout, got.Name, tt.want.Name)
+			}
+		})
+	}
+}
+
+func TestDetectEra(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout pageLayout
+		want   string
+	}{
+		{"order numbers in round divs", pageLayout{OrderNumbers: true, RoundDivs: true}, eraModern},
+		{"no order numbers", pageLayout{RoundDivs: true}, eraClassic},
+		{"bare round tables", pageLayout{OrderNumbers: true}, eraClassic},
+		{"neither", pageLayout{}, eraClassic},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectEra(tt.layout); got != tt.want {
+				t.Errorf("detectEra(%+v) = %s, want %s", tt.layout, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadPageLayout(t *testing.T) {
+	const (
+		orderedClue   = `<td class="clue"><table><tr><td class="clue_order_number"><a>1</a></td></tr></table></td>`
+		unorderedClue = `<td class="clue"><table><tr><td class="clue_order_number"></td></tr></table></td>`
+		finalRound    = `<div id="final_jeopardy_round"><table class="final_round"><tr><td></td></tr></table></div>`
+	)
+	tests := []struct {
+		name string
+		page string
+		want pageLayout
+	}{
+		{
+			name: "modern",
+			page: `<title>J! Archive - Show #8000, aired 2019-05-01</title>
+<div id="game_title"><h1>Show #8000 - Wednesday, May 1, 2019</h1></div>
+<div id="game_comments">Teen Tournament final game 1.</div>
+<div id="jeopardy_round"><table class="round"><tr>` + orderedClue + `</tr></table></div>
+<div id="double_jeopardy_round"><table class="round"><tr>` + orderedClue + `</tr></table></div>` + finalRound,
+			want: pageLayout{Header: "Show #8000 - Wednesday, May 1, 2019", Comments: "Teen Tournament final game 1.",
+				RoundDivs: true, OrderNumbers: true, RegularRounds: 2, FinalRounds: 1},
+		},
+		{
+			name: "empty order numbers",
+			page: `<div id="game_title"><h1>Show #1200</h1></div>
+<div id="jeopardy_round"><table class="round"><tr>` + unorderedClue + `</tr></table></div>` + finalRound,
+			want: pageLayout{Header: "Show #1200", RoundDivs: true, RegularRounds: 1, FinalRounds: 1},
+		},
+		{
+			name: "bare tables and title only",
+			page: `<title>J! Archive - Super Jeopardy! game 3, aired 1990-05-01</title>
+<table class="round"><tr>` + orderedClue + `</tr></table>`,
+			want: pageLayout{Header: "Super Jeopardy! game 3, aired 1990-05-01", OrderNumbers: true, RegularRounds: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html>" + tt.page + "</html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readPageLayout(doc); got != tt.want {
+				t.Errorf("readPageLayout =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Column types, used by formats that store typed values
+const (
+	exportString = "string"
+	exportInt    = "int"
+	exportBool   = "bool"
+	exportDate   = "date"
+)
+
+// exportColumn is one exported column and the SQL expression that produces it
+type exportColumn struct {
+	Name string
+	Expr string
+	Type string
+}
+
+// exportTable describes a table in the export bundle. Every query joins
+// games as g so the season and date filters apply to all tables.
+type exportTable struct {
+	Name    string
+	Columns []exportColumn
+	From    string
+	OrderBy string
+}
+
+var exportTables = []exportTable{
+	{
+		Name: "games",
+		Columns: []exportColumn{
+			{"game_id", "g.game_id", exportInt},
+			{"season_id", "g.season_id", exportString},
+			{"show_num", "g.show_num", exportInt},
+			{"air_date", "g.air_date", exportDate},
+			{"tape_date", "g.tape_date", exportDate},
+			{"era", "g.era", exportString},
+			{"format", "g.format", exportString},
+			{"unavailable_fields", "g.unavailable_fields", exportString},
+			{"parser_version", "g.parser_version", exportInt},
+		},
+		From:    "games g",
+		OrderBy: "g.game_id",
+	},
+	{
+		Name: "rounds",
+		Columns: []exportColumn{
+			{"round_id", "r.round_id", exportString},
+			{"game_id", "r.game_id", exportInt},
+			{"round_code", "r.round_code", exportString},
+			{"name", "r.name", exportString},
+			{"ordinal", "r.ordinal", exportInt},
+		},
+		From:    "rounds r JOIN games g ON g.game_id = r.game_id",
+		OrderBy: "r.game_id, r.ordinal",
+	},
+	{
+		Name: "categories",
+		Columns: []exportColumn{
+			{"category_id", "k.category_id", exportString},
+			{"game_id", "k.game_id", exportInt},
+			{"round_id", "k.round_id", exportString},
+			{"column_num", "k.column_num", exportInt},
+			{"name", "k.name", exportString},
+			{"name_html", "k.name_html", exportString},
+		},
+		From:    "categories k JOIN games g ON g.game_id = k.game_id JOIN rounds r ON r.round_id = k.round_id",
+		OrderBy: "k.game_id, r.ordinal, k.column_num",
+	},
+	{
+		Name: "clues",
+		Columns: []exportColumn{
+			{"clue_id", "c.clue_id", exportString},
+			{"game_id", "c.game_id", exportInt},
+			{"round_id", "c.round_id", exportString},
+			{"category_id", "c.category_id", exportString},
+			{"position", "c.position", exportString},
+			{"row_num", "c.row_num", exportInt},
+			{"value", "c.value", exportInt},
+			{"order_number", "c.order_number", exportInt},
+			{"daily_double", "c.daily_double", exportBool},
+			{"wager", "c.wager", exportInt},
+			{"triple_stumper", "c.triple_stumper", exportBool},
+			{"text", "c.text", exportString},
+			{"text_html", "c.text_html", exportString},
+			{"correct_response", "c.correct_response", exportString},
+			{"correct_response_html", "c.correct_response_html", exportString},
+			{"correct_contestant", "(SELECT x.nickname FROM responses x WHERE x.clue_id = c.clue_id AND x.correct = 1 ORDER BY x.seq LIMIT 1)", exportString},
+		},
+		From:    "clues c JOIN games g ON g.game_id = c.game_id JOIN rounds r ON r.round_id = c.round_id",
+		OrderBy: "c.game_id, r.ordinal, c.row_num, c.position",
+	},
+	{
+		Name: "responses",
+		Columns: []exportColumn{
+			{"clue_id", "x.clue_id", exportString},
+			{"seq", "x.seq", exportInt},
+			{"game_id", "x.game_id", exportInt},
+			{"nickname", "x.nickname", exportString},
+			{"player_id", "x.player_id", exportString},
+			{"correct", "x.correct", exportBool},
+		},
+		From:    "responses x JOIN games g ON g.game_id = x.game_id",
+		OrderBy: "x.game_id, x.clue_id, x.seq",
+	},
+	{
+		Name: "contestants",
+		Columns: []exportColumn{
+			{"game_id", "a.game_id", exportInt},
+			{"seat", "a.seat", exportInt},
+			{"player_id", "a.player_id", exportString},
+			{"name", "a.name", exportString},
+			{"nickname", "a.nickname", exportString},
+			{"bio", "a.bio", exportString},
+		},
+		From:    "appearances a JOIN games g ON g.game_id = a.game_id",
+		OrderBy: "a.game_id, a.seat",
+	},
+	{
+		Name: "final_jeopardy",
+		Columns: []exportColumn{
+			{"game_id", "f.game_id", exportInt},
+			{"round_code", "f.round_code", exportString},
+			{"seq", "f.seq", exportInt},
+			{"nickname", "f.nickname", exportString},
+			{"player_id", "f.player_id", exportString},
+			{"response", "f.response", exportString},
+			{"wager", "f.wager", exportInt},
+			{"correct", "f.correct", exportBool},
+		},
+		From:    "final_jeopardy f JOIN games g ON g.game_id = f.game_id",
+		OrderBy: "f.game_id, f.round_code, f.seq",
+	},
+}
+
+// exportFilters restricts an export to some games. Empty fields match everything.
+type exportFilters struct {
+	Season string
+	From   string
+	To     string
+}
+
+// register adds the filter flags to an export subcommand
+func (filters *exportFilters) register(flags *flag.FlagSet) {
+	flags.StringVar(&filters.Season, "season", "", "only export this season")
+	flags.StringVar(&filters.From, "from", "", "only games aired on or after this date (YYYY-MM-DD)")
+	flags.StringVar(&filters.To, "to", "", "only games aired on or before this date (YYYY-MM-DD)")
+}
+
+// where returns the SQL condition on games g for the filters
+func (filters exportFilters) where() (string, []interface{}) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+	if filters.Season != "" {
+		conditions = append(conditions, "g.season_id = ?")
+		args = append(args, filters.Season)
+	}
+	if filters.From != "" {
+		conditions = append(conditions, "g.air_date >= ?")
+		args = append(args, filters.From)
+	}
+	if filters.To != "" {
+		conditions = append(conditions, "g.air_date <= ?")
+		args = append(args, filters.To)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// selectExportTables picks the tables and columns to export. tables is a
+// comma-separated list of table names and columns a comma-separated list of
+// table.column; a table with no columns listed exports all of them.
+func selectExportTables(tables, columns string) ([]exportTable, error) {
+	byName := make(map[string]exportTable)
+	for _, table := range exportTables {
+		byName[table.Name] = table
+	}
+
+	// Copy so narrowing columns does not change exportTables
+	selected := append([]exportTable(nil), exportTables...)
+	if tables != "" {
+		selected = nil
+		for _, name := range strings.Split(tables, ",") {
+			table, ok := byName[strings.TrimSpace(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown table %q", name)
+			}
+			selected = append(selected, table)
+		}
+	}
+	if columns == "" {
+		return selected, nil
+	}
+
+	wanted := make(map[string][]string)
+	for _, qualified := range strings.Split(columns, ",") {
+		tableName, columnName, ok := strings.Cut(strings.TrimSpace(qualified), ".")
+		if !ok {
+			return nil, fmt.Errorf("column %q must be written as table.column", qualified)
+		}
+		if _, ok := byName[tableName]; !ok {
+			return nil, fmt.Errorf("unknown table %q", tableName)
+		}
+		wanted[tableName] = append(wanted[tableName], columnName)
+	}
+
+	for i, table := range selected {
+		names, ok := wanted[table.Name]
+		if !ok {
+			continue
+		}
+		var picked []exportColumn
+		for _, name := range names {
+			found := false
+			for _, column := range table.Columns {
+				if column.Name == name {
+					picked = append(picked, column)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unknown column %s.%s", table.Name, name)
+			}
+		}
+		selected[i].Columns = picked
+	}
+	return selected, nil
+}
+
+// queryExportTable streams the rows of an export table
+func queryExportTable(db *sql.DB, table exportTable, filters exportFilters) (*sql.Rows, error) {
+	expressions := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		expressions[i] = column.Expr
+	}
+	where, args := filters.where()
+	return db.Query(`SELECT `+strings.Join(expressions, ", ")+` FROM `+table.From+
+		` WHERE `+where+` ORDER BY `+table.OrderBy+`;`, args...)
+}
+
+// csvValue formats a scanned column value, writing NULL as an empty field
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		// DATE columns only ever hold a day
+		return v.Format("2006-01-02")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// exportTableCSV writes one table to a CSV file row by row
+func exportTableCSV(db *sql.DB, table exportTable, filters exportFilters, path string) (int, error) {
+	rows, err := queryExportTable(db, table, filters)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	header := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		header[i] = column.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return 0, err
+	}
+
+	values := make([]interface{}, len(table.Columns))
+	pointers := make([]interface{}, len(table.Columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	record := make([]string, len(table.Columns))
+
+	count := 0
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return count, err
+		}
+		for i, value := range values {
+			record[i] = csvValue(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return count, err
+	}
+	return count, file.Close()
+}
+
+// runExportCSV implements the export csv command
+//
+//	export csv [-out DIR] [-season S] [-from DATE] [-to DATE] [-tables T,...] [-columns table.column,...]
+func runExportCSV(db *sql.DB, args []string) {
+	flags := flag.NewFlagSet("export csv", flag.ExitOnError)
+	var filters exportFilters
+	filters.register(flags)
+	outDir := flags.String("out", "export", "directory to write the CSV files to")
+	tables := flags.String("tables", "", "comma-separated tables to export (default all)")
+	columns := flags.String("columns", "", "comma-separated table.column list; tables not listed keep all columns")
+	flags.Parse(args)
+
+	selected, err := selectExportTables(*tables, *columns)
+	if err != nil {
+		log.Fatalf("Invalid export selection: %v", err)
+	}
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		log.Fatalf("Failed to create %s: %v", *outDir, err)
+	}
+
+	for _, table := range selected {
+		path := filepath.Join(*outDir, table.Name+".csv")
+		count, err := exportTableCSV(db, table, filters, path)
+		if err != nil {
+			log.Fatalf("Failed to export %s: %v", table.Name, err)
+		}
+		fmt.Printf("Wrote %d rows to %s\n", count, path)
+	}
+}
+
+// runExport implements the export command. The schema describes the export
+// format rather than the data, so it is written without opening the database.
+//
+//	export csv|jsonl|parquet|schema [flags]
+func runExport(cfg Config, args []string) {
+	if len(args) == 0 {
+		log.Fatal("Usage: export csv|jsonl|parquet|schema [flags]")
+	}
+	if args[0] == "schema" {
+		runExportSchema(args[1:])
+		return
+	}
+
+	store := openStore(cfg)
+	defer store.Close()
+	switch args[0] {
+	case "csv":
+		runExportCSV(store.db, args[1:])
+	case "jsonl":
+		runExportJSONL(store, args[1:])
+	case "parquet":
+		runExportParquet(store.db, args[1:])
+	default:
+		log.Fatalf("Unknown export format %q", args[0])
+	}
+}
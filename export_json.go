@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// jsonExportVersion is written on every exported game. Bump it whenever a
+// field is renamed, removed or changes type; adding a field does not.
+const jsonExportVersion = 1
+
+// jsonGame is one line of a JSON Lines export. Its Rounds shadow the
+// embedded GameData.Rounds so clues are nested under their category.
+type jsonGame struct {
+	FormatVersion int    `json:"format_version"`
+	SeasonID      string `json:"season_id"`
+	GameData
+	Rounds []jsonRound `json:"rounds"`
+}
+
+type jsonRound struct {
+	Name       string         `json:"name"`
+	Categories []jsonCategory `json:"categories"`
+	// Clues whose position does not match a category
+	UnplacedClues []Clue `json:"unplaced_clues,omitempty"`
+}
+
+type jsonCategory struct {
+	Category
+	Column int    `json:"column"`
+	Clues  []Clue `json:"clues"`
+}
+
+// newJSONGame nests a game's clues under their categories
+func newJSONGame(seasonID string, game GameData) jsonGame {
+	exported := jsonGame{FormatVersion: jsonExportVersion, SeasonID: seasonID, GameData: game}
+	for _, round := range game.Rounds {
+		exportedRound := jsonRound{Name: round.Name, Categories: make([]jsonCategory, len(round.Categories))}
+		for i, category := range round.Categories {
+			exportedRound.Categories[i] = jsonCategory{Category: category, Column: i + 1, Clues: []Clue{}}
+		}
+		for _, clue := range round.Clues {
+			if _, column := clueCategory(round, clue); column > 0 {
+				exportedRound.Categories[column-1].Clues = append(exportedRound.Categories[column-1].Clues, clue)
+			} else {
+				exportedRound.UnplacedClues = append(exportedRound.UnplacedClues, clue)
+			}
+		}
+		exported.Rounds = append(exported.Rounds, exportedRound)
+	}
+	return exported
+}
+
+// exportJSONL writes one game per line, reading a game at a time from the store
+func exportJSONL(store *sqliteStore, filters exportFilters, path string) (int, error) {
+	where, args := filters.where()
+	rows, err := store.db.Query(`SELECT g.game_id, g.season_id FROM games g WHERE `+where+` ORDER BY g.air_date, g.game_id;`, args...)
+	if err != nil {
+		return 0, err
+	}
+	var gameIDs []int
+	var seasons []string
+	for rows.Next() {
+		var gameID int
+		var seasonID string
+		if err := rows.Scan(&gameID, &seasonID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		gameIDs = append(gameIDs, gameID)
+		seasons = append(seasons, seasonID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+
+	for i, gameID := range gameIDs {
+		game, err := store.GetGame(gameID)
+		if err != nil {
+			return i, err
+		}
+		if err := encoder.Encode(newJSONGame(seasons[i], game)); err != nil {
+			return i, err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return len(gameIDs), err
+	}
+	return len(gameIDs), file.Close()
+}
+
+// jsonSchema builds a JSON Schema for the export format from the Go types,
+// so the schema cannot drift from what is written
+func jsonSchema() map[string]interface{} {
+	definitions := make(map[string]interface{})
+	root := schemaFor(reflect.TypeOf(jsonGame{}), definitions)
+	return map[string]interface{}{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         fmt.Sprintf("https://github.com/tlegnard/answer-there/schema/game-v%d.schema.json", jsonExportVersion),
+		"title":       "J-Archive game",
+		"description": fmt.Sprintf("One line of a JSON Lines export, format version %d", jsonExportVersion),
+		"$ref":        root["$ref"],
+		"$defs":       definitions,
+	}
+}
+
+// schemaFor returns the schema of a type, adding named structs to definitions
+func schemaFor(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Slice:
+		// encoding/json writes nil slices as null
+		return map[string]interface{}{"type": []string{"array", "null"}, "items": schemaFor(t.Elem(), definitions)}
+	case reflect.Struct:
+		name := strings.TrimPrefix(t.Name(), "json")
+		ref := map[string]interface{}{"$ref": "#/$defs/" + name}
+		if _, ok := definitions[name]; ok {
+			return ref
+		}
+		// Reserve the name first so recursive types terminate
+		definitions[name] = nil
+		properties := make(map[string]interface{})
+		var required []string
+		addStructFields(t, properties, &required, definitions)
+		definitions[name] = map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+		return ref
+	default:
+		panic(fmt.Sprintf("no JSON schema for %s", t))
+	}
+}
+
+// addStructFields adds a struct's JSON fields to properties. Embedded structs
+// are flattened after the outer fields and, as in encoding/json, an outer
+// field wins over an embedded field with the same name.
+func addStructFields(t reflect.Type, properties map[string]interface{}, required *[]string, definitions map[string]interface{}) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			embedded = append(embedded, field.Type)
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, shadowed := properties[name]; shadowed {
+			continue
+		}
+		properties[name] = schemaFor(field.Type, definitions)
+		if options != "omitempty" {
+			*required = append(*required, name)
+		}
+	}
+
+	for _, inner := range embedded {
+		addStructFields(inner, properties, required, definitions)
+	}
+}
+
+// runExportJSONL implements the export jsonl command
+//
+//	export jsonl [-out FILE] [-season S] [-from DATE] [-to DATE]
+func runExportJSONL(store *sqliteStore, args []string) {
+	flags := flag.NewFlagSet("export jsonl", flag.ExitOnError)
+	var filters exportFilters
+	filters.register(flags)
+	out := flags.String("out", "games.jsonl", "file to write")
+	flags.Parse(args)
+
+	count, err := exportJSONL(store, filters, *out)
+	if err != nil {
+		log.Fatalf("Failed to export JSON Lines: %v", err)
+	}
+	fmt.Printf("Wrote %d games to %s (format version %d)\n", count, *out, jsonExportVersion)
+}
+
+// runExportSchema implements the export schema command, which prints the
+// JSON Schema of the JSON Lines export
+//
+//	export schema [-out FILE]
+func runExportSchema(args []string) {
+	flags := flag.NewFlagSet("export schema", flag.ExitOnError)
+	out := flags.String("out", "", "file to write (default stdout)")
+	flags.Parse(args)
+
+	data, err := json.MarshalIndent(jsonSchema(), "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to generate schema: %v", err)
+	}
+	data = append(data, '\n')
+
+	if *out == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*out, data, 0644); err != nil {
+		log.Fatalf("Failed to write schema: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewJSONGame(t *testing.T) {
+	game := GameData{ID: 7, ShowNum: 8001, AirDate: "2019-05-01", Rounds: []Round{
+		{
+			Name:       roundJeopardy,
+			Categories: []Category{{Name: "RIVERS"}, {Name: "LAKES"}, {Name: "SEAS"}},
+			Clues: []Clue{
+				{Position: "J_1_1", Text: "It flows through Cairo"},
+				{Position: "J_2_1", Text: "The largest Great Lake"},
+				{Position: "J_1_2", Text: "It flows through Baghdad"},
+				{Position: "J_6_1", Text: "A clue past the last category"},
+			},
+		},
+		{
+			Name:       roundFinalJeopardy,
+			Categories: []Category{{Name: "OCEANS"}},
+			Clues:      []Clue{{Position: "FJ", Text: "The deepest ocean"}},
+		},
+	}}
+
+	exported := newJSONGame("35", game)
+	if exported.FormatVersion != jsonExportVersion || exported.SeasonID != "35" || exported.ID != 7 {
+		t.Errorf("newJSONGame = version %d, season %q, game %d", exported.FormatVersion, exported.SeasonID, exported.ID)
+	}
+	want := []jsonRound{
+		{
+			Name: roundJeopardy,
+			Categories: []jsonCategory{
+				{Category: Category{Name: "RIVERS"}, Column: 1, Clues: []Clue{
+					{Position: "J_1_1", Text: "It flows through Cairo"},
+					{Position: "J_1_2", Text: "It flows through Baghdad"},
+				}},
+				{Category: Category{Name: "LAKES"}, Column: 2, Clues: []Clue{{Position: "J_2_1", Text: "The largest Great Lake"}}},
+				// An empty category is written as an empty list rather than null
+				{Category: Category{Name: "SEAS"}, Column: 3, Clues: []Clue{}},
+			},
+			UnplacedClues: []Clue{{Position: "J_6_1", Text: "A clue past the last category"}},
+		},
+		{
+			Name: roundFinalJeopardy,
+			Categories: []jsonCategory{
+				{Category: Category{Name: "OCEANS"}, Column: 1, Clues: []Clue{{Position: "FJ", Text: "The deepest ocean"}}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(exported.Rounds, want) {
+		t.Errorf("newJSONGame rounds = %+v, want %+v", exported.Rounds, want)
+	}
+
+	// The nested rounds replace the flat ones in the encoded game
+	data, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Rounds []struct {
+			Categories []struct {
+				Name  string            `json:"name"`
+				Clues []json.RawMessage `json:"clues"`
+			} `json:"categories"`
+			Clues []json.RawMessage `json:"clues"`
+		} `json:"rounds"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Rounds) != 2 || len(decoded.Rounds[0].Categories) != 3 || len(decoded.Rounds[0].Categories[0].Clues) != 2 {
+		t.Fatalf("encoded game = %s", data)
+	}
+	if decoded.Rounds[0].Clues != nil {
+		t.Errorf("encoded round still lists its clues flat: %s", data)
+	}
+}
+
+// TestExportSchemaWithoutDatabase checks that export schema neither needs
+// nor creates a database
+func TestExportSchemaWithoutDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "jeopardy.db")
+	out := filepath.Join(dir, "schema.json")
+	runExport(Config{DB: dbPath}, []string{"schema", "-out", out})
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Errorf("schema is not JSON: %v", err)
+	}
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Errorf("export schema created the database: %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// parquetWriterGoroutines is how many goroutines each Parquet writer uses to
+// encode columns
+const parquetWriterGoroutines = 4
+
+// parquetPartitionColumn is the column the Parquet export partitions by. It
+// is kept out of the files, since readers take it from the directory names.
+const parquetPartitionColumn = "season_id"
+
+// withoutColumn returns table without the named column
+func withoutColumn(table exportTable, name string) exportTable {
+	columns := make([]exportColumn, 0, len(table.Columns))
+	for _, column := range table.Columns {
+		if column.Name != name {
+			columns = append(columns, column)
+		}
+	}
+	table.Columns = columns
+	return table
+}
+
+// parquetSchema describes a table's columns in the metadata format the
+// Parquet writer expects. Every column is optional because any of them may
+// be NULL in the database.
+func parquetSchema(table exportTable) []string {
+	schema := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		var physical string
+		switch column.Type {
+		case exportInt:
+			physical = "type=INT64"
+		case exportBool:
+			physical = "type=BOOLEAN"
+		case exportDate:
+			physical = "type=INT32, convertedtype=DATE"
+		default:
+			physical = "type=BYTE_ARRAY, convertedtype=UTF8"
+		}
+		schema[i] = fmt.Sprintf("name=%s, %s, repetitiontype=OPTIONAL", column.Name, physical)
+	}
+	return schema
+}
+
+// parquetValue converts a scanned column value to the Go type the Parquet
+// writer expects for the column, or nil for NULL
+func parquetValue(value interface{}, columnType string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch columnType {
+	case exportInt:
+		if v, ok := value.(int64); ok {
+			return v, nil
+		}
+	case exportBool:
+		switch v := value.(type) {
+		case int64:
+			return v != 0, nil
+		case bool:
+			return v, nil
+		}
+	case exportDate:
+		// DATE is stored as days since the Unix epoch
+		switch v := value.(type) {
+		case time.Time:
+			return int32(v.Unix() / 86400), nil
+		case string:
+			day, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				return nil, err
+			}
+			return int32(day.Unix() / 86400), nil
+		}
+	default:
+		return csvValue(value), nil
+	}
+	return nil, fmt.Errorf("unexpected %T value %v for %s column", value, value, columnType)
+}
+
+// exportTableParquet writes one table, restricted by filters, to a Parquet file
+func exportTableParquet(db *sql.DB, table exportTable, filters exportFilters, path string) (int, error) {
+	rows, err := queryExportTable(db, table, filters)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+	file, err := local.NewLocalFileWriter(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	parquetWriter, err := writer.NewCSVWriter(parquetSchema(table), file, parquetWriterGoroutines)
+	if err != nil {
+		return 0, err
+	}
+
+	values := make([]interface{}, len(table.Columns))
+	pointers := make([]interface{}, len(table.Columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	count := 0
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return count, err
+		}
+		record := make([]interface{}, len(table.Columns))
+		for i, value := range values {
+			if record[i], err = parquetValue(value, table.Columns[i].Type); err != nil {
+				return count, fmt.Errorf("column %s: %v", table.Columns[i].Name, err)
+			}
+		}
+		if err := parquetWriter.Write(record); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+
+	if err := parquetWriter.WriteStop(); err != nil {
+		return count, err
+	}
+	return count, file.Close()
+}
+
+// exportTablePartitions writes one Parquet file of table per season under
+// <outDir>/<table>/season_id=<season>, leaving the partition column out of
+// the files, and returns the number of rows written
+func exportTablePartitions(db *sql.DB, table exportTable, filters exportFilters, seasons []string, outDir string) (int, error) {
+	table = withoutColumn(table, parquetPartitionColumn)
+	if len(table.Columns) == 0 {
+		return 0, fmt.Errorf("no columns to export besides %s, which is written as the partition", parquetPartitionColumn)
+	}
+	total := 0
+	for _, seasonID := range seasons {
+		seasonFilters := filters
+		seasonFilters.Season = seasonID
+		path := filepath.Join(outDir, table.Name, parquetPartitionColumn+"="+seasonID, "part-0.parquet")
+		count, err := exportTableParquet(db, table, seasonFilters, path)
+		if err != nil {
+			return total, fmt.Errorf("season %s: %v", seasonID, err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// exportSeasons lists the seasons with games matching filters
+func exportSeasons(db *sql.DB, filters exportFilters) ([]string, error) {
+	where, args := filters.where()
+	rows, err := db.Query(`SELECT DISTINCT g.season_id FROM games g WHERE `+where+` ORDER BY g.season_id;`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var seasons []string
+	for rows.Next() {
+		var seasonID string
+		if err := rows.Scan(&seasonID); err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, seasonID)
+	}
+	return seasons, rows.Err()
+}
+
+// runExportParquet implements the export parquet command. Files are
+// partitioned by season as <out>/<table>/season_id=<season>/part-0.parquet,
+// the layout pandas, Spark and Superset read as a single dataset. The
+// season_id column is only in the directory names.
+//
+//	export parquet [-out DIR] [-season S] [-from DATE] [-to DATE] [-tables T,...] [-columns table.column,...]
+func runExportParquet(db *sql.DB, args []string) {
+	flags := flag.NewFlagSet("export parquet", flag.ExitOnError)
+	var filters exportFilters
+	filters.register(flags)
+	outDir := flags.String("out", "export", "directory to write the Parquet files to")
+	tables := flags.String("tables", "", "comma-separated tables to export (default all)")
+	columns := flags.String("columns", "", "comma-separated table.column list; tables not listed keep all columns")
+	flags.Parse(args)
+
+	selected, err := selectExportTables(*tables, *columns)
+	if err != nil {
+		log.Fatalf("Invalid export selection: %v", err)
+	}
+	seasons, err := exportSeasons(db, filters)
+	if err != nil {
+		log.Fatalf("Failed to list seasons: %v", err)
+	}
+
+	for _, table := range selected {
+		total, err := exportTablePartitions(db, table, filters, seasons, *outDir)
+		if err != nil {
+			log.Fatalf("Failed to export %s: %v", table.Name, err)
+		}
+		fmt.Printf("Wrote %d %s rows in %d seasons to %s\n", total, table.Name, len(seasons), filepath.Join(*outDir, table.Name))
+	}
+}
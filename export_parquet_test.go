@@ -0,0 +1,114 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+func TestWithoutColumn(t *testing.T) {
+	table := exportTable{Name: "games", Columns: []exportColumn{{Name: "game_id"}, {Name: "season_id"}, {Name: "air_date"}}}
+	got := withoutColumn(table, "season_id")
+	if want := []exportColumn{{Name: "game_id"}, {Name: "air_date"}}; !reflect.DeepEqual(got.Columns, want) {
+		t.Errorf("withoutColumn = %v, want %v", got.Columns, want)
+	}
+	if len(table.Columns) != 3 {
+		t.Errorf("withoutColumn changed the original table to %v", table.Columns)
+	}
+}
+
+// parquetColumns reads the column names and row count of a Parquet file
+func parquetColumns(t *testing.T, path string) ([]string, int64) {
+	t.Helper()
+	file, err := local.NewLocalFileReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	parquetReader, err := reader.NewParquetReader(file, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer parquetReader.ReadStop()
+
+	// The reader renames columns in memory; ExName is the name in the file.
+	// The first schema element is the root.
+	var names []string
+	for _, info := range parquetReader.SchemaHandler.Infos[1:] {
+		names = append(names, info.ExName)
+	}
+	return names, parquetReader.GetNumRows()
+}
+
+func TestExportTablePartitions(t *testing.T) {
+	store, err := newSQLiteStore(filepath.Join(t.TempDir(), "jeopardy.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for seasonID, game := range map[string]GameData{
+		"34": {ID: 1, ShowNum: 7001, AirDate: "2018-05-01"},
+		"35": {ID: 2, ShowNum: 8001, AirDate: "2019-05-01"},
+	} {
+		if err := store.SaveGame(seasonID, game); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		columns string
+		want    []string
+		wantErr bool
+	}{
+		{"all columns", "", []string{"game_id", "show_num", "air_date"}, false},
+		{"selected columns", "games.game_id,games.season_id", []string{"game_id"}, false},
+		{"only the partition column", "games.season_id", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := selectExportTables("games", tt.columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outDir := t.TempDir()
+			total, err := exportTablePartitions(store.db, selected[0], exportFilters{}, []string{"34", "35"}, outDir)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("exportTablePartitions succeeded without columns to write")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != 2 {
+				t.Errorf("wrote %d rows, want 2", total)
+			}
+
+			for _, seasonID := range []string{"34", "35"} {
+				names, rows := parquetColumns(t, filepath.Join(outDir, "games", "season_id="+seasonID, "part-0.parquet"))
+				for _, name := range names {
+					if name == parquetPartitionColumn {
+						t.Errorf("season %s file has the partition column: %v", seasonID, names)
+					}
+				}
+				if rows != 1 {
+					t.Errorf("season %s file has %d rows, want 1", seasonID, rows)
+				}
+				for _, want := range tt.want {
+					found := false
+					for _, name := range names {
+						found = found || name == want
+					}
+					if !found {
+						t.Errorf("season %s file columns %v lack %s", seasonID, names, want)
+					}
+				}
+			}
+		})
+	}
+}
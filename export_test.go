@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSelectExportTables(t *testing.T) {
+	tests := []struct {
+		name    string
+		tables  string
+		columns string
+		// want is the names of the selected tables, in order
+		want    []string
+		wantErr bool
+	}{
+		{"everything", "", "", []string{"games", "rounds", "categories", "clues", "responses", "contestants", "final_jeopardy"}, false},
+		{"some tables", "clues, games", "", []string{"clues", "games"}, false},
+		{"unknown table", "games,scores", "", nil, true},
+		{"unknown column table", "", "scores.total", nil, true},
+		{"unknown column", "games", "games.host", nil, true},
+		{"unqualified column", "games", "game_id", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := selectExportTables(tt.tables, tt.columns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectExportTables(%q, %q) error = %v, want error %v", tt.tables, tt.columns, err, tt.wantErr)
+			}
+			var names []string
+			for _, table := range selected {
+				names = append(names, table.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("selectExportTables(%q, %q) = %q, want %q", tt.tables, tt.columns, names, tt.want)
+			}
+		})
+	}
+
+	selected, err := selectExportTables("games,clues", "clues.text,clues.clue_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected[0].Columns) != len(exportTables[0].Columns) {
+		t.Errorf("games has %d columns, want all %d", len(selected[0].Columns), len(exportTables[0].Columns))
+	}
+	if got := selected[1].Columns; len(got) != 2 || got[0].Name != "text" || got[1].Name != "clue_id" {
+		t.Errorf("clues columns = %+v, want text and clue_id", got)
+	}
+	if len(exportTables[3].Columns) == 2 {
+		t.Error("selecting columns changed exportTables")
+	}
+}
+
+// exportGames are the games the export tests write, one in each of two seasons
+var exportGames = map[string]GameData{
+	"34": {ID: 1, ShowNum: 7001, AirDate: "2018-05-01", Rounds: []Round{{
+		Name:       roundJeopardy,
+		Categories: []Category{{Name: "RIVERS"}},
+		Clues:      []Clue{{Position: "J_1_1", Value: "$200", Text: "It flows through Cairo", CorrectResponse: "the Nile"}},
+	}}},
+	"35": {ID: 2, ShowNum: 8001, AirDate: "2019-05-01", Rounds: []Round{{
+		Name:       roundJeopardy,
+		Categories: []Category{{Name: "LAKES"}},
+		Clues: []Clue{
+			{Position: "J_1_1", Value: "$200", Text: "The largest of the Great Lakes, \"superior\" to the rest", CorrectResponse: "Lake Superior"},
+			{Position: "J_1_2", Value: "$400", Text: "Its name means \"big water\", roughly", CorrectResponse: "Lake Michigan"},
+		},
+	}}},
+}
+
+// openExportDatabase returns a migrated database holding exportGames
+func openExportDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+	db := openTestDatabase(t)
+	if err := migrateDatabase(db); err != nil {
+		t.Fatal(err)
+	}
+	for seasonID, game := range exportGames {
+		if _, err := writeGameBatch(db, seasonID, []GameData{game}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// readCSV reads every record of a CSV file, header first
+func readCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	return records
+}
+
+func TestRunExportCSV(t *testing.T) {
+	db := openExportDatabase(t)
+	tests := []struct {
+		name string
+		args []string
+		// want maps each file written to its records, header first
+		want map[string][][]string
+	}{
+		{"selected columns", []string{"-tables", "games,clues", "-columns", "games.game_id,games.air_date,clues.clue_id,clues.value,clues.text"},
+			map[string][][]string{
+				"games.csv": {{"game_id", "air_date"}, {"1", "2018-05-01"}, {"2", "2019-05-01"}},
+				"clues.csv": {
+					{"clue_id", "value", "text"},
+					{"1-J_1_1", "200", "It flows through Cairo"},
+					{"2-J_1_1", "200", "The largest of the Great Lakes, \"superior\" to the rest"},
+					{"2-J_1_2", "400", "Its name means \"big water\", roughly"},
+				},
+			}},
+		{"season", []string{"-season", "34", "-tables", "games,categories", "-columns", "games.game_id,games.season_id,categories.name"},
+			map[string][][]string{
+				"games.csv":      {{"game_id", "season_id"}, {"1", "34"}},
+				"categories.csv": {{"name"}, {"RIVERS"}},
+			}},
+		{"from", []string{"-from", "2019-01-01", "-tables", "clues", "-columns", "clues.clue_id,clues.correct_response"},
+			map[string][][]string{
+				"clues.csv": {{"clue_id", "correct_response"}, {"2-J_1_1", "Lake Superior"}, {"2-J_1_2", "Lake Michigan"}},
+			}},
+		{"to", []string{"-to", "2018-12-31", "-tables", "clues", "-columns", "clues.clue_id"},
+			map[string][][]string{
+				"clues.csv": {{"clue_id"}, {"1-J_1_1"}},
+			}},
+		{"no games match", []string{"-season", "40", "-tables", "games", "-columns", "games.game_id"},
+			map[string][][]string{
+				"games.csv": {{"game_id"}},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outDir := filepath.Join(t.TempDir(), "export")
+			runExportCSV(db, append([]string{"-out", outDir}, tt.args...))
+
+			entries, err := os.ReadDir(outDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string][][]string)
+			for _, entry := range entries {
+				got[entry.Name()] = readCSV(t, filepath.Join(outDir, entry.Name()))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("export csv %q wrote %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRunExportCSVAllColumns checks that every column of every table is
+// written when nothing is selected
+func TestRunExportCSVAllColumns(t *testing.T) {
+	db := openExportDatabase(t)
+	outDir := t.TempDir()
+	runExportCSV(db, []string{"-out", outDir})
+
+	rows := map[string]int{"games": 2, "rounds": 2, "categories": 2, "clues": 3, "responses": 0, "contestants": 0, "final_jeopardy": 0}
+	for _, table := range exportTables {
+		records := readCSV(t, filepath.Join(outDir, table.Name+".csv"))
+		var header []string
+		for _, column := range table.Columns {
+			header = append(header, column.Name)
+		}
+		if len(records) == 0 || !reflect.DeepEqual(records[0], header) {
+			t.Errorf("%s.csv header = %q, want %q", table.Name, records, header)
+			continue
+		}
+		if got := len(records) - 1; got != rows[table.Name] {
+			t.Errorf("%s.csv has %d rows, want %d", table.Name, got, rows[table.Name])
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Stages of the pipeline a game can fail in
+const (
+	failureFetch = "fetch"
+	failureParse = "parse"
+	failureStore = "store"
+	failurePanic = "panic"
+)
+
+// gameError is a failure to scrape one game
+type gameError struct {
+	GameID   int
+	SeasonID string
+	Stage    string
+	Err      error
+}
+
+func (e *gameError) Error() string {
+	return fmt.Sprintf("game %d in season %s: %s failed: %v", e.GameID, e.SeasonID, e.Stage, e.Err)
+}
+
+func (e *gameError) Unwrap() error {
+	return e.Err
+}
+
+// gameErrors extracts the per-game failures from an error, which may join
+// several of them. Errors that are not about one game are returned as rest.
+func gameErrors(err error) (failures []*gameError, rest []error) {
+	if err == nil {
+		return nil, nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, inner := range joined.Unwrap() {
+			innerFailures, innerRest := gameErrors(inner)
+			failures = append(failures, innerFailures...)
+			rest = append(rest, innerRest...)
+		}
+		return failures, rest
+	}
+	var failure *gameError
+	if errors.As(err, &failure) {
+		return []*gameError{failure}, nil
+	}
+	return nil, []error{err}
+}
+
+// GameFailure is a game that failed and has not been stored since
+type GameFailure struct {
+	GameID        int
+	SeasonID      string
+	Stage         string
+	Reason        string
+	Attempts      int
+	FirstFailedAt time.Time
+	LastFailedAt  time.Time
+}
+
+func (s *sqliteStore) RecordFailures(failures []*gameError) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	for _, failure := range failures {
+		_, err := tx.Exec(`
+			INSERT INTO failed_games (game_id, season_id, stage, reason, attempts, first_failed_at, last_failed_at)
+			VALUES (?, ?, ?, ?, 1, ?, ?)
+			ON CONFLICT (game_id) DO UPDATE SET
+				season_id = excluded.season_id,
+				stage = excluded.stage,
+				reason = excluded.reason,
+				attempts = attempts + 1,
+				last_failed_at = excluded.last_failed_at;`,
+			failure.GameID, failure.SeasonID, failure.Stage, failure.Err.Error(), now, now)
+		if err != nil {
+			return fmt.Errorf("failed to record failure of game %d: %v", failure.GameID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) ClearFailures(gameIDs []int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, gameID := range gameIDs {
+		if _, err := tx.Exec(`DELETE FROM failed_games WHERE game_id = ?;`, gameID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) Failures() ([]GameFailure, error) {
+	rows, err := s.db.Query(`
+		SELECT game_id, season_id, stage, reason, attempts, first_failed_at, last_failed_at
+		FROM failed_games ORDER BY season_id, game_id;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var failures []GameFailure
+	for rows.Next() {
+		var failure GameFailure
+		if err := rows.Scan(&failure.GameID, &failure.SeasonID, &failure.Stage, &failure.Reason, &failure.Attempts,
+			&failure.FirstFailedAt, &failure.LastFailedAt); err != nil {
+			return nil, err
+		}
+		failures = append(failures, failure)
+	}
+	return failures, rows.Err()
+}
+
+// printFailures lists failed games under a season summary
+func printFailures(failures []*gameError) {
+	sort.Slice(failures, func(i, j int) bool { return failures[i].GameID < failures[j].GameID })
+	for _, failure := range failures {
+		fmt.Printf("  game %d: %s failed: %v\n", failure.GameID, failure.Stage, failure.Err)
+	}
+}
+
+// runRetryFailed implements the retry-failed command, which runs failed
+// games through the pipeline again. Games that have failed max-attempts
+// times are left alone until -max-attempts is raised. The cached page of a
+// game that failed to parse is fetched again in case it was truncated.
+//
+//	retry-failed [-season S] [-max-attempts N] [-list]
+func runRetryFailed(ctx context.Context, cfg Config, args []string) {
+	flags := flag.NewFlagSet("retry-failed", flag.ExitOnError)
+	seasonID := flags.String("season", "", "only retry games from this season")
+	maxAttempts := flags.Int("max-attempts", 3, "skip games that have already failed this many times")
+	listOnly := flags.Bool("list", false, "list the failed games without retrying them")
+	flags.Parse(args)
+
+	if !*listOnly {
+		lock := lockRun(cfg)
+		defer lock.Release()
+	}
+	store := openStore(cfg)
+	defer store.Close()
+
+	failures, err := store.Failures()
+	if err != nil {
+		log.Fatalf("Failed to read failed games: %v", err)
+	}
+
+	bySeason := make(map[string][]int)
+	var seasons []string
+	given := 0
+	for _, failure := range failures {
+		if *seasonID != "" && failure.SeasonID != *seasonID {
+			continue
+		}
+		if *listOnly {
+			fmt.Printf("game %d  season %s  %s failed %d times, last %s: %s\n", failure.GameID, failure.SeasonID, failure.Stage,
+				failure.Attempts, failure.LastFailedAt.Local().Format("2006-01-02 15:04:05"), failure.Reason)
+			continue
+		}
+		if failure.Attempts >= *maxAttempts {
+			given++
+			continue
+		}
+		if failure.Stage == failureParse {
+			path := filepath.Join(cfg.DataDir, "season_"+failure.SeasonID, cachedGameFilename(failure.GameID, failure.SeasonID))
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to remove cached page of game %d: %v", failure.GameID, err)
+			}
+		}
+		if _, ok := bySeason[failure.SeasonID]; !ok {
+			seasons = append(seasons, failure.SeasonID)
+		}
+		bySeason[failure.SeasonID] = append(bySeason[failure.SeasonID], failure.GameID)
+	}
+	if *listOnly {
+		return
+	}
+	if given > 0 {
+		fmt.Printf("Skipping %d games that failed %d or more times\n", given, *maxAttempts)
+	}
+	if len(seasons) == 0 {
+		fmt.Println("No failed games to retry")
+		return
+	}
+
+	state, err := loadProcessingState(cfg.StateFile)
+	if err != nil {
+		log.Fatalf("Failed to load processing state: %v", err)
+	}
+	run := startRunLedger(store, "retry-failed", args)
+	defer run.finish(ctx)
+	pages := newFetcher(cfg, run)
+	for _, season := range seasons {
+		if ctx.Err() != nil {
+			fmt.Println("\nInterrupted before every failed game was retried")
+			return
+		}
+		fmt.Printf("\nRetrying Season: %s (%d games)\n", season, len(bySeason[season]))
+		scrapeGames(ctx, cfg, store, pages, &state, run, season, bySeason[season])
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRecordFailures(t *testing.T) {
+	stores := map[string]func(t *testing.T) PipelineStore{
+		"memory": func(t *testing.T) PipelineStore { return newMemoryStore() },
+		"sqlite": func(t *testing.T) PipelineStore { return openTestStore(t, nil) },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			fetchFailed := &gameError{GameID: 2, SeasonID: "35", Stage: failureFetch, Err: errors.New("timed out")}
+			if err := store.RecordFailures([]*gameError{fetchFailed}); err != nil {
+				t.Fatal(err)
+			}
+			first, err := store.Failures()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A second failure counts another attempt and keeps the latest reason
+			parseFailed := &gameError{GameID: 2, SeasonID: "35", Stage: failureParse, Err: errors.New("no rounds")}
+			otherFailed := &gameError{GameID: 5, SeasonID: "34", Stage: failureStore, Err: errors.New("locked")}
+			if err := store.RecordFailures([]*gameError{parseFailed, otherFailed}); err != nil {
+				t.Fatal(err)
+			}
+			failures, err := store.Failures()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(failures) != 2 {
+				t.Fatalf("failures = %+v, want games 5 and 2", failures)
+			}
+			other, again := failures[0], failures[1]
+			if other.GameID != 5 || other.Attempts != 1 || other.Stage != failureStore || other.Reason != "locked" {
+				t.Errorf("game 5 failure = %+v", other)
+			}
+			if again.GameID != 2 || again.Attempts != 2 || again.Stage != failureParse || again.Reason != "no rounds" {
+				t.Errorf("game 2 failure = %+v, want a second parse failure", again)
+			}
+			if !again.FirstFailedAt.Equal(first[0].FirstFailedAt) || again.LastFailedAt.Before(again.FirstFailedAt) {
+				t.Errorf("game 2 failed first at %v and last at %v, first recorded at %v",
+					again.FirstFailedAt, again.LastFailedAt, first[0].FirstFailedAt)
+			}
+
+			// Storing a game clears only its own failure
+			if err := store.ClearFailures([]int{2, 9}); err != nil {
+				t.Fatal(err)
+			}
+			failures, err = store.Failures()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(failures) != 1 || failures[0].GameID != 5 {
+				t.Errorf("failures after clearing game 2 = %+v", failures)
+			}
+		})
+	}
+}
+
+// TestRetryFailed checks that retry-failed skips games that have failed
+// max-attempts times and clears the failure of a game once it is stored
+func TestRetryFailed(t *testing.T) {
+	archive, server := newTestArchive(t, []string{"36", "35"}, testSeasons)
+	cfg := testConfig(t, server)
+	store, err := newSQLiteStore(cfg.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	failures := []*gameError{
+		{GameID: 1, SeasonID: "35", Stage: failureFetch, Err: errors.New("timed out")},
+		{GameID: 2, SeasonID: "35", Stage: failureFetch, Err: errors.New("timed out")},
+		{GameID: 3, SeasonID: "36", Stage: failureFetch, Err: errors.New("timed out")},
+	}
+	for attempt := 0; attempt < 3; attempt++ {
+		if err := store.RecordFailures(failures[:1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.RecordFailures(failures[1:]); err != nil {
+		t.Fatal(err)
+	}
+
+	// Game 3 still fails and is counted again; game 1 has failed too often
+	archive.setFailing(3, true)
+	runRetryFailed(context.Background(), cfg, []string{"-max-attempts", "3"})
+
+	if got, want := storedGameIDs(t, store), []int{2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored games %v, want %v", got, want)
+	}
+	remaining, err := store.Failures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	attempts := make(map[int]int)
+	for _, failure := range remaining {
+		attempts[failure.GameID] = failure.Attempts
+	}
+	if want := map[int]int{1: 3, 3: 2}; !reflect.DeepEqual(attempts, want) {
+		t.Errorf("failed games and attempts = %v, want %v", attempts, want)
+	}
+	if n := archive.requests("/showgame.php?game_id=1"); n != 0 {
+		t.Errorf("game 1 fetched %d times after failing max-attempts times", n)
+	}
+
+	// Raising the cutoff retries game 1
+	archive.setFailing(3, false)
+	runRetryFailed(context.Background(), cfg, []string{"-max-attempts", "4"})
+	if got, want := storedGameIDs(t, store), []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored games %v, want %v", got, want)
+	}
+	if remaining, err := store.Failures(); err != nil || len(remaining) != 0 {
+		t.Errorf("failures %+v after every game was stored: %v", remaining, err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Formatting tags kept in sanitized HTML. Everything else is unwrapped to its
+// contents and all attributes are dropped.
+var allowedFormattingTags = map[atom.Atom]bool{
+	atom.I:      true,
+	atom.Em:     true,
+	atom.B:      true,
+	atom.Strong: true,
+	atom.U:      true,
+	atom.Br:     true,
+	atom.Sub:    true,
+	atom.Sup:    true,
+}
+
+// normalizeText applies NFC normalization and collapses all Unicode
+// whitespace, including non-breaking spaces, into single spaces
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(norm.NFC.String(text)), " ")
+}
+
+// plainText returns the normalized text of a selection, treating <br> as a space
+func plainText(sel *goquery.Selection) string {
+	var b strings.Builder
+	for _, node := range sel.Nodes {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			writePlainText(&b, child)
+		}
+		b.WriteString(" ")
+	}
+	return normalizeText(b.String())
+}
+
+func writePlainText(b *strings.Builder, node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		b.WriteString(node.Data)
+	case html.ElementNode:
+		if node.DataAtom == atom.Script || node.DataAtom == atom.Style {
+			return
+		}
+		if node.DataAtom == atom.Br {
+			b.WriteString(" ")
+			return
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			writePlainText(b, child)
+		}
+	}
+}
+
+// sanitizedHTML returns the contents of a selection with only formatting tags
+// kept, so titles in italics, underlined emphasis and line breaks survive
+func sanitizedHTML(sel *goquery.Selection) string {
+	var b strings.Builder
+	for _, node := range sel.Nodes {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			writeSanitizedHTML(&b, child)
+		}
+		b.WriteString(" ")
+	}
+	// Tags are written without attributes, so collapsing whitespace only touches text
+	return normalizeText(b.String())
+}
+
+func writeSanitizedHTML(b *strings.Builder, node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		b.WriteString(html.EscapeString(node.Data))
+	case html.ElementNode:
+		switch {
+		case node.DataAtom == atom.Script || node.DataAtom == atom.Style:
+			return
+		case node.DataAtom == atom.Br:
+			b.WriteString("<br>")
+			return
+		case allowedFormattingTags[node.DataAtom]:
+			b.WriteString("<" + node.Data + ">")
+			for child := node.FirstChild; child != nil; child = child.NextSibling {
+				writeSanitizedHTML(b, child)
+			}
+			b.WriteString("</" + node.Data + ">")
+		default:
+			for child := node.FirstChild; child != nil; child = child.NextSibling {
+				writeSanitizedHTML(b, child)
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// fragment parses an HTML fragment and selects the element with id "x"
+func fragment(t *testing.T, body string) *goquery.Selection {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><body>" + body + "</body></html>"))
+	if err != nil {
+		t.Fatalf("parsing %q: %v", body, err)
+	}
+	return doc.Find("#x")
+}
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"trims", "  word  ", "word"},
+		{"collapses runs", "one \t two\n\nthree", "one two three"},
+		{"non-breaking space", "one\u00a0two", "one two"},
+		{"composes accents", "cafe\u0301", "café"},
+		{"already composed", "café", "café"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeText(tt.in); got != tt.want {
+				t.Errorf("normalizeText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"text", `<td id="x">A clue</td>`, "A clue"},
+		{"formatting dropped", `<td id="x">This <i>Book</i> is <u>here</u></td>`, "This Book is here"},
+		{"br is a space", `<td id="x">line one<br />line two</td>`, "line one line two"},
+		{"script skipped", `<td id="x">seen<script>hidden()</script></td>`, "seen"},
+		{"entities decoded", `<td id="x">Tom &amp; Jerry&nbsp;&quot;cat&quot;</td>`, `Tom & Jerry "cat"`},
+		{"nested", `<td id="x"><a href="y"><b>deep</b> link</a></td>`, "deep link"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := plainText(fragment(t, "<table><tr>"+tt.body+"</tr></table>")); got != tt.want {
+				t.Errorf("plainText(%s) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizedHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"text", `<td id="x">A clue</td>`, "A clue"},
+		{"formatting kept", `<td id="x">This <i>Book</i> is <u>here</u></td>`, "This <i>Book</i> is <u>here</u>"},
+		{"attributes dropped", `<td id="x"><i class="t" style="x">Book</i></td>`, "<i>Book</i>"},
+		{"other tags unwrapped", `<td id="x"><a href="y">link <b>bold</b></a></td>`, "link <b>bold</b>"},
+		{"br kept", `<td id="x">one<br />two</td>`, "one<br>two"},
+		{"script skipped", `<td id="x">seen<script>hidden()</script></td>`, "seen"},
+		{"text escaped", `<td id="x">a &lt;b&gt; &amp; c</td>`, "a &lt;b&gt; &amp; c"},
+		{"whitespace collapsed", "<td id=\"x\">  spaced \n <em>out</em>  </td>", "spaced <em>out</em>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizedHTML(fragment(t, "<table><tr>"+tt.body+"</tr></table>")); got != tt.want {
+				t.Errorf("sanitizedHTML(%s) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
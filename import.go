@@ -0,0 +1,776 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tlegnard/answer-there/jarchive"
+)
+
+// Community datasets cover games J-Archive pages sometimes lack or get
+// wrong. An import loads one of them into staged_clues, matches each row to
+// a stored clue by air date, round and value, and records which fields
+// disagree. Nothing outside the staging tables is changed.
+
+// Supported import formats
+const (
+	// importJSON is the JSON array of the "200,000 Jeopardy questions" dataset
+	importJSON = "json"
+	// importCSV is the CSV release of the same dataset
+	importCSV = "csv"
+	// importTSV is the tab-separated clue dataset published per season
+	importTSV = "tsv"
+)
+
+// Match results stored in staged_clues.match_status
+const (
+	matchFound     = "matched"
+	matchNoGame    = "no_game"
+	matchNoClue    = "no_clue"
+	matchAmbiguous = "ambiguous"
+	// matchInvalid rows could not be read and are not matched
+	matchInvalid = "invalid"
+)
+
+var matchStatuses = []string{matchFound, matchNoGame, matchNoClue, matchAmbiguous, matchInvalid}
+
+// Fields compared between a staged row and its matched clue
+var discrepancyFields = []string{"category", "value", "text", "correct_response"}
+
+// importRoundCodes maps the round names used by the datasets to round codes
+var importRoundCodes = map[string]string{
+	"jeopardy!":        "J",
+	"double jeopardy!": "DJ",
+	"final jeopardy!":  "FJ",
+	"tiebreaker":       "TB",
+	"1":                "J",
+	"2":                "DJ",
+	"3":                "FJ",
+}
+
+// stagedClue is one clue read from a community dataset
+type stagedClue struct {
+	ShowNum         int
+	AirDate         string
+	RoundCode       string
+	Category        string
+	Value           int
+	DailyDouble     bool
+	Text            string
+	CorrectResponse string
+	// Invalid is why the row could not be read, such as an unknown round
+	Invalid string
+}
+
+// storedClue is a clue already in the database, as compared with staged rows
+type storedClue struct {
+	ClueID          string
+	GameID          int
+	RoundCode       string
+	Category        string
+	Value           int
+	DailyDouble     bool
+	Text            string
+	CorrectResponse string
+}
+
+// detectImportFormat guesses the format of a dataset from its file extension
+func detectImportFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return importJSON, nil
+	case ".csv":
+		return importCSV, nil
+	case ".tsv", ".txt":
+		return importTSV, nil
+	}
+	return "", fmt.Errorf("cannot tell the format of %s, use -format", path)
+}
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// cleanImportedText strips the markup and quoting the datasets leave in clue
+// text, such as links to clue media and the quotes wrapping every clue in
+// the 200,000 questions dataset
+func cleanImportedText(text string) string {
+	text = html.UnescapeString(htmlTagPattern.ReplaceAllString(text, " "))
+	text = normalizeText(text)
+	if len(text) >= 2 && strings.HasPrefix(text, "'") && strings.HasSuffix(text, "'") {
+		text = strings.TrimSpace(text[1 : len(text)-1])
+	}
+	return text
+}
+
+// comparableText reduces text to lowercase letters and digits separated by
+// single spaces, so punctuation and quoting differences are not reported
+func comparableText(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(normalizeText(text)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// importedMoney parses a dataset value such as "$1,000", treating "None",
+// "0" and empty values as no value
+func importedMoney(text string) int {
+	amount, err := parseMoney(text)
+	if err != nil || amount < 0 {
+		return 0
+	}
+	return amount
+}
+
+// importedDate normalizes a dataset air date to YYYY-MM-DD. ok is false,
+// and the text returned unchanged, if it is not a date.
+func importedDate(text string) (date string, ok bool) {
+	text = strings.TrimSpace(text)
+	for _, layout := range []string{"2006-01-02", "1/2/2006", "01/02/2006"} {
+		if day, err := time.Parse(layout, text); err == nil {
+			return day.Format("2006-01-02"), true
+		}
+	}
+	return text, false
+}
+
+// newStagedClue builds a staged clue from the fields the datasets share. A
+// row with an unknown round or air date is returned marked invalid.
+func newStagedClue(showNum, airDate, round, category, text, correctResponse string) stagedClue {
+	number, _ := strconv.Atoi(strings.TrimSpace(showNum))
+	clue := stagedClue{
+		ShowNum:         number,
+		Category:        cleanImportedText(category),
+		Text:            cleanImportedText(text),
+		CorrectResponse: cleanImportedText(correctResponse),
+	}
+	var ok bool
+	if clue.AirDate, ok = importedDate(airDate); !ok {
+		clue.Invalid = fmt.Sprintf("invalid air date %q", airDate)
+	}
+	if clue.RoundCode, ok = importRoundCodes[strings.ToLower(strings.TrimSpace(round))]; !ok {
+		clue.Invalid = fmt.Sprintf("unknown round %q", round)
+	}
+	return clue
+}
+
+// readImportJSON streams the JSON array of the 200,000 questions dataset
+func readImportJSON(r io.Reader, emit func(stagedClue) error) error {
+	decoder := json.NewDecoder(r)
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	for decoder.More() {
+		var record struct {
+			Category   string  `json:"category"`
+			AirDate    string  `json:"air_date"`
+			Question   string  `json:"question"`
+			Value      *string `json:"value"`
+			Answer     string  `json:"answer"`
+			Round      string  `json:"round"`
+			ShowNumber string  `json:"show_number"`
+		}
+		// A field of the wrong type spoils only its record; the decoder has read past it
+		var typeErr *json.UnmarshalTypeError
+		err := decoder.Decode(&record)
+		if err != nil && !errors.As(err, &typeErr) {
+			return err
+		}
+		clue := newStagedClue(record.ShowNumber, record.AirDate, record.Round, record.Category, record.Question, record.Answer)
+		if err != nil {
+			clue.Invalid = err.Error()
+		}
+		if record.Value != nil {
+			clue.Value = importedMoney(*record.Value)
+		}
+		if err := emit(clue); err != nil {
+			return err
+		}
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+// headerIndex maps the trimmed, lowercased header names of a table to their
+// columns and checks the required ones are present
+func headerIndex(header []string, required ...string) (map[string]int, error) {
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range required {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+	return index, nil
+}
+
+// field returns a named column of a record, or "" if the record is short
+func field(record []string, index map[string]int, name string) string {
+	i, ok := index[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+// readImportCSV reads the CSV release of the 200,000 questions dataset
+func readImportCSV(r io.Reader, emit func(stagedClue) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	index, err := headerIndex(header, "show number", "air date", "round", "category", "value", "question", "answer")
+	if err != nil {
+		return err
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		clue := newStagedClue(field(record, index, "show number"), field(record, index, "air date"),
+			field(record, index, "round"), field(record, index, "category"),
+			field(record, index, "question"), field(record, index, "answer"))
+		clue.Value = importedMoney(field(record, index, "value"))
+		if err := emit(clue); err != nil {
+			return err
+		}
+	}
+}
+
+// readImportTSV reads the per-season TSV dataset. Its answer column holds
+// the clue text and its question column the correct response. The fields
+// are not quoted, so lines are split on tabs rather than parsed as CSV.
+func readImportTSV(r io.Reader, emit func(stagedClue) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	if !scanner.Scan() {
+		return scanner.Err()
+	}
+	index, err := headerIndex(strings.Split(scanner.Text(), "\t"), "round", "clue_value", "category", "answer", "question", "air_date")
+	if err != nil {
+		return err
+	}
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		record := strings.Split(scanner.Text(), "\t")
+		clue := newStagedClue("", field(record, index, "air_date"), field(record, index, "round"),
+			field(record, index, "category"), field(record, index, "answer"), field(record, index, "question"))
+		clue.Value = importedMoney(field(record, index, "clue_value"))
+		clue.DailyDouble = importedMoney(field(record, index, "daily_double_value")) > 0
+		if err := emit(clue); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// readStoredCluesByAirDate reads the stored clues of the games aired on a
+// day. found is false when no game aired that day.
+func readStoredCluesByAirDate(tx *sql.Tx, airDate string) (clues []storedClue, found bool, err error) {
+	var games int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM games WHERE air_date = ?;`, airDate).Scan(&games); err != nil {
+		return nil, false, err
+	}
+	if games == 0 {
+		return nil, false, nil
+	}
+
+	rows, err := tx.Query(`
+		SELECT c.clue_id, c.game_id, r.round_code, COALESCE(k.name, ''), COALESCE(c.value, 0), c.daily_double,
+			COALESCE(c.text, ''), COALESCE(c.correct_response, '')
+		FROM clues c
+		JOIN games g ON g.game_id = c.game_id
+		JOIN rounds r ON r.round_id = c.round_id
+		LEFT JOIN categories k ON k.category_id = c.category_id
+		WHERE g.air_date = ?;`, airDate)
+	if err != nil {
+		return nil, true, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var clue storedClue
+		if err := rows.Scan(&clue.ClueID, &clue.GameID, &clue.RoundCode, &clue.Category, &clue.Value, &clue.DailyDouble,
+			&clue.Text, &clue.CorrectResponse); err != nil {
+			return nil, true, err
+		}
+		clues = append(clues, clue)
+	}
+	return clues, true, rows.Err()
+}
+
+// narrowCandidates keeps the candidates that satisfy keep, unless none do
+func narrowCandidates(candidates []storedClue, keep func(storedClue) bool) []storedClue {
+	var kept []storedClue
+	for _, candidate := range candidates {
+		if keep(candidate) {
+			kept = append(kept, candidate)
+		}
+	}
+	if len(kept) == 0 {
+		return candidates
+	}
+	return kept
+}
+
+// matchStagedClue finds the stored clue a staged row describes. Rows are
+// matched by round and value within the game aired that day, and the
+// category and then the clue text settle ties. Some datasets give the wager
+// as a Daily Double's value, so a value no clue has points at one.
+func matchStagedClue(staged stagedClue, clues []storedClue) (storedClue, string) {
+	var candidates []storedClue
+	for _, clue := range clues {
+		if clue.RoundCode == staged.RoundCode {
+			candidates = append(candidates, clue)
+		}
+	}
+	if len(candidates) == 0 {
+		return storedClue{}, matchNoClue
+	}
+
+	if staged.RoundCode != "FJ" && staged.RoundCode != "TB" {
+		category := comparableText(staged.Category)
+		candidates = narrowCandidates(candidates, func(clue storedClue) bool {
+			return comparableText(clue.Category) == category
+		})
+		if staged.Value > 0 {
+			var byValue []storedClue
+			for _, clue := range candidates {
+				if clue.Value == staged.Value {
+					byValue = append(byValue, clue)
+				}
+			}
+			if len(byValue) > 0 {
+				candidates = byValue
+			} else {
+				// A value no clue has is usually a Daily Double wager
+				candidates = narrowCandidates(candidates, func(clue storedClue) bool { return clue.DailyDouble })
+			}
+		}
+	}
+
+	if len(candidates) > 1 {
+		text := comparableText(staged.Text)
+		candidates = narrowCandidates(candidates, func(clue storedClue) bool { return comparableText(clue.Text) == text })
+	}
+	if len(candidates) > 1 {
+		return storedClue{}, matchAmbiguous
+	}
+	return candidates[0], matchFound
+}
+
+// stagedDiscrepancies lists the fields on which a staged row and its matched
+// clue disagree. Values are only compared off Daily Doubles, and fields the
+// dataset leaves empty are not compared.
+func stagedDiscrepancies(staged stagedClue, clue storedClue) []string {
+	var fields []string
+	if staged.Category != "" && comparableText(staged.Category) != comparableText(clue.Category) {
+		fields = append(fields, "category")
+	}
+	if staged.Value > 0 && !staged.DailyDouble && !clue.DailyDouble && staged.Value != clue.Value {
+		fields = append(fields, "value")
+	}
+	if staged.Text != "" && comparableText(staged.Text) != comparableText(clue.Text) {
+		fields = append(fields, "text")
+	}
+	if staged.CorrectResponse != "" && comparableText(staged.CorrectResponse) != comparableText(clue.CorrectResponse) {
+		fields = append(fields, "correct_response")
+	}
+	return fields
+}
+
+// importDataset loads a dataset into a new import batch, replacing earlier
+// batches of the same file, and matches every row against the stored clues.
+// Rows that cannot be read are staged as invalid with the reason.
+func importDataset(db *sql.DB, path, format string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var read func(io.Reader, func(stagedClue) error) error
+	switch format {
+	case importJSON:
+		read = readImportJSON
+	case importCSV:
+		read = readImportCSV
+	case importTSV:
+		read = readImportTSV
+	default:
+		return 0, fmt.Errorf("unknown import format %q", format)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM import_batches WHERE path = ?;`, absPath); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(`INSERT INTO import_batches (path, format, imported_at) VALUES (?, ?, ?);`,
+		absPath, format, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	batchID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	insert, err := tx.Prepare(`
+		INSERT INTO staged_clues (batch_id, show_num, air_date, round_code, category, value, daily_double, text,
+			correct_response, match_status, game_id, clue_id, discrepancies, invalid_reason)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`)
+	if err != nil {
+		return 0, err
+	}
+	defer insert.Close()
+
+	type airDateClues struct {
+		clues []storedClue
+		found bool
+	}
+	byAirDate := make(map[string]airDateClues)
+
+	row := 0
+	err = read(file, func(staged stagedClue) error {
+		row++
+		if staged.Invalid != "" {
+			_, err := insert.Exec(batchID, nullInt(staged.ShowNum), nullString(staged.AirDate), nullString(staged.RoundCode),
+				staged.Category, nullInt(staged.Value), staged.DailyDouble, staged.Text, staged.CorrectResponse, matchInvalid,
+				nil, nil, nil, fmt.Sprintf("row %d: %s", row, staged.Invalid))
+			return err
+		}
+
+		stored, ok := byAirDate[staged.AirDate]
+		if !ok {
+			clues, found, err := readStoredCluesByAirDate(tx, staged.AirDate)
+			if err != nil {
+				return err
+			}
+			stored = airDateClues{clues, found}
+			byAirDate[staged.AirDate] = stored
+		}
+
+		status := matchNoGame
+		var clue storedClue
+		var discrepancies []string
+		if stored.found {
+			clue, status = matchStagedClue(staged, stored.clues)
+			if status == matchFound {
+				discrepancies = stagedDiscrepancies(staged, clue)
+			}
+		}
+
+		_, err := insert.Exec(batchID, nullInt(staged.ShowNum), staged.AirDate, staged.RoundCode, staged.Category,
+			nullInt(staged.Value), staged.DailyDouble, staged.Text, staged.CorrectResponse, status,
+			nullInt(clue.GameID), nullString(clue.ClueID), nullString(strings.Join(discrepancies, ",")), nil)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to import %s: %v", path, err)
+	}
+	return batchID, tx.Commit()
+}
+
+// importReport summarizes how an import batch compares with the scraped data
+type importReport struct {
+	BatchID       int64          `json:"batch_id"`
+	Path          string         `json:"path"`
+	Format        string         `json:"format"`
+	Rows          int            `json:"rows"`
+	Statuses      map[string]int `json:"statuses"`
+	MissingGames  []string       `json:"missing_games"`
+	Discrepancies map[string]int `json:"discrepancies"`
+	Examples      []string       `json:"examples"`
+	// Invalid holds the reasons for the first rows that could not be read
+	Invalid []string `json:"invalid"`
+}
+
+// maxImportExamples limits how many discrepancies a report shows per field
+const maxImportExamples = 5
+
+// latestImportBatch returns the most recent import batch, or 0 if there is none
+func latestImportBatch(db *sql.DB) (int64, error) {
+	var batchID int64
+	err := db.QueryRow(`SELECT COALESCE(MAX(batch_id), 0) FROM import_batches;`).Scan(&batchID)
+	return batchID, err
+}
+
+// buildImportReport reads the staged rows of a batch into a report
+func buildImportReport(db *sql.DB, batchID int64) (importReport, error) {
+	report := importReport{
+		BatchID:       batchID,
+		Statuses:      make(map[string]int),
+		MissingGames:  []string{},
+		Discrepancies: make(map[string]int),
+		Examples:      []string{},
+		Invalid:       []string{},
+	}
+	err := db.QueryRow(`SELECT path, format FROM import_batches WHERE batch_id = ?;`, batchID).Scan(&report.Path, &report.Format)
+	if err == sql.ErrNoRows {
+		return report, fmt.Errorf("import batch %d: %w", batchID, jarchive.ErrNotFound)
+	}
+	if err != nil {
+		return report, err
+	}
+
+	rows, err := db.Query(`SELECT match_status, COUNT(*) FROM staged_clues WHERE batch_id = ? GROUP BY match_status;`, batchID)
+	if err != nil {
+		return report, err
+	}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			rows.Close()
+			return report, err
+		}
+		report.Statuses[status] = count
+		report.Rows += count
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	// Days the dataset covers that were never scraped
+	rows, err = db.Query(`
+		SELECT DISTINCT COALESCE(air_date, '') FROM staged_clues
+		WHERE batch_id = ? AND match_status = ? ORDER BY air_date;`, batchID, matchNoGame)
+	if err != nil {
+		return report, err
+	}
+	for rows.Next() {
+		var airDate string
+		if err := rows.Scan(&airDate); err != nil {
+			rows.Close()
+			return report, err
+		}
+		report.MissingGames = append(report.MissingGames, airDate)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	rows, err = db.Query(`
+		SELECT COALESCE(invalid_reason, '') FROM staged_clues
+		WHERE batch_id = ? AND match_status = ? ORDER BY id LIMIT ?;`, batchID, matchInvalid, maxImportExamples)
+	if err != nil {
+		return report, err
+	}
+	for rows.Next() {
+		var reason string
+		if err := rows.Scan(&reason); err != nil {
+			rows.Close()
+			return report, err
+		}
+		report.Invalid = append(report.Invalid, reason)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	for _, name := range discrepancyFields {
+		var count int
+		err := db.QueryRow(`
+			SELECT COUNT(*) FROM staged_clues
+			WHERE batch_id = ? AND ',' || discrepancies || ',' LIKE ?;`, batchID, "%,"+name+",%").Scan(&count)
+		if err != nil {
+			return report, err
+		}
+		if count == 0 {
+			continue
+		}
+		report.Discrepancies[name] = count
+
+		examples, err := discrepancyExamples(db, batchID, name)
+		if err != nil {
+			return report, err
+		}
+		report.Examples = append(report.Examples, examples...)
+	}
+	return report, nil
+}
+
+// discrepancyExamples shows a few staged rows that disagree on a field,
+// with the stored value first
+func discrepancyExamples(db *sql.DB, batchID int64, name string) ([]string, error) {
+	ours := map[string]string{
+		"category":         "COALESCE(k.name, '')",
+		"value":            "COALESCE(c.value, '')",
+		"text":             "COALESCE(c.text, '')",
+		"correct_response": "COALESCE(c.correct_response, '')",
+	}[name]
+	rows, err := db.Query(`
+		SELECT s.clue_id, `+ours+`, COALESCE(s.`+name+`, '')
+		FROM staged_clues s
+		JOIN clues c ON c.clue_id = s.clue_id
+		LEFT JOIN categories k ON k.category_id = c.category_id
+		WHERE s.batch_id = ? AND ',' || s.discrepancies || ',' LIKE ?
+		ORDER BY s.id LIMIT ?;`, batchID, "%,"+name+",%", maxImportExamples)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var examples []string
+	for rows.Next() {
+		var cID, stored, imported string
+		if err := rows.Scan(&cID, &stored, &imported); err != nil {
+			return nil, err
+		}
+		examples = append(examples, fmt.Sprintf("%s %s: %q vs %q", cID, name, stored, imported))
+	}
+	return examples, rows.Err()
+}
+
+// printImportReport writes a report as text or JSON
+func printImportReport(report importReport, asJSON bool) {
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+		return
+	}
+
+	fmt.Printf("Import batch %d: %d %s rows from %s\n", report.BatchID, report.Rows, report.Format, report.Path)
+	for _, status := range matchStatuses {
+		fmt.Printf("  %-10s %d\n", status, report.Statuses[status])
+	}
+	if len(report.MissingGames) > 0 {
+		fmt.Printf("Games missing from the database: %d\n", len(report.MissingGames))
+		for i, airDate := range report.MissingGames {
+			if i == maxImportExamples {
+				fmt.Printf("    ... and %d more\n", len(report.MissingGames)-i)
+				break
+			}
+			fmt.Printf("    %s\n", airDate)
+		}
+	}
+	if invalid := report.Statuses[matchInvalid]; invalid > 0 {
+		fmt.Printf("Rows that could not be read: %d\n", invalid)
+		for _, reason := range report.Invalid {
+			fmt.Printf("    %s\n", reason)
+		}
+		if invalid > len(report.Invalid) {
+			fmt.Printf("    ... and %d more\n", invalid-len(report.Invalid))
+		}
+	}
+	if len(report.Discrepancies) == 0 {
+		fmt.Println("No discrepancies in matched clues")
+		return
+	}
+	fmt.Println("Discrepancies in matched clues (ours vs theirs):")
+	for _, name := range discrepancyFields {
+		if report.Discrepancies[name] == 0 {
+			continue
+		}
+		fmt.Printf("  %s: %d\n", name, report.Discrepancies[name])
+		for _, example := range report.Examples {
+			if strings.Contains(example, " "+name+": ") {
+				fmt.Printf("      %s\n", example)
+			}
+		}
+	}
+}
+
+// runImport implements the import command
+//
+//	import [-format json|csv|tsv] [-json] FILE
+//	import report [-batch N] [-json]
+func runImport(ctx context.Context, store *sqliteStore, args []string) {
+	db := store.db
+	if len(args) > 0 && args[0] == "report" {
+		flags := flag.NewFlagSet("import report", flag.ExitOnError)
+		batchID := flags.Int64("batch", 0, "import batch to report on (default the latest)")
+		asJSON := flags.Bool("json", false, "print the report as JSON")
+		flags.Parse(args[1:])
+
+		if *batchID == 0 {
+			latest, err := latestImportBatch(db)
+			if err != nil {
+				log.Fatalf("Failed to find the latest import: %v", err)
+			}
+			if latest == 0 {
+				log.Fatal("Nothing has been imported yet")
+			}
+			*batchID = latest
+		}
+		report, err := buildImportReport(db, *batchID)
+		if err != nil {
+			log.Fatalf("Failed to build report: %v", err)
+		}
+		printImportReport(report, *asJSON)
+		return
+	}
+
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "dataset format: json, csv or tsv (default from the file extension)")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		log.Fatal("Usage: import [-format json|csv|tsv] [-json] FILE")
+	}
+	path := flags.Arg(0)
+
+	if *format == "" {
+		detected, err := detectImportFormat(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		*format = detected
+	}
+
+	run := startRunLedger(store, "import", args)
+	defer run.finish(ctx)
+	batchID, err := importDataset(db, path, *format)
+	if err != nil {
+		run.fatalf("%v", err)
+	}
+	report, err := buildImportReport(db, batchID)
+	if err != nil {
+		log.Fatalf("Failed to build report: %v", err)
+	}
+	printImportReport(report, *asJSON)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewStagedClue(t *testing.T) {
+	tests := []struct {
+		name                  string
+		airDate, round, text  string
+		wantDate, wantCode    string
+		wantText, wantInvalid string
+	}{
+		{"dataset round name", "2004-12-31", "Jeopardy!", "'A clue'", "2004-12-31", "J", "A clue", ""},
+		{"numbered round", "12/31/2004", "2", "<a href=\"x\">Linked</a> clue", "2004-12-31", "DJ", "Linked clue", ""},
+		{"final", "2004-12-31", " Final Jeopardy! ", "Last", "2004-12-31", "FJ", "Last", ""},
+		{"unknown round", "2004-12-31", "Jeopardy Round", "A clue", "2004-12-31", "", "A clue", `unknown round "Jeopardy Round"`},
+		{"invalid air date", "sometime", "Jeopardy!", "A clue", "sometime", "J", "A clue", `invalid air date "sometime"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clue := newStagedClue("4680", tt.airDate, tt.round, "HISTORY", tt.text, "an answer")
+			if clue.AirDate != tt.wantDate || clue.RoundCode != tt.wantCode || clue.Text != tt.wantText || clue.Invalid != tt.wantInvalid {
+				t.Errorf("newStagedClue = %+v, want date %q, round %q, text %q, invalid %q",
+					clue, tt.wantDate, tt.wantCode, tt.wantText, tt.wantInvalid)
+			}
+			if clue.ShowNum != 4680 || clue.Category != "HISTORY" {
+				t.Errorf("newStagedClue = %+v", clue)
+			}
+		})
+	}
+}
+
+func TestReadImportSkipsBadRows(t *testing.T) {
+	tests := []struct {
+		name string
+		read func(r *strings.Reader, emit func(stagedClue) error) error
+		data string
+	}{
+		{"json", func(r *strings.Reader, emit func(stagedClue) error) error { return readImportJSON(r, emit) }, `[
+			{"category": "HISTORY", "air_date": "2004-12-31", "question": "First", "value": "$200", "answer": "one", "round": "Jeopardy!", "show_number": "4680"},
+			{"category": "HISTORY", "air_date": "2004-12-31", "question": "Bad round", "value": "$400", "answer": "two", "round": "Bonus", "show_number": "4680"},
+			{"category": "HISTORY", "air_date": "2004-12-31", "question": "Bad value", "value": 600, "answer": "three", "round": "Jeopardy!", "show_number": "4680"},
+			{"category": "HISTORY", "air_date": "2004-12-31", "question": "Last", "value": null, "answer": "four", "round": "Final Jeopardy!", "show_number": "4680"}
+		]`},
+		{"csv", func(r *strings.Reader, emit func(stagedClue) error) error { return readImportCSV(r, emit) },
+			"Show Number, Air Date, Round, Category, Value, Question, Answer\n" +
+				"4680,2004-12-31,Jeopardy!,HISTORY,$200,First,one\n" +
+				"4680,2004-12-31,Bonus,HISTORY,$400,Bad round,two\n" +
+				"4680,2004-12-31\n" +
+				"4680,2004-12-31,Final Jeopardy!,HISTORY,None,Last,four\n"},
+		{"tsv", func(r *strings.Reader, emit func(stagedClue) error) error { return readImportTSV(r, emit) },
+			"round\tclue_value\tdaily_double_value\tcategory\tcomments\tanswer\tquestion\tair_date\tnotes\n" +
+				"1\t200\t0\tHISTORY\t\tFirst\tone\t2004-12-31\t\n" +
+				"7\t400\t0\tHISTORY\t\tBad round\ttwo\t2004-12-31\t\n" +
+				"1\t600\n" +
+				"3\t0\t0\tHISTORY\t\tLast\tfour\t2004-12-31\t\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var clues []stagedClue
+			err := tt.read(strings.NewReader(tt.data), func(clue stagedClue) error {
+				clues = append(clues, clue)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("reading stopped at a bad row: %v", err)
+			}
+			var invalid []bool
+			for _, clue := range clues {
+				invalid = append(invalid, clue.Invalid != "")
+			}
+			if want := []bool{false, true, true, false}; !reflect.DeepEqual(invalid, want) {
+				t.Errorf("invalid rows = %v, want %v: %+v", invalid, want, clues)
+			}
+			if clues[0].Value != 200 || clues[0].RoundCode != "J" || clues[3].RoundCode != "FJ" || clues[3].Value != 0 {
+				t.Errorf("valid rows = %+v, %+v", clues[0], clues[3])
+			}
+		})
+	}
+}
+
+func TestMatchStagedClue(t *testing.T) {
+	clues := []storedClue{
+		{ClueID: "1-J_1_1", RoundCode: "J", Category: "HISTORY", Value: 200, Text: "First"},
+		{ClueID: "1-J_1_2", RoundCode: "J", Category: "HISTORY", Value: 400, Text: "Second", DailyDouble: true},
+		{ClueID: "1-J_2_1", RoundCode: "J", Category: "SCIENCE", Value: 200, Text: "Third"},
+		{ClueID: "1-J_3_1", RoundCode: "J", Category: "POTPOURRI", Value: 200, Text: "Same"},
+		{ClueID: "1-J_3_2", RoundCode: "J", Category: "POTPOURRI", Value: 200, Text: "Same"},
+		{ClueID: "1-FJ", RoundCode: "FJ", Category: "FINAL", Text: "Last"},
+	}
+	tests := []struct {
+		name       string
+		staged     stagedClue
+		wantID     string
+		wantStatus string
+	}{
+		{"category and value", stagedClue{RoundCode: "J", Category: "History", Value: 200}, "1-J_1_1", matchFound},
+		{"category punctuation ignored", stagedClue{RoundCode: "J", Category: "SCIENCE!", Value: 200}, "1-J_2_1", matchFound},
+		{"wager points at the daily double", stagedClue{RoundCode: "J", Category: "HISTORY", Value: 1500}, "1-J_1_2", matchFound},
+		{"text settles a tie", stagedClue{RoundCode: "J", Category: "OTHER", Value: 200, Text: "third"}, "1-J_2_1", matchFound},
+		{"final by round", stagedClue{RoundCode: "FJ", Category: "SOMETHING ELSE"}, "1-FJ", matchFound},
+		{"identical clues", stagedClue{RoundCode: "J", Category: "POTPOURRI", Value: 200, Text: "Same"}, "", matchAmbiguous},
+		{"round not stored", stagedClue{RoundCode: "DJ", Value: 400}, "", matchNoClue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clue, status := matchStagedClue(tt.staged, clues)
+			if clue.ClueID != tt.wantID || status != tt.wantStatus {
+				t.Errorf("matchStagedClue = %q %s, want %q %s", clue.ClueID, status, tt.wantID, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestStagedDiscrepancies(t *testing.T) {
+	clue := storedClue{Category: "HISTORY", Value: 200, Text: "This \"city\" is the capital", CorrectResponse: "Paris"}
+	tests := []struct {
+		name   string
+		staged stagedClue
+		want   []string
+	}{
+		{"same after normalizing", stagedClue{Category: "history", Value: 200, Text: "this city is the capital", CorrectResponse: "paris"}, nil},
+		{"empty fields not compared", stagedClue{}, nil},
+		{"value", stagedClue{Value: 400}, []string{"value"}},
+		{"daily double value not compared", stagedClue{Value: 1000, DailyDouble: true}, nil},
+		{"every field", stagedClue{Category: "GEOGRAPHY", Value: 400, Text: "Another clue", CorrectResponse: "Rome"},
+			[]string{"category", "value", "text", "correct_response"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stagedDiscrepancies(tt.staged, clue); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stagedDiscrepancies = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportDataset(t *testing.T) {
+	db := openTestDatabase(t)
+	if err := migrateDatabase(db); err != nil {
+		t.Fatal(err)
+	}
+	game := GameData{ID: 1, ShowNum: 4680, AirDate: "2004-12-31", Rounds: []Round{{
+		Name:       roundJeopardy,
+		Categories: []Category{{Name: "HISTORY"}},
+		Clues: []Clue{
+			{Position: "J_1_1", Value: "$200", Text: "First", CorrectResponse: "one"},
+			{Position: "J_1_2", Value: "$400", Text: "Second", CorrectResponse: "two"},
+		},
+	}}}
+	if _, err := writeGameBatch(db, "21", []GameData{game}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jeopardy.csv")
+	data := "Show Number, Air Date, Round, Category, Value, Question, Answer\n" +
+		"4680,2004-12-31,Jeopardy!,HISTORY,$200,First,one\n" +
+		"4680,2004-12-31,Jeopardy!,HISTORY,$400,Second,a different answer\n" +
+		"4680,2004-12-31,Jeopardy Round,HISTORY,$600,Third,three\n" +
+		"4681,2005-01-03,Jeopardy!,SCIENCE,$200,Elsewhere,four\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Importing twice replaces the first batch
+	for i := 0; i < 2; i++ {
+		batchID, err := importDataset(db, path, importCSV)
+		if err != nil {
+			t.Fatalf("importDataset: %v", err)
+		}
+		report, err := buildImportReport(db, batchID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wantStatuses := map[string]int{matchFound: 2, matchInvalid: 1, matchNoGame: 1}
+		if report.Rows != 4 || !reflect.DeepEqual(report.Statuses, wantStatuses) {
+			t.Errorf("report has %d rows with statuses %v, want 4 with %v", report.Rows, report.Statuses, wantStatuses)
+		}
+		if !reflect.DeepEqual(report.MissingGames, []string{"2005-01-03"}) {
+			t.Errorf("missing games = %v", report.MissingGames)
+		}
+		if !reflect.DeepEqual(report.Discrepancies, map[string]int{"correct_response": 1}) {
+			t.Errorf("discrepancies = %v", report.Discrepancies)
+		}
+		if want := []string{`row 3: unknown round "Jeopardy Round"`}; !reflect.DeepEqual(report.Invalid, want) {
+			t.Errorf("invalid rows = %q, want %q", report.Invalid, want)
+		}
+	}
+
+	var batches int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM import_batches;`).Scan(&batches); err != nil || batches != 1 {
+		t.Errorf("%d import batches after importing the same file twice, want 1 (%v)", batches, err)
+	}
+}
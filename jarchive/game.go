@@ -0,0 +1,122 @@
+// Package jarchive holds the game types the answer-there scraper produces
+// and a read API over the SQLite database it writes, so other programs can
+// use the data without knowing the schema.
+package jarchive
+
+import "fmt"
+
+// Clue text and responses are kept both as normalized plain text and as
+// sanitized HTML that preserves formatting such as italicized titles
+type Clue struct {
+	Position            string     `json:"position"`
+	Value               string     `json:"value"`
+	OrderNumber         int        `json:"order_number"`
+	Text                string     `json:"text"`
+	TextHTML            string     `json:"text_html"`
+	CorrectResponse     string     `json:"correct_response"`
+	CorrectResponseHTML string     `json:"correct_response_html"`
+	CorrectContestant   string     `json:"correct_contestant"`
+	DailyDouble         bool       `json:"daily_double"`
+	Wager               int        `json:"wager"` // Daily Double wager
+	TripleStumper       bool       `json:"triple_stumper"`
+	Responses           []Response `json:"responses"`
+}
+
+// Response is one contestant's response to a clue. Text and Wager are only
+// shown for Final Jeopardy.
+type Response struct {
+	Contestant string `json:"contestant"` // nickname as shown on the page
+	Correct    bool   `json:"correct"`
+	Text       string `json:"text,omitempty"`
+	Wager      int    `json:"wager,omitempty"`
+}
+
+// RoundScore holds the scores the page reports at the end of a round
+type RoundScore struct {
+	Round  string            `json:"round"`
+	Scores []ContestantScore `json:"scores"`
+}
+
+type ContestantScore struct {
+	Nickname string `json:"nickname"`
+	Score    int    `json:"score"`
+}
+
+// ScoreEvent is a change to one contestant's score
+type ScoreEvent struct {
+	Round       string `json:"round"`
+	Position    string `json:"position"`
+	OrderNumber int    `json:"order_number"`
+	Contestant  string `json:"contestant"`
+	Delta       int    `json:"delta"`
+	// Scores holds every contestant's score after this event
+	Scores []ContestantScore `json:"scores"`
+}
+
+// Category struct represents a category column on the board
+type Category struct {
+	Name     string `json:"name"`
+	NameHTML string `json:"name_html"`
+}
+
+// Round struct represents a round of the game
+type Round struct {
+	Name       string     `json:"name"`
+	Categories []Category `json:"categories"`
+	Clues      []Clue     `json:"clues"`
+}
+
+type Contestant struct {
+	PlayerID string `json:"player_id"`
+	Name     string `json:"name"`
+	Nickname string `json:"nickname"`
+	Bio      string `json:"bio"`
+}
+
+// ParseWarning describes a problem found while parsing a game page that
+// did not prevent the game from being parsed.
+type ParseWarning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (w ParseWarning) String() string {
+	return fmt.Sprintf("[%s] %s", w.Code, w.Message)
+}
+
+// GameData struct represents the game data including multiple rounds
+type GameData struct {
+	ID          int            `json:"id"`
+	Rounds      []Round        `json:"rounds"`
+	Contestants []Contestant   `json:"contestants"`
+	ShowNum     int            `json:"show_num"`
+	AirDate     string         `json:"air_date"`
+	TapeDate    string         `json:"tape_date"`
+	Warnings    []ParseWarning `json:"warnings"`
+	// Era and Format describe the page layout and round structure, and
+	// Unavailable lists the fields the page did not provide
+	Era         string       `json:"era"`
+	Format      string       `json:"format"`
+	Unavailable []string     `json:"unavailable"`
+	RoundScores []RoundScore `json:"round_scores"`
+	// ScoreTimeline holds every score change in the order the clues were
+	// played, replayed from the responses
+	ScoreTimeline []ScoreEvent `json:"score_timeline"`
+	// ParserVersion is the version of the parser that produced this data
+	ParserVersion int `json:"parser_version"`
+}
+
+// IsUnavailable reports whether a field was missing from the game page
+func (game GameData) IsUnavailable(field string) bool {
+	for _, unavailable := range game.Unavailable {
+		if unavailable == field {
+			return true
+		}
+	}
+	return false
+}
+
+type SeasonData struct {
+	ID    string     `json:"id"` // Season ID, e.g., "40"
+	Games []GameData `json:"games"`
+}
@@ -0,0 +1,589 @@
+package jarchive
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+)
+
+// The read API turns rows in the normalized tables back into the types the
+// parser produces, so callers never need to know the schema. Round scores
+// are not stored and are left empty on games read back from the database.
+
+// ErrNotFound is returned, wrapped, when the requested game or player is not in the database
+var ErrNotFound = errors.New("not found")
+
+// batchSize is the number of clues whose responses are read in one query,
+// kept below SQLite's limit on query parameters
+const batchSize = 500
+
+// DB reads games, clues and players from a database written by answer-there
+type DB struct {
+	db *sql.DB
+}
+
+// Open opens the database at path read-only
+func Open(path string) (*DB, error) {
+	db, err := sql.Open("sqlite3", "file:"+(&url.URL{Path: path}).EscapedPath()+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	return &DB{db: db}, nil
+}
+
+// New reads from an open database handle, which the caller keeps ownership of
+func New(db *sql.DB) *DB {
+	return &DB{db: db}
+}
+
+// Close closes a database opened with Open
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// GameFilter selects games for ListGames. Empty fields match everything.
+type GameFilter struct {
+	SeasonID string
+	From     string // air date, YYYY-MM-DD, inclusive
+	To       string
+}
+
+// ClueFilter selects clues for ListClues. Zero values match everything.
+type ClueFilter struct {
+	GameID        int
+	SeasonID      string
+	Category      string // exact category name, case-insensitive
+	Round         string // round code: J, DJ, FJ or TB
+	MinValue      int
+	MaxValue      int
+	DailyDouble   bool // only Daily Doubles
+	TripleStumper bool // only clues nobody got right
+	Limit         int
+}
+
+// ClueRecord is a clue together with where it was played
+type ClueRecord struct {
+	GameID   int      `json:"game_id"`
+	SeasonID string   `json:"season_id"`
+	AirDate  string   `json:"air_date"`
+	Round    string   `json:"round"`
+	Category Category `json:"category"`
+	Clue     Clue     `json:"clue"`
+}
+
+// Appearance is one game a player appeared in
+type Appearance struct {
+	GameID   int    `json:"game_id"`
+	SeasonID string `json:"season_id"`
+	ShowNum  int    `json:"show_num"`
+	AirDate  string `json:"air_date"`
+	Seat     int    `json:"seat"`
+	Nickname string `json:"nickname"`
+	Bio      string `json:"bio"`
+}
+
+// Player is a contestant with every game they appeared in, oldest first
+type Player struct {
+	Contestant
+	Appearances []Appearance `json:"appearances"`
+}
+
+// GetGame reads a complete game by its J-Archive game ID
+func (d *DB) GetGame(gameID int) (GameData, error) {
+	return d.getGameWhere(fmt.Sprintf("game %d", gameID), `game_id = ?`, gameID)
+}
+
+// GetGameByShowNumber reads a complete game by its show number
+func (d *DB) GetGameByShowNumber(showNum int) (GameData, error) {
+	return d.getGameWhere(fmt.Sprintf("show #%d", showNum), `show_num = ?`, showNum)
+}
+
+// GetGameByAirDate reads the game that aired on date (YYYY-MM-DD)
+func (d *DB) GetGameByAirDate(date string) (GameData, error) {
+	return d.getGameWhere("game aired "+date, `air_date = ?`, date)
+}
+
+func (d *DB) getGameWhere(description, where string, arg interface{}) (GameData, error) {
+	games, err := d.queryGames(`WHERE `+where+` ORDER BY game_id LIMIT 1`, arg)
+	if err != nil {
+		return GameData{}, err
+	}
+	if len(games) == 0 {
+		return GameData{}, fmt.Errorf("%s: %w", description, ErrNotFound)
+	}
+
+	game := games[0]
+	if game.Contestants, err = d.readAppearances(game.ID); err != nil {
+		return GameData{}, err
+	}
+	if game.Rounds, err = d.readRounds(game.ID); err != nil {
+		return GameData{}, err
+	}
+	if game.Warnings, err = d.readParseWarnings(game.ID); err != nil {
+		return GameData{}, err
+	}
+	if game.ScoreTimeline, err = d.readScoreTimeline(game.ID); err != nil {
+		return GameData{}, err
+	}
+	return game, nil
+}
+
+// readScoreTimeline reads the running scores of a game in play order
+func (d *DB) readScoreTimeline(gameID int) ([]ScoreEvent, error) {
+	rows, err := d.db.Query(`
+		SELECT round, position, COALESCE(order_number, 0), nickname, delta, scores
+		FROM score_timeline WHERE game_id = ? ORDER BY seq;`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var timeline []ScoreEvent
+	for rows.Next() {
+		var event ScoreEvent
+		var scores string
+		if err := rows.Scan(&event.Round, &event.Position, &event.OrderNumber, &event.Contestant, &event.Delta, &scores); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(scores), &event.Scores); err != nil {
+			return nil, fmt.Errorf("game %d: invalid scores %q: %v", gameID, scores, err)
+		}
+		timeline = append(timeline, event)
+	}
+	return timeline, rows.Err()
+}
+
+// ListGames returns the games matching filter ordered by air date. Only the
+// game's own fields are filled in; use GetGame for rounds and contestants.
+func (d *DB) ListGames(filter GameFilter) ([]GameData, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.SeasonID != "" {
+		conditions = append(conditions, `season_id = ?`)
+		args = append(args, filter.SeasonID)
+	}
+	if filter.From != "" {
+		conditions = append(conditions, `air_date >= ?`)
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		conditions = append(conditions, `air_date <= ?`)
+		args = append(args, filter.To)
+	}
+
+	query := ``
+	if len(conditions) > 0 {
+		query = `WHERE ` + strings.Join(conditions, ` AND `)
+	}
+	return d.queryGames(query+` ORDER BY air_date, game_id`, args...)
+}
+
+// queryGames reads game rows. Dates are selected through COALESCE so the
+// driver returns them as stored rather than converting DATE columns to times.
+func (d *DB) queryGames(clauses string, args ...interface{}) ([]GameData, error) {
+	rows, err := d.db.Query(`
+		SELECT game_id, show_num, COALESCE(air_date, ''), COALESCE(tape_date, ''), era, format, unavailable_fields, parser_version
+		FROM games `+clauses+`;`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var games []GameData
+	for rows.Next() {
+		var game GameData
+		var showNum sql.NullInt64
+		var era, format, unavailable sql.NullString
+		if err := rows.Scan(&game.ID, &showNum, &game.AirDate, &game.TapeDate, &era, &format, &unavailable, &game.ParserVersion); err != nil {
+			return nil, err
+		}
+		game.ShowNum = int(showNum.Int64)
+		game.Era = era.String
+		game.Format = format.String
+		if unavailable.String != "" {
+			game.Unavailable = strings.Split(unavailable.String, ",")
+		}
+		games = append(games, game)
+	}
+	return games, rows.Err()
+}
+
+func (d *DB) readAppearances(gameID int) ([]Contestant, error) {
+	rows, err := d.db.Query(`
+		SELECT COALESCE(player_id, ''), name, COALESCE(nickname, ''), COALESCE(bio, '')
+		FROM appearances WHERE game_id = ? ORDER BY seat;`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contestants []Contestant
+	for rows.Next() {
+		var contestant Contestant
+		if err := rows.Scan(&contestant.PlayerID, &contestant.Name, &contestant.Nickname, &contestant.Bio); err != nil {
+			return nil, err
+		}
+		contestants = append(contestants, contestant)
+	}
+	return contestants, rows.Err()
+}
+
+// readRounds reads the rounds of a game in play order with their categories and clues
+func (d *DB) readRounds(gameID int) ([]Round, error) {
+	rows, err := d.db.Query(`SELECT round_id, name FROM rounds WHERE game_id = ? ORDER BY ordinal;`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	var rounds []Round
+	var roundIDs []string
+	for rows.Next() {
+		var rID string
+		var round Round
+		if err := rows.Scan(&rID, &round.Name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rounds = append(rounds, round)
+		roundIDs = append(roundIDs, rID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, rID := range roundIDs {
+		if rounds[i].Categories, err = d.readCategories(rID); err != nil {
+			return nil, err
+		}
+	}
+	records, err := d.queryClues(`WHERE c.game_id = ? ORDER BY r.ordinal, c.row_num, k.column_num`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		for i := range rounds {
+			if rounds[i].Name == record.Round {
+				rounds[i].Clues = append(rounds[i].Clues, record.Clue)
+				break
+			}
+		}
+	}
+	return rounds, nil
+}
+
+func (d *DB) readCategories(rID string) ([]Category, error) {
+	rows, err := d.db.Query(`
+		SELECT name, COALESCE(name_html, '') FROM categories WHERE round_id = ? ORDER BY column_num;`, rID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []Category
+	for rows.Next() {
+		var category Category
+		if err := rows.Scan(&category.Name, &category.NameHTML); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+func (d *DB) readParseWarnings(gameID int) ([]ParseWarning, error) {
+	rows, err := d.db.Query(`SELECT code, message FROM parse_warnings WHERE game_id = ? ORDER BY id;`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var warnings []ParseWarning
+	for rows.Next() {
+		var warning ParseWarning
+		if err := rows.Scan(&warning.Code, &warning.Message); err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings, rows.Err()
+}
+
+// ListClues returns the clues matching filter in air date and board order
+func (d *DB) ListClues(filter ClueFilter) ([]ClueRecord, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.GameID != 0 {
+		conditions = append(conditions, `c.game_id = ?`)
+		args = append(args, filter.GameID)
+	}
+	if filter.SeasonID != "" {
+		conditions = append(conditions, `g.season_id = ?`)
+		args = append(args, filter.SeasonID)
+	}
+	if filter.Category != "" {
+		conditions = append(conditions, `k.name = ? COLLATE NOCASE`)
+		args = append(args, filter.Category)
+	}
+	if filter.Round != "" {
+		conditions = append(conditions, `r.round_code = ?`)
+		args = append(args, strings.ToUpper(filter.Round))
+	}
+	if filter.MinValue > 0 {
+		conditions = append(conditions, `c.value >= ?`)
+		args = append(args, filter.MinValue)
+	}
+	if filter.MaxValue > 0 {
+		conditions = append(conditions, `c.value <= ?`)
+		args = append(args, filter.MaxValue)
+	}
+	if filter.DailyDouble {
+		conditions = append(conditions, `c.daily_double = 1`)
+	}
+	if filter.TripleStumper {
+		conditions = append(conditions, `c.triple_stumper = 1`)
+	}
+
+	clauses := ``
+	if len(conditions) > 0 {
+		clauses = `WHERE ` + strings.Join(conditions, ` AND `)
+	}
+	clauses += ` ORDER BY g.air_date, g.game_id, r.ordinal, c.row_num, k.column_num`
+	if filter.Limit > 0 {
+		clauses += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+	return d.queryClues(clauses, args...)
+}
+
+// queryClues reads clues with their category and responses. Clauses may
+// refer to clues as c, games as g, rounds as r and categories as k.
+func (d *DB) queryClues(clauses string, args ...interface{}) ([]ClueRecord, error) {
+	if !strings.Contains(clauses, `ORDER BY`) {
+		clauses += ` ORDER BY c.row_num, k.column_num`
+	}
+	rows, err := d.db.Query(`
+		SELECT c.clue_id, c.game_id, g.season_id, COALESCE(g.air_date, ''), r.name, r.round_code,
+			COALESCE(k.name, ''), COALESCE(k.name_html, ''),
+			c.position, c.value, c.order_number, c.daily_double, c.wager, c.triple_stumper,
+			COALESCE(c.text, ''), COALESCE(c.text_html, ''),
+			COALESCE(c.correct_response, ''), COALESCE(c.correct_response_html, '')
+		FROM clues c
+		JOIN games g ON g.game_id = c.game_id
+		JOIN rounds r ON r.round_id = c.round_id
+		LEFT JOIN categories k ON k.category_id = c.category_id
+		`+clauses+`;`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []ClueRecord
+	var clueIDs []string
+	var finals []finalRound
+	for rows.Next() {
+		var record ClueRecord
+		var cID, roundCode string
+		var value, orderNumber, wager sql.NullInt64
+		clue := &record.Clue
+		if err := rows.Scan(&cID, &record.GameID, &record.SeasonID, &record.AirDate, &record.Round, &roundCode,
+			&record.Category.Name, &record.Category.NameHTML,
+			&clue.Position, &value, &orderNumber, &clue.DailyDouble, &wager, &clue.TripleStumper,
+			&clue.Text, &clue.TextHTML, &clue.CorrectResponse, &clue.CorrectResponseHTML); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if value.Valid {
+			clue.Value = FormatMoney(int(value.Int64))
+		}
+		clue.OrderNumber = int(orderNumber.Int64)
+		clue.Wager = int(wager.Int64)
+		records = append(records, record)
+		clueIDs = append(clueIDs, cID)
+		finals = append(finals, finalRound{record.GameID, roundCode})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	responses, err := d.readResponses(clueIDs)
+	if err != nil {
+		return nil, err
+	}
+	finalResponses, err := d.readFinalResponses(records)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		clue := &records[i].Clue
+		if isFinalRoundCode(finals[i].roundCode) {
+			clue.Responses = finalResponses[finals[i]]
+		} else {
+			clue.Responses = responses[clueIDs[i]]
+		}
+		for _, response := range clue.Responses {
+			if response.Correct {
+				clue.CorrectContestant = response.Contestant
+				break
+			}
+		}
+	}
+	return records, nil
+}
+
+// finalRound identifies the Final Jeopardy or tiebreaker clue of a game,
+// whose responses are stored in the final_jeopardy table
+type finalRound struct {
+	gameID    int
+	roundCode string
+}
+
+func isFinalRoundCode(code string) bool {
+	return code == "FJ" || code == "TB"
+}
+
+// placeholders returns n comma-separated query parameters
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// readResponses reads the responses to the given clues, batchSize clues per query
+func (d *DB) readResponses(clueIDs []string) (map[string][]Response, error) {
+	responses := make(map[string][]Response)
+	for start := 0; start < len(clueIDs); start += batchSize {
+		batch := clueIDs[start:]
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		args := make([]interface{}, len(batch))
+		for i, cID := range batch {
+			args[i] = cID
+		}
+		rows, err := d.db.Query(`
+			SELECT clue_id, nickname, correct FROM responses
+			WHERE clue_id IN (`+placeholders(len(batch))+`) ORDER BY clue_id, seq;`, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var cID string
+			var response Response
+			if err := rows.Scan(&cID, &response.Contestant, &response.Correct); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			responses[cID] = append(responses[cID], response)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return responses, nil
+}
+
+// readFinalResponses reads the Final Jeopardy and tiebreaker responses of the games of records
+func (d *DB) readFinalResponses(records []ClueRecord) (map[finalRound][]Response, error) {
+	seen := make(map[int]bool)
+	var gameIDs []interface{}
+	for _, record := range records {
+		if !seen[record.GameID] {
+			seen[record.GameID] = true
+			gameIDs = append(gameIDs, record.GameID)
+		}
+	}
+
+	responses := make(map[finalRound][]Response)
+	for start := 0; start < len(gameIDs); start += batchSize {
+		batch := gameIDs[start:]
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		rows, err := d.db.Query(`
+			SELECT game_id, round_code, nickname, correct, COALESCE(response, ''), COALESCE(wager, 0)
+			FROM final_jeopardy WHERE game_id IN (`+placeholders(len(batch))+`)
+			ORDER BY game_id, round_code, seq;`, batch...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var key finalRound
+			var response Response
+			if err := rows.Scan(&key.gameID, &key.roundCode, &response.Contestant, &response.Correct, &response.Text, &response.Wager); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			responses[key] = append(responses[key], response)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return responses, nil
+}
+
+// GetPlayer reads a player and every game they appeared in
+func (d *DB) GetPlayer(playerID string) (Player, error) {
+	player := Player{Contestant: Contestant{PlayerID: playerID}}
+	err := d.db.QueryRow(`SELECT name FROM players WHERE player_id = ?;`, playerID).Scan(&player.Name)
+	if err == sql.ErrNoRows {
+		return Player{}, fmt.Errorf("player %s: %w", playerID, ErrNotFound)
+	}
+	if err != nil {
+		return Player{}, err
+	}
+
+	rows, err := d.db.Query(`
+		SELECT a.game_id, g.season_id, g.show_num, COALESCE(g.air_date, ''), a.seat,
+			COALESCE(a.nickname, ''), COALESCE(a.bio, '')
+		FROM appearances a
+		JOIN games g ON g.game_id = a.game_id
+		WHERE a.player_id = ?
+		ORDER BY g.air_date, a.game_id;`, playerID)
+	if err != nil {
+		return Player{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var appearance Appearance
+		var showNum sql.NullInt64
+		if err := rows.Scan(&appearance.GameID, &appearance.SeasonID, &showNum, &appearance.AirDate, &appearance.Seat,
+			&appearance.Nickname, &appearance.Bio); err != nil {
+			return Player{}, err
+		}
+		appearance.ShowNum = int(showNum.Int64)
+		player.Appearances = append(player.Appearances, appearance)
+	}
+	if len(player.Appearances) > 0 {
+		// The most recent appearance has the current nickname and bio
+		latest := player.Appearances[len(player.Appearances)-1]
+		player.Nickname = latest.Nickname
+		player.Bio = latest.Bio
+	}
+	return player, rows.Err()
+}
+
+// FormatMoney writes an amount the way the board shows it, e.g. $1,000
+func FormatMoney(amount int) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.Itoa(amount)
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+	return sign + "$" + digits
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// roundCodes are the short round names J-Archive uses in clue ids
+var roundCodes = map[string]string{
+	roundJeopardy:       "J",
+	roundDoubleJeopardy: "DJ",
+	roundFinalJeopardy:  "FJ",
+	roundTiebreaker:     "TB",
+}
+
+// roundID derives a stable round id from the game and round
+func roundID(gameID int, roundName string) string {
+	return fmt.Sprintf("%d-%s", gameID, roundCodes[roundName])
+}
+
+// clueID derives a stable clue id from the game and the clue's board position
+func clueID(gameID int, position string) string {
+	return fmt.Sprintf("%d-%s", gameID, position)
+}
+
+// categoryID derives a stable category id from the game, round and board
+// column (1-based), so re-ingesting a game always produces the same ids.
+// It returns "" when the column is unknown.
+func categoryID(gameID int, roundName string, column int) string {
+	if column < 1 {
+		return ""
+	}
+	return fmt.Sprintf("%d-%s-%d", gameID, roundCodes[roundName], column)
+}
+
+// boardPosition builds the position of the clue in a given board cell,
+// matching the ids J-Archive gives revealed clues
+func boardPosition(roundName string, cellIndex int, numCategories int) string {
+	code := roundCodes[roundName]
+	if roundName == roundFinalJeopardy || roundName == roundTiebreaker {
+		return code
+	}
+	if numCategories == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s_%d_%d", code, cellIndex%numCategories+1, cellIndex/numCategories+1)
+}
+
+// clueRowNumber returns the board row from a clue position such as J_3_2,
+// or 0 for clues outside the board grid
+func clueRowNumber(position string) int {
+	row, err := strconv.Atoi(clueRow(position))
+	if err != nil || strings.Count(position, "_") != 2 {
+		return 0
+	}
+	return row
+}
+
+// clueColumn returns the board column from a clue position such as J_3_2.
+// Final Jeopardy and tiebreaker clues are in the single column 1.
+func clueColumn(position string) int {
+	if position == "FJ" || position == "TB" {
+		return 1
+	}
+	parts := strings.Split(position, "_")
+	if len(parts) != 3 {
+		return 0
+	}
+	column, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0
+	}
+	return column
+}
+
+// clueCategory returns the category a clue belongs to and its 1-based column,
+// or a zero column if the clue's position does not match a category
+func clueCategory(round Round, clue Clue) (Category, int) {
+	column := clueColumn(clue.Position)
+	if column < 1 || column > len(round.Categories) {
+		return Category{}, 0
+	}
+	return round.Categories[column-1], column
+}
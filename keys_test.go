@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+func TestDerivedIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"jeopardy round", roundID(9001, roundJeopardy), "9001-J"},
+		{"double jeopardy round", roundID(9001, roundDoubleJeopardy), "9001-DJ"},
+		{"final jeopardy round", roundID(9001, roundFinalJeopardy), "9001-FJ"},
+		{"tiebreaker round", roundID(9001, roundTiebreaker), "9001-TB"},
+		{"board clue", clueID(9001, "DJ_3_2"), "9001-DJ_3_2"},
+		{"final clue", clueID(9001, "FJ"), "9001-FJ"},
+		{"category", categoryID(9001, roundJeopardy, 4), "9001-J-4"},
+		{"final category", categoryID(9001, roundFinalJeopardy, 1), "9001-FJ-1"},
+		{"unknown column", categoryID(9001, roundJeopardy, 0), ""},
+		{"negative column", categoryID(9001, roundJeopardy, -1), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoardPosition(t *testing.T) {
+	tests := []struct {
+		round      string
+		cell       int
+		categories int
+		want       string
+	}{
+		{roundJeopardy, 0, 6, "J_1_1"},
+		{roundJeopardy, 5, 6, "J_6_1"},
+		{roundJeopardy, 6, 6, "J_1_2"},
+		{roundDoubleJeopardy, 29, 6, "DJ_6_5"},
+		{roundJeopardy, 7, 5, "J_3_2"},
+		{roundJeopardy, 3, 0, ""},
+		{roundFinalJeopardy, 0, 1, "FJ"},
+		{roundTiebreaker, 0, 0, "TB"},
+	}
+	for _, tt := range tests {
+		if got := boardPosition(tt.round, tt.cell, tt.categories); got != tt.want {
+			t.Errorf("boardPosition(%s, %d, %d) = %q, want %q", tt.round, tt.cell, tt.categories, got, tt.want)
+		}
+	}
+}
+
+func TestCluePositionParts(t *testing.T) {
+	tests := []struct {
+		position string
+		row      int
+		column   int
+	}{
+		{"J_1_1", 1, 1},
+		{"J_6_5", 5, 6},
+		{"DJ_3_2", 2, 3},
+		{"FJ", 0, 1},
+		{"TB", 0, 1},
+		{"", 0, 0},
+		{"J_x_2", 2, 0},
+		{"J_2", 0, 0},
+		{"J_1_2_3", 0, 0},
+	}
+	for _, tt := range tests {
+		if got := clueRowNumber(tt.position); got != tt.row {
+			t.Errorf("clueRowNumber(%q) = %d, want %d", tt.position, got, tt.row)
+		}
+		if got := clueColumn(tt.position); got != tt.column {
+			t.Errorf("clueColumn(%q) = %d, want %d", tt.position, got, tt.column)
+		}
+	}
+}
+
+func TestClueCategory(t *testing.T) {
+	round := Round{Name: roundJeopardy, Categories: []Category{{Name: "ONE"}, {Name: "TWO"}}}
+	tests := []struct {
+		position string
+		want     string
+		column   int
+	}{
+		{"J_1_3", "ONE", 1},
+		{"J_2_1", "TWO", 2},
+		{"J_3_1", "", 0},
+		{"FJ", "ONE", 1},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		category, column := clueCategory(round, Clue{Position: tt.position})
+		if category.Name != tt.want || column != tt.column {
+			t.Errorf("clueCategory(%q) = %q, %d, want %q, %d", tt.position, category.Name, column, tt.want, tt.column)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 //TODO : Add contestant, incorrect response, and triple stumper to clue.
 import (
-	"encoding/csv"
 	"fmt"
 	"log"
 	"os"
@@ -12,55 +11,30 @@ import (
 	"strings"
 
 	"encoding/json"
-	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	_ "github.com/mattn/go-sqlite3" // SQLite driver
+	"github.com/tlegnard/answer-there/jarchive"
 )
 
-type Clue struct {
-	Position          string
-	Value             string
-	OrderNumber       int
-	Text              string
-	CorrectResponse   string
-	CorrectContestant string
-}
-
-// Round struct represents a round of the game
-type Round struct {
-	Name       string
-	Categories []string
-	Clues      []Clue
-	// GameID     int
-}
-
-type Contestant struct {
-	PlayerID string
-	Name     string
-	Nickname string
-	Bio      string
-}
-
-// GameData struct represents the game data including multiple rounds
-type GameData struct {
-	ID          int
-	Rounds      []Round
-	Contestants []Contestant
-	ShowNum     int
-	AirDate     string
-	TapeDate    string
-}
-
-type SeasonData struct {
-	ID    string // Season ID, e.g., "40"
-	Games []GameData
-}
+// The game types live in the jarchive package so other programs can read
+// the database with them
+type (
+	Clue            = jarchive.Clue
+	Response        = jarchive.Response
+	RoundScore      = jarchive.RoundScore
+	ContestantScore = jarchive.ContestantScore
+	Category        = jarchive.Category
+	Round           = jarchive.Round
+	Contestant      = jarchive.Contestant
+	GameData        = jarchive.GameData
+	SeasonData      = jarchive.SeasonData
+)
 
 func extractCluePosition(clueHTMLText string) (string, error) {
 	// Define a regular expression pattern for the ID
-	re := regexp.MustCompile(`(clue_)((J|DJ)_(\d+_\d+))`)
+	re := regexp.MustCompile(`(clue_)((J|DJ)_(\d+_\d+)|FJ|TB)`)
 
 	// Find the first match in the HTML text
 	matches := re.FindStringSubmatch(clueHTMLText)
@@ -82,26 +56,40 @@ func extractId(textHTML string, match_string string) (string, error) {
 
 }
 
-func parseDoc(Data string) *goquery.Document {
+func parseDoc(Data string) (*goquery.Document, error) {
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(Data))
 	if err != nil {
-		fmt.Println("no url found")
-		log.Fatal(err)
+		return nil, fmt.Errorf("failed to parse HTML document: %v", err)
 	}
-	return doc
+	return doc, nil
 }
 
-func parseGameTableData(gameData string) GameData {
+// parserVersion is stored with every game so rows written by an older parser
+// can be found and refreshed by the reparse command. Bump it whenever a change
+// to parseGameTableData alters the data it produces.
+const parserVersion = 6
+
+// parseGameTableData parses a J-Archive game page. Problems that leave the
+// game usable are returned as warnings; an error means nothing usable was found.
+func parseGameTableData(gameData string) (GameData, []ParseWarning, error) {
 	var game GameData
+	var warnings []ParseWarning
 
-	doc := parseDoc(gameData)
+	doc, err := parseDoc(gameData)
+	if err != nil {
+		return game, nil, err
+	}
 
 	// Extract show number and air date from title
 	title := doc.Find("title").Text()
 	showNumRegex := regexp.MustCompile(`Show #(\d+)`)
 	airDateRegex := regexp.MustCompile(`aired (\d{4}-\d{2}-\d{2})`)
 	if showNumMatch := showNumRegex.FindStringSubmatch(title); len(showNumMatch) > 1 {
-		game.ShowNum, _ = strconv.Atoi(showNumMatch[1])
+		showNum, err := strconv.Atoi(showNumMatch[1])
+		if err != nil {
+			warnings = append(warnings, newParseWarning(warnShowNumber, "invalid show number %q: %v", showNumMatch[1], err))
+		}
+		game.ShowNum = showNum
 	}
 	if airDateMatch := airDateRegex.FindStringSubmatch(title); len(airDateMatch) > 1 {
 		game.AirDate = airDateMatch[1]
@@ -123,7 +111,9 @@ func parseGameTableData(gameData string) GameData {
 			htmlText, _ = contestantHtml.Html()
 
 			contestant.Name = contestantHtml.Find("a").Text()
-			contestant.Nickname = strings.Fields(contestant.Name)[0]
+			if fields := strings.Fields(contestant.Name); len(fields) > 0 {
+				contestant.Nickname = fields[0]
+			}
 			contestant.PlayerID, _ = extractId(htmlText, "player_id")
 
 			// Filter out text matching contestant.Name
@@ -140,21 +130,43 @@ func parseGameTableData(gameData string) GameData {
 	})
 
 	// Find each round table
+	regularRounds, finalRounds := 0, 0
 	doc.Find("table").FilterFunction(func(_ int, tableHtml *goquery.Selection) bool {
 		return tableHtml.HasClass("round") || tableHtml.HasClass("final_round")
 	}).Each(func(roundIndex int, roundHtml *goquery.Selection) {
 		var round Round
 
+		// Older pages can be missing a round entirely, so prefer the
+		// enclosing div over the table's position on the page
 		if roundHtml.HasClass("final_round") {
-			round.Name = "Final Jeopardy"
-		} else if roundIndex == 0 {
-			round.Name = "Jeopardy! Round"
-		} else if roundIndex == 1 {
-			round.Name = "Double Jeopardy! Round"
+			// Any final round after the first is a tiebreaker
+			if finalRounds == 0 {
+				round.Name = roundFinalJeopardy
+			} else {
+				round.Name = roundTiebreaker
+			}
+			finalRounds++
+		} else {
+			switch roundHtml.Closest("div").AttrOr("id", "") {
+			case "jeopardy_round":
+				round.Name = roundJeopardy
+			case "double_jeopardy_round":
+				round.Name = roundDoubleJeopardy
+			default:
+				if regularRounds == 0 {
+					round.Name = roundJeopardy
+				} else {
+					round.Name = roundDoubleJeopardy
+				}
+			}
+			regularRounds++
 		}
 		roundHtml.Find("td.category").Each(func(index int, categoryHtml *goquery.Selection) {
-			categoryName := categoryHtml.Find("td.category_name").Text()
-			round.Categories = append(round.Categories, categoryName)
+			categoryNameHtml := categoryHtml.Find("td.category_name")
+			round.Categories = append(round.Categories, Category{
+				Name:     plainText(categoryNameHtml),
+				NameHTML: sanitizedHTML(categoryNameHtml),
+			})
 		})
 
 		// Parse Clues for the round
@@ -167,30 +179,76 @@ func parseGameTableData(gameData string) GameData {
 				return
 			}
 
+			// Unrevealed clues have no id, so place them by their cell on the board
+			if position == "" {
+				position = boardPosition(round.Name, index, len(round.Categories))
+			}
+
 			clue.Position = position
 			clue.Value = clueHtml.Find("td.clue_value").Text()
-			clue.OrderNumber, _ = strconv.Atoi(clueHtml.Find("td.clue_order_number").Text())
+			if dailyDoubleHtml := clueHtml.Find("td.clue_value_daily_double"); dailyDoubleHtml.Length() > 0 {
+				clue.DailyDouble = true
+				wager, err := parseMoney(dailyDoubleHtml.Text())
+				if err != nil {
+					warnings = append(warnings, newParseWarning(warnWager, "%s %s: %v", round.Name, position, err))
+				}
+				clue.Wager = wager
+			}
+			if orderText := strings.TrimSpace(clueHtml.Find("td.clue_order_number").Text()); orderText != "" {
+				orderNumber, err := strconv.Atoi(orderText)
+				if err != nil {
+					warnings = append(warnings, newParseWarning(warnOrderNumber, "%s %s: invalid order number %q", round.Name, position, orderText))
+				}
+				clue.OrderNumber = orderNumber
+			}
 
-			clue.Text = clueHtml.Find("td.clue_text").First().Text()
-			clue.CorrectResponse = clueHtml.Find("td.clue_text em.correct_response").Text()
+			clueTextHtml := clueHtml.Find("td.clue_text").First()
+			clue.Text = plainText(clueTextHtml)
+			clue.TextHTML = sanitizedHTML(clueTextHtml)
+
+			correctResponseHtml := clueHtml.Find("td.clue_text em.correct_response")
+			clue.CorrectResponse = plainText(correctResponseHtml)
+			clue.CorrectResponseHTML = sanitizedHTML(correctResponseHtml)
 
 			// Extract correct contestant's name
 			clueHtml.Find("td.clue_text table").Each(func(_ int, subTableHtml *goquery.Selection) {
 				clue.CorrectContestant = subTableHtml.Find("td.right").Text()
 			})
 
+			var responseWarnings []ParseWarning
+			clue.Responses, clue.TripleStumper, responseWarnings = parseResponses(clueHtml, round.Name)
+			warnings = append(warnings, responseWarnings...)
+
 			round.Clues = append(round.Clues, clue)
 		})
+		fillDailyDoubleValues(&round)
 
 		game.Rounds = append(game.Rounds, round)
 	})
 
-	return game
+	if len(game.Rounds) == 0 {
+		return game, warnings, fmt.Errorf("no rounds found in game page %q", title)
+	}
+
+	var scoreWarnings []ParseWarning
+	game.RoundScores, scoreWarnings = parseRoundScores(doc)
+	warnings = append(warnings, scoreWarnings...)
+
+	game.ParserVersion = parserVersion
+	classifyGame(&game, readPageLayout(doc))
+	warnings = append(warnings, validateGame(game)...)
+	timeline := replayScores(game)
+	game.ScoreTimeline = timeline.Events
+	warnings = append(warnings, timeline.Warnings()...)
+	return game, warnings, nil
 }
-func GetSeasonList(seasonListHTML string) []string {
+func GetSeasonList(seasonListHTML string) ([]string, error) {
 	var seasons []string
 
-	doc := parseDoc(seasonListHTML)
+	doc, err := parseDoc(seasonListHTML)
+	if err != nil {
+		return nil, err
+	}
 
 	doc.Find("a[href*='showseason.php?season=']").Each(func(i int, s *goquery.Selection) {
 		href, exists := s.Attr("href")
@@ -212,75 +270,51 @@ func GetSeasonList(seasonListHTML string) []string {
 			}
 		}
 	})
-	return seasons
+	return seasons, nil
 }
 
-func GetSeasonGameList(seasonData string) []int {
-	var seasonList []int
-
-	doc := parseDoc(seasonData)
-
-	doc.Find("a[href*='showgame.php?game_id=']").Each(func(i int, s *goquery.Selection) {
-		href, exists := s.Attr("href")
-		if exists {
-			gameIDtext, _ := extractId(href, "game_id")
-			gameID, err := strconv.Atoi(gameIDtext)
-			if err != nil {
-				panic(err)
-			}
-			seasonList = append(seasonList, gameID)
-		}
-	})
-
-	return seasonList
+// catalogGame is a game as listed on its season's page
+type catalogGame struct {
+	GameID   int
+	SeasonID string
+	ShowNum  int    // 0 if the listing has no show number
+	AirDate  string // "" if the listing has no air date
 }
 
-func writeCluesToCSV(filePath string, season SeasonData) {
-	file, err := os.Create(filePath)
-	if err != nil {
-		log.Fatalf("Failed to create CSV file: %v", err)
-	}
-	defer file.Close()
+// seasonListingRegex matches the text of a season page's game links, e.g.
+// "#8045, aired 2019-07-26"; pilots are listed as taped instead
+var seasonListingRegex = regexp.MustCompile(`#(\d+),\s*(?:aired|taped)\s*(\d{4}-\d{2}-\d{2})`)
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+// GetSeasonCatalog lists the games on a season page with their show
+// numbers and air dates
+func GetSeasonCatalog(seasonID, seasonData string) ([]catalogGame, error) {
+	var games []catalogGame
 
-	headers := []string{"SeasonID", "GameId", "RoundName", "Category", "Position", "Value", "OrderNumber", "Text", "CorrectResponse"}
-	if err := writer.Write(headers); err != nil {
-		log.Fatalf("Failed to write headers to CSV file: %v", err)
+	doc, err := parseDoc(seasonData)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, game := range season.Games {
-		for _, round := range game.Rounds {
-			numCategories := len(round.Categories)
-			if numCategories == 0 {
-				log.Println("No categories found for round:", round.Name)
-				continue
+	doc.Find("a[href*='showgame.php?game_id=']").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		href, exists := s.Attr("href")
+		if exists {
+			gameIDtext, _ := extractId(href, "game_id")
+			gameID, convErr := strconv.Atoi(gameIDtext)
+			if convErr != nil {
+				err = fmt.Errorf("invalid game_id in link %q: %v", href, convErr)
+				return false
 			}
-
-			for clueIndex, clue := range round.Clues {
-				categoryIndex := clueIndex % numCategories // Assign clue to the correct category
-				category := round.Categories[categoryIndex]
-
-				record := []string{
-					season.ID,
-					strconv.Itoa(game.ID),
-					round.Name,
-					category,
-					clue.Position,
-					clue.Value,
-					strconv.Itoa(clue.OrderNumber),
-					clue.Text,
-					clue.CorrectResponse,
-				}
-				if err := writer.Write(record); err != nil {
-					log.Fatalf("Failed to write record to CSV file: %v", err)
-				}
+			game := catalogGame{GameID: gameID, SeasonID: seasonID}
+			if matches := seasonListingRegex.FindStringSubmatch(normalizeText(strings.ReplaceAll(s.Text(), "\u00a0", " "))); matches != nil {
+				game.ShowNum, _ = strconv.Atoi(matches[1])
+				game.AirDate = matches[2]
 			}
+			games = append(games, game)
 		}
-	}
+		return true
+	})
 
-	log.Println("Successfully wrote clues to CSV")
+	return games, err
 }
 
 func saveHTMLToFile(directory, filename, content string) error {
@@ -288,13 +322,8 @@ func saveHTMLToFile(directory, filename, content string) error {
 		return fmt.Errorf("failed to create directory %s: %v", directory, err)
 	}
 	filePath := filepath.Join(directory, filename)
-	file, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to create file %s: %v", filePath, err)
-	}
-	defer file.Close()
-	_, err = file.WriteString(content)
-	if err != nil {
+	// A page cut short by a crash would otherwise be loaded as if complete
+	if err := writeFileAtomic(filePath, []byte(content), 0644); err != nil {
 		return fmt.Errorf("failed to write content to file %s: %v", filePath, err)
 	}
 	log.Printf("Saved HTML to %s", filePath)
@@ -310,40 +339,23 @@ func loadHTMLFromFile(directory, filename string) (string, error) {
 	return string(content), nil
 }
 
-func RequestGameDataWithCache(gameID int, seasonID string) string {
-	seasonDir := fmt.Sprintf("data/season_%s", seasonID)
-	filename := fmt.Sprintf("%d_%s_j-archive.html", gameID, seasonID)
-	cachedContent, err := loadHTMLFromFile(seasonDir, filename)
-	if err == nil {
-		log.Printf("Loaded cached game data for game %d from %s", gameID, filename)
-		return cachedContent
-	}
-
-	log.Printf("Fetching game data for game %d from J-Archive", gameID)
-	gameData := RequestGameData(gameID) // Replace with the actual function to fetch game data from the web
-	saveErr := saveHTMLToFile(seasonDir, filename, gameData)
-	if saveErr != nil {
-		log.Printf("Error saving game data: %v", saveErr)
-	}
-	return gameData
-}
-
-// ProcessingState tracks progress for resuming interrupted processing
+// ProcessingState summarizes scrape progress. Resuming relies on the
+// database, so the state only lists games once their rows are committed.
 type ProcessingState struct {
 	LastCompletedSeason string
-	SeasonProgress      map[string][]int // Maps season ID to completed game IDs
+	SeasonProgress      map[string][]int // Maps season ID to stored game IDs
 	FailedGames         map[string][]int // Maps season ID to failed game IDs
 	LastUpdated         time.Time
 }
 
-// saveProcessingState saves current progress to a JSON file
+// saveProcessingState atomically replaces the JSON state file
 func saveProcessingState(state ProcessingState, filename string) error {
 	state.LastUpdated = time.Now()
 	data, err := json.Marshal(state)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filename, data, 0644)
+	return writeFileAtomic(filename, data, 0644)
 }
 
 // loadProcessingState loads progress from a JSON file
@@ -360,200 +372,34 @@ func loadProcessingState(filename string) (ProcessingState, error) {
 		}
 		return state, err
 	}
-	err = json.Unmarshal(data, &state)
-	return state, err
-}
-
-// processGame handles a single game with error reporting
-func processGame(gameID int, seasonID string, wg *sync.WaitGroup, results chan<- GameData, errors chan<- error) {
-	defer wg.Done()
-
-	// Recover from panics
-	defer func() {
-		if r := recover(); r != nil {
-			errors <- fmt.Errorf("panic processing game %d in season %s: %v", gameID, seasonID, r)
-		}
-	}()
-
-	gameData := RequestGameDataWithCache(gameID, seasonID)
-	game := parseGameTableData(gameData)
-	game.ID = gameID
-
-	results <- game
-}
-func readSeasonsFile(filename string) ([]string, error) {
-	content, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, err
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, err
 	}
-
-	// Split content by newlines and filter empty lines
-	var seasons []string
-	for _, season := range strings.Split(string(content), "\n") {
-		if trimmed := strings.TrimSpace(season); trimmed != "" {
-			seasons = append(seasons, trimmed)
-		}
+	if state.SeasonProgress == nil {
+		state.SeasonProgress = make(map[string][]int)
 	}
-	return seasons, nil
+	if state.FailedGames == nil {
+		state.FailedGames = make(map[string][]int)
+	}
+	return state, nil
 }
 
 func main() {
-	const (
-		stateFile     = "processing_state.json"
-		seasonsFile   = "seasons.txt"
-		maxConcurrent = 5
-		dbName        = "jeopardy.db"
-	)
-
-	// Load or initialize processing state
-	state, err := loadProcessingState(stateFile)
+	cfg, flags, err := parseGlobalFlags(os.Args[1:], os.Getenv)
 	if err != nil {
-		log.Fatalf("Failed to load processing state: %v", err)
-	}
-
-	// Try to read seasons from file first
-	var seasonsList []string
-	if seasons, err := readSeasonsFile(seasonsFile); err == nil {
-		fmt.Printf("Using seasons from %s\n", seasonsFile)
-		seasonsList = seasons
-	} else {
-		if !os.IsNotExist(err) {
-			log.Printf("Error reading %s: %v. Falling back to web scraping.", seasonsFile, err)
-		}
-		// Fall back to getting all seasons from web
-		seasonListHTML := RequestSeasonList("https://www.j-archive.com/listseasons.php")
-		seasonsList = GetSeasonList(seasonListHTML)
+		log.Fatal(err)
 	}
-
-	fmt.Printf("Found %d seasons to process\n", len(seasonsList))
-
-	// Process each season
-	for _, seasonID := range seasonsList {
-		// Skip if season was already completed
-		if seasonID == state.LastCompletedSeason {
-			continue
-		}
-
-		fmt.Printf("\nProcessing Season: %s\n", seasonID)
-		seasonHTML := RequestSeason("https://j-archive.com/showseason.php?season=" + seasonID)
-		seasonGameList := GetSeasonGameList(seasonHTML)
-		fmt.Printf("Found %d games in Season %s\n", len(seasonGameList), seasonID)
-
-		var seasonData SeasonData
-		seasonData.ID = seasonID
-
-		// Create channels for results and errors
-		results := make(chan GameData, len(seasonGameList))
-		errors := make(chan error, len(seasonGameList))
-
-		// Process games concurrently with worker pool
-		var wg sync.WaitGroup
-		semaphore := make(chan struct{}, maxConcurrent)
-
-		for _, gameID := range seasonGameList {
-			// Skip if game was already processed successfully
-			found := false
-			for _, gameIDInProgress := range state.SeasonProgress[seasonID] {
-				if gameIDInProgress == gameID {
-					found = true
-					break
-				}
-			}
-			if found {
-				fmt.Printf("\rSkipping already processed game %d", gameID)
-				continue
-			}
-
-			wg.Add(1)
-			semaphore <- struct{}{} // Acquire semaphore
-
-			go func(gID int) {
-				processGame(gID, seasonID, &wg, results, errors)
-				<-semaphore // Release semaphore
-			}(gameID)
-		}
-
-		// Start a goroutine to close channels when all games are processed
-		go func() {
-			wg.Wait()
-			close(results)
-			close(errors)
-		}()
-
-		// Collect results and errors
-		var processedGames []GameData
-		var failedGames []int
-
-		// Process results as they come in
-		for game := range results {
-			processedGames = append(processedGames, game)
-			state.SeasonProgress[seasonID] = append(state.SeasonProgress[seasonID], game.ID)
-
-			// Print progress
-			fmt.Printf("\rProcessed %d/%d games", len(processedGames), len(seasonGameList))
-
-			// Save state periodically (every 10 games)
-			if len(processedGames)%10 == 0 {
-				if err := saveProcessingState(state, stateFile); err != nil {
-					log.Printf("\nError saving processing state: %v", err)
-				}
-			}
-		}
-
-		//  TODO:Process any errors
-		// for err := range errors {
-		// 	log.Printf("\nError: %v", err)
-		// 	if gameID := extractGameIDFromError(err); gameID != 0 {
-		// 		failedGames = append(failedGames, gameID)
-		// 	}
-		// }
-
-		seasonData.Games = processedGames
-
-		// Write season data to database if we have processed games
-		if len(seasonData.Games) > 0 {
-			// Wrap database operations in a transaction if possible
-			// if err := writeGameList(dbName, seasonData); err != nil {
-			// 	log.Printf("\nError writing game list: %v", err)
-			// }
-			// if err := writeClues(dbName, seasonData); err != nil {
-			// 	log.Printf("\nError writing clues: %v", err)
-			// }
-			// if err := writeContestants(dbName, seasonData); err != nil {
-			// 	log.Printf("\nError writing contestants: %v", err)
-			// }
-			// if err := writeCategories(dbName, seasonData); err != nil {
-			// 	log.Printf("\nError writing categories: %v", err)
-			// }
-			writeGameList(dbName, seasonData)
-			writeClues(dbName, seasonData)
-			writeContestants(dbName, seasonData)
-			writeCategories(dbName, seasonData)
-		}
-
-		// Update state
-		state.LastCompletedSeason = seasonID
-		state.FailedGames[seasonID] = failedGames
-		if err := saveProcessingState(state, stateFile); err != nil {
-			log.Printf("\nError saving final state for season %s: %v", seasonID, err)
-		}
-
-		fmt.Printf("\nSeason %s: Successfully processed %d games, Failed %d games\n",
-			seasonID, len(processedGames), len(failedGames))
-
-		// Optional delay between seasons to be nice to the server
-		time.Sleep(2 * time.Second)
+	ctx, stop := shutdownContext()
+	runCommand(ctx, cfg, flags)
+	interrupted := ctx.Err() != nil
+	stop()
+	if interrupted {
+		os.Exit(130)
 	}
-
-	fmt.Println("\nFinished processing all seasons")
 }
 
 //TODO
-// write something to generate the order in which the game was played, and the money earned (I might just be able to write a query for this)
-//finalize the tables and data models. add incexes, PKs foreign keys, etc./
-//add season list table
 //cleanup the code, can probaly write one handler and pas in schema to write the tables
 
 //plug into superset/visualization
 //sentiment analysis on categories
-//cli to generate categories to quiz myself on
@@ -0,0 +1,729 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+)
+
+// migration is one versioned change to the database schema. Migrations run
+// in version order, each in its own transaction, and are recorded in the
+// schema_version table so they are applied exactly once.
+type migration struct {
+	Version     int
+	Description string
+	Up          func(tx *sql.Tx) error
+	// RebuildsParent is set when Up replaces a table that other tables
+	// reference. Dropping the old table would cascade to their rows, so
+	// foreign keys are off while it runs and checked before it commits.
+	RebuildsParent bool
+}
+
+// migrations must only ever be appended to. Never edit a migration that has
+// been released, add a new one instead.
+var migrations = []migration{
+	{
+		Version:     1,
+		Description: "create gamelist, clues, game_roster and categories tables",
+		Up: execStatements(`
+			CREATE TABLE IF NOT EXISTS gamelist (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				season_id TEXT NOT NULL,
+				game_id INTEGER NOT NULL UNIQUE,
+				show_num INTEGER NOT NULL UNIQUE,
+				air_date DATE NOT NULL,
+				tape_date DATE NOT NULL
+			);`, `
+			CREATE TABLE IF NOT EXISTS clues (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				season_id TEXT NOT NULL,
+				game_id INTEGER NOT NULL,
+				round_name TEXT NOT NULL,
+				category TEXT NOT NULL,
+				position TEXT,
+				value TEXT,
+				order_number INTEGER,
+				text TEXT NOT NULL,
+				correct_response TEXT,
+				correct_contestant TEXT
+			);`, `
+			CREATE TABLE IF NOT EXISTS game_roster (
+				player_id TEXT NOT NULL,
+				season_id TEXT NOT NULL,
+				game_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				nickname TEXT,
+				bio TEXT
+			);`, `
+			CREATE TABLE IF NOT EXISTS categories (
+				category_id TEXT PRIMARY KEY,
+				season_id TEXT NOT NULL,
+				game_id INTEGER NOT NULL,
+				round_name TEXT NOT NULL,
+				category_name TEXT NOT NULL
+			);`),
+	},
+	{
+		Version:     2,
+		Description: "add sanitized HTML columns to clues and categories",
+		Up: func(tx *sql.Tx) error {
+			for _, column := range []struct{ table, name string }{
+				{"clues", "text_html"},
+				{"clues", "correct_response_html"},
+				{"categories", "category_name_html"},
+			} {
+				if err := addColumnIfMissing(tx, column.table, column.name, "TEXT"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     3,
+		Description: "create parse_warnings table",
+		Up: execStatements(`
+			CREATE TABLE IF NOT EXISTS parse_warnings (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				season_id TEXT NOT NULL,
+				game_id INTEGER NOT NULL,
+				code TEXT NOT NULL,
+				message TEXT NOT NULL
+			);`),
+	},
+	{
+		Version:     4,
+		Description: "rebuild gamelist with parser version, era, format and nullable dates",
+		Up: func(tx *sql.Tx) error {
+			// Databases written before migrations existed may already have some of these
+			for _, column := range []struct{ name, definition string }{
+				{"era", "TEXT"},
+				{"format", "TEXT"},
+				{"unavailable_fields", "TEXT"},
+				{"parser_version", "INTEGER NOT NULL DEFAULT 0"},
+			} {
+				if err := addColumnIfMissing(tx, "gamelist", column.name, column.definition); err != nil {
+					return err
+				}
+			}
+			// SQLite cannot drop NOT NULL constraints, so copy into a new table
+			return execStatements(`
+				CREATE TABLE gamelist_new (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					season_id TEXT NOT NULL,
+					game_id INTEGER NOT NULL UNIQUE,
+					show_num INTEGER UNIQUE,
+					air_date DATE,
+					tape_date DATE,
+					era TEXT,
+					format TEXT,
+					unavailable_fields TEXT,
+					parser_version INTEGER NOT NULL DEFAULT 0
+				);`, `
+				INSERT INTO gamelist_new (
+					id, season_id, game_id, show_num, air_date, tape_date, era, format, unavailable_fields, parser_version
+				)
+				SELECT id, season_id, game_id, NULLIF(show_num, 0), NULLIF(air_date, ''), NULLIF(tape_date, ''),
+					era, format, unavailable_fields, parser_version
+				FROM gamelist;`,
+				`DROP TABLE gamelist;`,
+				`ALTER TABLE gamelist_new RENAME TO gamelist;`,
+			)(tx)
+		},
+	},
+	{
+		Version:     5,
+		Description: "index per-game rows and create contestants view once",
+		Up: execStatements(
+			`CREATE INDEX IF NOT EXISTS idx_clues_game_id ON clues (game_id);`,
+			`CREATE INDEX IF NOT EXISTS idx_game_roster_game_id ON game_roster (game_id);`,
+			`CREATE INDEX IF NOT EXISTS idx_categories_game_id ON categories (game_id);`,
+			`CREATE INDEX IF NOT EXISTS idx_parse_warnings_game_id ON parse_warnings (game_id);`,
+			`DROP VIEW IF EXISTS contestants;`,
+			`CREATE VIEW contestants AS SELECT DISTINCT player_id, name FROM game_roster;`,
+		),
+	},
+	{
+		Version:     6,
+		Description: "link clues to categories by category_id",
+		Up: func(tx *sql.Tx) error {
+			if err := addColumnIfMissing(tx, "clues", "category_id", "TEXT REFERENCES categories (category_id)"); err != nil {
+				return err
+			}
+			return execStatements(`CREATE INDEX IF NOT EXISTS idx_clues_category_id ON clues (category_id);`)(tx)
+		},
+	},
+	{
+		Version:     7,
+		Description: "replace flat tables with normalized schema, keeping old rows in legacy_* tables",
+		// The flat tables may hold duplicate rows from earlier runs, so they are
+		// kept as they are rather than copied into tables with unique keys.
+		// migrateDatabase then copies them across with repairDatabase.
+		Up: func(tx *sql.Tx) error {
+			if err := retireFlatTables(tx); err != nil {
+				return err
+			}
+			return execStatements(normalizedSchema...)(tx)
+		},
+	},
+	{
+		Version:     8,
+		Description: "create settings table",
+		Up: execStatements(`
+			CREATE TABLE IF NOT EXISTS settings (
+				key TEXT PRIMARY KEY,
+				value TEXT NOT NULL
+			);`),
+	},
+	{
+		Version:     9,
+		Description: "create clue_revisions table",
+		// Clues are replaced on every write, so revisions are not tied to clue rows
+		Up: execStatements(`
+			CREATE TABLE clue_revisions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				game_id INTEGER NOT NULL REFERENCES games (game_id) ON DELETE CASCADE,
+				clue_id TEXT NOT NULL,
+				field TEXT NOT NULL,
+				old_value TEXT,
+				new_value TEXT,
+				parser_version INTEGER NOT NULL,
+				revised_at TIMESTAMP NOT NULL
+			);`,
+			`CREATE INDEX idx_clue_revisions_game_id ON clue_revisions (game_id);`,
+		),
+	},
+	{
+		Version:     10,
+		Description: "create staging tables for imported community datasets",
+		Up: execStatements(`
+			CREATE TABLE import_batches (
+				batch_id INTEGER PRIMARY KEY AUTOINCREMENT,
+				path TEXT NOT NULL,
+				format TEXT NOT NULL,
+				imported_at TIMESTAMP NOT NULL
+			);`, `
+			CREATE TABLE staged_clues (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				batch_id INTEGER NOT NULL REFERENCES import_batches (batch_id) ON DELETE CASCADE,
+				show_num INTEGER,
+				air_date DATE,
+				round_code TEXT,
+				category TEXT,
+				value INTEGER,
+				daily_double INTEGER NOT NULL DEFAULT 0,
+				text TEXT,
+				correct_response TEXT,
+				match_status TEXT NOT NULL,
+				game_id INTEGER,
+				clue_id TEXT,
+				discrepancies TEXT
+			);`,
+			`CREATE INDEX idx_staged_clues_batch_id ON staged_clues (batch_id);`,
+			`CREATE INDEX idx_staged_clues_air_date ON staged_clues (air_date);`,
+		),
+	},
+	{
+		Version:     11,
+		Description: "create season_games catalog of the games listed on season pages",
+		Up: execStatements(`
+			CREATE TABLE season_games (
+				game_id INTEGER PRIMARY KEY,
+				season_id TEXT NOT NULL,
+				show_num INTEGER,
+				air_date DATE,
+				listed_at TIMESTAMP NOT NULL
+			);`,
+			`CREATE INDEX idx_season_games_season_id ON season_games (season_id);`,
+			`CREATE INDEX idx_season_games_air_date ON season_games (air_date);`,
+		),
+	},
+	{
+		Version:     12,
+		Description: "create failed_games to track games that could not be scraped",
+		Up: execStatements(`
+			CREATE TABLE failed_games (
+				game_id INTEGER PRIMARY KEY,
+				season_id TEXT NOT NULL,
+				stage TEXT NOT NULL,
+				reason TEXT NOT NULL,
+				attempts INTEGER NOT NULL,
+				first_failed_at TIMESTAMP NOT NULL,
+				last_failed_at TIMESTAMP NOT NULL
+			);`,
+		),
+	},
+	{
+		Version:     13,
+		Description: "create completed_seasons to checkpoint scrapes in the database",
+		Up: execStatements(`
+			CREATE TABLE completed_seasons (
+				season_id TEXT PRIMARY KEY,
+				games INTEGER NOT NULL,
+				completed_at TIMESTAMP NOT NULL
+			);`,
+		),
+	},
+	{
+		Version:     14,
+		Description: "create runs ledger recording what each scrape did",
+		Up: execStatements(`
+			CREATE TABLE runs (
+				run_id INTEGER PRIMARY KEY AUTOINCREMENT,
+				command TEXT NOT NULL,
+				args TEXT NOT NULL,
+				started_at TIMESTAMP NOT NULL,
+				finished_at TIMESTAMP,
+				status TEXT NOT NULL,
+				seasons TEXT NOT NULL,
+				games_fetched INTEGER NOT NULL,
+				games_cached INTEGER NOT NULL,
+				bytes_downloaded INTEGER NOT NULL,
+				games_stored INTEGER NOT NULL,
+				parse_warnings INTEGER NOT NULL,
+				failures INTEGER NOT NULL,
+				parser_version INTEGER NOT NULL
+			);`,
+		),
+	},
+	{
+		Version:     15,
+		Description: "create score_timeline holding running scores after each clue",
+		Up: execStatements(`
+			CREATE TABLE score_timeline (
+				game_id INTEGER NOT NULL REFERENCES games (game_id) ON DELETE CASCADE,
+				seq INTEGER NOT NULL,
+				round TEXT NOT NULL,
+				position TEXT NOT NULL,
+				order_number INTEGER,
+				nickname TEXT NOT NULL,
+				delta INTEGER NOT NULL,
+				score INTEGER NOT NULL,
+				scores TEXT NOT NULL,
+				PRIMARY KEY (game_id, seq)
+			);`,
+		),
+	},
+	{
+		Version:     16,
+		Description: "record whether a clue revision came from J-Archive or a parser change",
+		// parser_version is the version that produced new_value; rows written
+		// before this migration cannot tell the two sources apart
+		Up: execStatements(
+			`ALTER TABLE clue_revisions ADD COLUMN source TEXT NOT NULL DEFAULT 'archive';`,
+			`ALTER TABLE clue_revisions ADD COLUMN old_parser_version INTEGER;`,
+		),
+	},
+	{
+		Version:     17,
+		Description: "record why an imported row could not be read",
+		Up:          execStatements(`ALTER TABLE staged_clues ADD COLUMN invalid_reason TEXT;`),
+	},
+	{
+		Version:     18,
+		Description: "allow games to share a show number",
+		// Some games have no show number and some special and pilot games are
+		// listed under one another game already has. SQLite cannot drop the
+		// unique constraint, so the table is copied into one with an index.
+		RebuildsParent: true,
+		Up: execStatements(`
+			CREATE TABLE games_new (
+				game_id INTEGER PRIMARY KEY,
+				season_id TEXT NOT NULL REFERENCES seasons (season_id),
+				show_num INTEGER,
+				air_date DATE,
+				tape_date DATE,
+				era TEXT,
+				format TEXT,
+				unavailable_fields TEXT,
+				parser_version INTEGER NOT NULL DEFAULT 0
+			);`, `
+			INSERT INTO games_new (
+				game_id, season_id, show_num, air_date, tape_date, era, format, unavailable_fields, parser_version
+			)
+			SELECT game_id, season_id, show_num, air_date, tape_date, era, format, unavailable_fields, parser_version
+			FROM games;`,
+			`DROP TABLE games;`,
+			`ALTER TABLE games_new RENAME TO games;`,
+			`CREATE INDEX idx_games_season_id ON games (season_id);`,
+			`CREATE INDEX idx_games_air_date ON games (air_date);`,
+			`CREATE INDEX idx_games_show_num ON games (show_num);`,
+		),
+	},
+}
+
+// flatTables are the tables written before the normalized schema
+var flatTables = []string{"gamelist", "clues", "categories", "game_roster"}
+
+// retireFlatTables makes way for the normalized tables. Flat tables holding
+// rows are renamed to legacy_* for repairDatabase to copy, and empty ones,
+// as on a fresh database, are dropped.
+func retireFlatTables(tx *sql.Tx) error {
+	hasRows := false
+	for _, table := range flatTables {
+		var found int
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM ` + table + `);`).Scan(&found)
+		if err != nil {
+			return err
+		}
+		if found == 1 {
+			hasRows = true
+		}
+	}
+
+	statements := []string{
+		`DROP VIEW IF EXISTS contestants;`,
+		`DROP INDEX IF EXISTS idx_clues_game_id;`,
+		`DROP INDEX IF EXISTS idx_clues_category_id;`,
+		`DROP INDEX IF EXISTS idx_game_roster_game_id;`,
+		`DROP INDEX IF EXISTS idx_categories_game_id;`,
+	}
+	for _, table := range flatTables {
+		if hasRows {
+			statements = append(statements, `ALTER TABLE `+table+` RENAME TO legacy_`+table+`;`)
+		} else {
+			statements = append(statements, `DROP TABLE `+table+`;`)
+		}
+	}
+	return execStatements(statements...)(tx)
+}
+
+// normalizedSchema creates the tables that replaced the flat tables in migration 7
+var normalizedSchema = []string{
+	`
+	CREATE TABLE seasons (
+		season_id TEXT PRIMARY KEY
+	);`, `
+	CREATE TABLE games (
+		game_id INTEGER PRIMARY KEY,
+		season_id TEXT NOT NULL REFERENCES seasons (season_id),
+		show_num INTEGER UNIQUE,
+		air_date DATE,
+		tape_date DATE,
+		era TEXT,
+		format TEXT,
+		unavailable_fields TEXT,
+		parser_version INTEGER NOT NULL DEFAULT 0
+	);`,
+	`CREATE INDEX idx_games_season_id ON games (season_id);`,
+	`CREATE INDEX idx_games_air_date ON games (air_date);`,
+	`
+	CREATE TABLE rounds (
+		round_id TEXT PRIMARY KEY,
+		game_id INTEGER NOT NULL REFERENCES games (game_id) ON DELETE CASCADE,
+		round_code TEXT NOT NULL,
+		name TEXT NOT NULL,
+		ordinal INTEGER NOT NULL,
+		UNIQUE (game_id, round_code)
+	);`, `
+	CREATE TABLE categories (
+		category_id TEXT PRIMARY KEY,
+		game_id INTEGER NOT NULL REFERENCES games (game_id) ON DELETE CASCADE,
+		round_id TEXT NOT NULL REFERENCES rounds (round_id) ON DELETE CASCADE,
+		column_num INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		name_html TEXT,
+		UNIQUE (round_id, column_num)
+	);`,
+	`CREATE INDEX idx_categories_game_id ON categories (game_id);`,
+	`CREATE INDEX idx_categories_name ON categories (name);`,
+	`
+	CREATE TABLE clues (
+		clue_id TEXT PRIMARY KEY,
+		game_id INTEGER NOT NULL REFERENCES games (game_id) ON DELETE CASCADE,
+		round_id TEXT NOT NULL REFERENCES rounds (round_id) ON DELETE CASCADE,
+		category_id TEXT REFERENCES categories (category_id) ON DELETE CASCADE,
+		position TEXT NOT NULL,
+		row_num INTEGER,
+		value INTEGER,
+		order_number INTEGER,
+		daily_double INTEGER NOT NULL DEFAULT 0,
+		wager INTEGER,
+		triple_stumper INTEGER NOT NULL DEFAULT 0,
+		text TEXT,
+		text_html TEXT,
+		correct_response TEXT,
+		correct_response_html TEXT,
+		UNIQUE (game_id, position)
+	);`,
+	`CREATE INDEX idx_clues_game_id ON clues (game_id);`,
+	`CREATE INDEX idx_clues_category_id ON clues (category_id);`,
+	`
+	CREATE TABLE players (
+		player_id TEXT PRIMARY KEY,
+		name TEXT NOT NULL
+	);`,
+	`CREATE INDEX idx_players_name ON players (name);`,
+	`
+	CREATE TABLE appearances (
+		game_id INTEGER NOT NULL REFERENCES games (game_id) ON DELETE CASCADE,
+		seat INTEGER NOT NULL,
+		player_id TEXT REFERENCES players (player_id),
+		name TEXT NOT NULL,
+		nickname TEXT,
+		bio TEXT,
+		PRIMARY KEY (game_id, seat)
+	);`,
+	`CREATE INDEX idx_appearances_player_id ON appearances (player_id);`,
+	`
+	CREATE TABLE responses (
+		clue_id TEXT NOT NULL REFERENCES clues (clue_id) ON DELETE CASCADE,
+		seq INTEGER NOT NULL,
+		game_id INTEGER NOT NULL REFERENCES games (game_id) ON DELETE CASCADE,
+		nickname TEXT NOT NULL,
+		player_id TEXT REFERENCES players (player_id),
+		correct INTEGER NOT NULL,
+		PRIMARY KEY (clue_id, seq)
+	);`,
+	`CREATE INDEX idx_responses_game_id ON responses (game_id);`,
+	`CREATE INDEX idx_responses_player_id ON responses (player_id);`,
+	`
+	CREATE TABLE final_jeopardy (
+		game_id INTEGER NOT NULL REFERENCES games (game_id) ON DELETE CASCADE,
+		round_code TEXT NOT NULL,
+		seq INTEGER NOT NULL,
+		nickname TEXT NOT NULL,
+		player_id TEXT REFERENCES players (player_id),
+		response TEXT,
+		wager INTEGER,
+		correct INTEGER NOT NULL,
+		PRIMARY KEY (game_id, round_code, seq)
+	);`,
+	`CREATE INDEX idx_final_jeopardy_player_id ON final_jeopardy (player_id);`,
+	// Kept for queries written against the old view
+	`CREATE VIEW contestants AS SELECT player_id, name FROM players;`,
+}
+
+// execStatements returns a migration step that runs each statement in order
+func execStatements(statements ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// addColumnIfMissing adds a column unless an earlier version of the code already created it
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) error {
+	exists, err := columnExists(tx, table, column)
+	if err != nil || exists {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, column, definition))
+	return err
+}
+
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s);", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, colType    string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// appliedMigration is a row in the schema_version table
+type appliedMigration struct {
+	Version     int
+	Description string
+	AppliedAt   time.Time
+}
+
+func ensureSchemaVersionTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		);
+	`)
+	return err
+}
+
+// appliedMigrations returns the migrations already recorded in the database, keyed by version
+func appliedMigrations(db *sql.DB) (map[int]appliedMigration, error) {
+	if err := ensureSchemaVersionTable(db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT version, description, applied_at FROM schema_version;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var m appliedMigration
+		if err := rows.Scan(&m.Version, &m.Description, &m.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[m.Version] = m
+	}
+	return applied, rows.Err()
+}
+
+// pendingMigrations returns the migrations that have not been applied yet, in order
+func pendingMigrations(db *sql.DB) ([]migration, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// applyMigration runs a single migration and records it in the same transaction
+func applyMigration(db *sql.DB, m migration) error {
+	if m.RebuildsParent {
+		// The pragma is ignored inside a transaction. The database has a
+		// single connection, so it applies to the one the migration runs on.
+		if _, err := db.Exec(`PRAGMA foreign_keys = OFF;`); err != nil {
+			return err
+		}
+		defer db.Exec(`PRAGMA foreign_keys = ON;`)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Rows that already referenced a missing parent are left to doctor
+	var brokenBefore int
+	if m.RebuildsParent {
+		if brokenBefore, err = countForeignKeyErrors(tx); err != nil {
+			return err
+		}
+	}
+	if err := m.Up(tx); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %v", m.Version, m.Description, err)
+	}
+	if m.RebuildsParent {
+		broken, err := countForeignKeyErrors(tx)
+		if err != nil {
+			return err
+		}
+		if broken > brokenBefore {
+			return fmt.Errorf("migration %d (%s) left %d rows referencing a missing parent",
+				m.Version, m.Description, broken-brokenBefore)
+		}
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO schema_version (version, description, applied_at) VALUES (?, ?, ?);`,
+		m.Version, m.Description, time.Now().UTC(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// countForeignKeyErrors counts the rows that reference a missing parent row
+func countForeignKeyErrors(tx *sql.Tx) (int, error) {
+	var count int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_foreign_key_check;`).Scan(&count)
+	return count, err
+}
+
+// migrateDatabase brings the database schema up to date. It is called once
+// at startup before anything is written.
+func migrateDatabase(db *sql.DB) error {
+	pending, err := pendingMigrations(db)
+	if err != nil {
+		return err
+	}
+	return applyMigrations(db, pending, func(m migration) {
+		log.Printf("Applied migration %d: %s", m.Version, m.Description)
+	})
+}
+
+// applyMigrations applies pending migrations in order, calling applied after
+// each one. If migration 7 moved the flat tables aside, their rows are then
+// copied into the normalized tables, which can only be written once every
+// migration has run.
+func applyMigrations(db *sql.DB, pending []migration, applied func(migration)) error {
+	replacedFlatTables := false
+	for _, m := range pending {
+		if err := applyMigration(db, m); err != nil {
+			return err
+		}
+		applied(m)
+		replacedFlatTables = replacedFlatTables || m.Version == 7
+	}
+	if replacedFlatTables {
+		copyLegacyData(db)
+	}
+	return nil
+}
+
+// runMigrate implements the migrate command
+//
+//	migrate [-dry-run] [status]
+func runMigrate(db *sql.DB, args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "list pending migrations without applying them")
+	flags.Parse(args)
+
+	if flags.Arg(0) == "status" {
+		applied, err := appliedMigrations(db)
+		if err != nil {
+			log.Fatalf("Failed to read schema version: %v", err)
+		}
+		for _, m := range migrations {
+			if a, ok := applied[m.Version]; ok {
+				fmt.Printf("%3d  applied %s  %s\n", m.Version, a.AppliedAt.Local().Format("2006-01-02 15:04:05"), m.Description)
+			} else {
+				fmt.Printf("%3d  pending                      %s\n", m.Version, m.Description)
+			}
+		}
+		return
+	}
+
+	pending, err := pendingMigrations(db)
+	if err != nil {
+		log.Fatalf("Failed to read schema version: %v", err)
+	}
+	if len(pending) == 0 {
+		fmt.Println("Database schema is up to date")
+		return
+	}
+
+	if *dryRun {
+		for _, m := range pending {
+			fmt.Printf("Would apply %d: %s\n", m.Version, m.Description)
+		}
+		return
+	}
+	err = applyMigrations(db, pending, func(m migration) {
+		fmt.Printf("Applied %d: %s\n", m.Version, m.Description)
+	})
+	if err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// preMigrationSchema is the schema the write functions created before
+// migrations existed, with no schema_version table and no HTML, era or
+// parser version columns
+var preMigrationSchema = []string{`
+	CREATE TABLE gamelist (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		season_id TEXT NOT NULL,
+		game_id INTEGER NOT NULL UNIQUE,
+		show_num INTEGER NOT NULL UNIQUE,
+		air_date DATE NOT NULL,
+		tape_date DATE NOT NULL
+	);`, `
+	CREATE TABLE clues (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		season_id TEXT NOT NULL,
+		game_id INTEGER NOT NULL,
+		round_name TEXT NOT NULL,
+		category TEXT NOT NULL,
+		position TEXT,
+		value TEXT,
+		order_number INTEGER,
+		text TEXT NOT NULL,
+		correct_response TEXT,
+		correct_contestant TEXT
+	);`, `
+	CREATE TABLE game_roster (
+		player_id TEXT NOT NULL,
+		season_id TEXT NOT NULL,
+		game_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		nickname TEXT,
+		bio TEXT
+	);`, `
+	CREATE TABLE categories (
+		category_id TEXT PRIMARY KEY,
+		season_id TEXT NOT NULL,
+		game_id INTEGER NOT NULL,
+		round_name TEXT NOT NULL,
+		category_name TEXT NOT NULL
+	);`,
+	`CREATE VIEW contestants AS SELECT DISTINCT player_id, name FROM game_roster;`,
+	`INSERT INTO gamelist (season_id, game_id, show_num, air_date, tape_date) VALUES ('35', 9001, 8000, '2019-05-01', '');`,
+	`INSERT INTO categories VALUES ('aB3dE6gH', '35', 9001, 'Jeopardy! Round', 'CAT 1');`,
+	`INSERT INTO clues (season_id, game_id, round_name, category, position, value, order_number, text, correct_response)
+		VALUES ('35', 9001, 'Jeopardy! Round', 'CAT 1', 'J_1_1', '$200', 1, 'A clue', 'an answer');`,
+	`INSERT INTO game_roster VALUES ('1', '35', 9001, 'Alice Smith', 'Alice', 'a teacher');`,
+}
+
+// openTestDatabase opens an empty database in a temporary directory
+func openTestDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := openDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMigrationVersionsIncrease(t *testing.T) {
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d, want %d", i, m.Version, i+1)
+		}
+		if m.Description == "" {
+			t.Errorf("migration %d has no description", m.Version)
+		}
+	}
+}
+
+func TestMigrateDatabase(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup []string
+		// tables that must exist after migrating and the rows each must hold
+		rows map[string]int
+	}{
+		{
+			name: "fresh database",
+			rows: map[string]int{"games": 0, "clues": 0, "players": 0, "score_timeline": 0, "runs": 0},
+		},
+		{
+			// The flat rows are copied into the normalized tables
+			name:  "pre-migration database",
+			setup: preMigrationSchema,
+			rows:  map[string]int{"games": 1, "rounds": 1, "categories": 1, "clues": 1, "appearances": 1, "players": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDatabase(t)
+			for _, statement := range tt.setup {
+				if _, err := db.Exec(statement); err != nil {
+					t.Fatalf("setting up: %v", err)
+				}
+			}
+
+			if err := migrateDatabase(db); err != nil {
+				t.Fatalf("migrateDatabase: %v", err)
+			}
+			pending, err := pendingMigrations(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pending) != 0 {
+				t.Errorf("%d migrations still pending", len(pending))
+			}
+			applied, err := appliedMigrations(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(applied) != len(migrations) {
+				t.Errorf("%d migrations recorded, want %d", len(applied), len(migrations))
+			}
+			for table, want := range tt.rows {
+				var got int
+				if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table + `;`).Scan(&got); err != nil {
+					t.Errorf("counting %s: %v", table, err)
+					continue
+				}
+				if got != want {
+					t.Errorf("%s has %d rows, want %d", table, got, want)
+				}
+			}
+
+			// Legacy tables are dropped once copied and never created empty
+			for _, table := range legacyTables {
+				if exists, err := tableExists(db, table); err != nil || exists {
+					t.Errorf("%s exists after migrating: %v", table, err)
+				}
+			}
+
+			// A second start finds nothing to do
+			if err := migrateDatabase(db); err != nil {
+				t.Fatalf("migrating again: %v", err)
+			}
+			again, err := appliedMigrations(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for version, m := range applied {
+				if !again[version].AppliedAt.Equal(m.AppliedAt) {
+					t.Errorf("migration %d was applied again", version)
+				}
+			}
+		})
+	}
+}
+
+// TestMigrateCommand checks that the migrate command copies the flat rows
+// into the normalized tables the same way opening the database does
+func TestMigrateCommand(t *testing.T) {
+	db := openTestDatabase(t)
+	for _, statement := range preMigrationSchema {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("setting up: %v", err)
+		}
+	}
+
+	runMigrate(db, []string{"-dry-run"})
+	if applied, err := appliedMigrations(db); err != nil || len(applied) != 0 {
+		t.Fatalf("dry run applied %d migrations: %v", len(applied), err)
+	}
+
+	runMigrate(db, nil)
+	if pending, err := pendingMigrations(db); err != nil || len(pending) != 0 {
+		t.Fatalf("%d migrations still pending: %v", len(pending), err)
+	}
+	for table, want := range map[string]int{"games": 1, "rounds": 1, "categories": 1, "clues": 1, "appearances": 1, "players": 1} {
+		var got int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table + `;`).Scan(&got); err != nil {
+			t.Fatalf("counting %s: %v", table, err)
+		}
+		if got != want {
+			t.Errorf("%s has %d rows, want %d", table, got, want)
+		}
+	}
+	for _, table := range legacyTables {
+		if exists, err := tableExists(db, table); err != nil || exists {
+			t.Errorf("%s exists after migrating: %v", table, err)
+		}
+	}
+}
+
+func TestMigrationAddsColumnsToOldTables(t *testing.T) {
+	db := openTestDatabase(t)
+	for _, statement := range preMigrationSchema {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("setting up: %v", err)
+		}
+	}
+	if err := ensureSchemaVersionTable(db); err != nil {
+		t.Fatal(err)
+	}
+	// Stop before the flat tables are replaced to check the early migrations
+	for _, m := range migrations[:6] {
+		if err := applyMigration(db, m); err != nil {
+			t.Fatalf("applying migration %d: %v", m.Version, err)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	tests := []struct{ table, column string }{
+		{"clues", "text_html"},
+		{"clues", "correct_response_html"},
+		{"clues", "category_id"},
+		{"categories", "category_name_html"},
+		{"gamelist", "era"},
+		{"gamelist", "parser_version"},
+	}
+	for _, tt := range tests {
+		exists, err := columnExists(tx, tt.table, tt.column)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Errorf("%s.%s was not added", tt.table, tt.column)
+		}
+	}
+
+	// Rebuilding gamelist turns the empty tape date into NULL
+	var tapeDate sql.NullString
+	if err := tx.QueryRow(`SELECT tape_date FROM gamelist WHERE game_id = 9001;`).Scan(&tapeDate); err != nil {
+		t.Fatal(err)
+	}
+	if tapeDate.Valid {
+		t.Errorf("tape_date = %q, want NULL", tapeDate.String)
+	}
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	db := openTestDatabase(t)
+	if err := ensureSchemaVersionTable(db); err != nil {
+		t.Fatal(err)
+	}
+	failing := migration{
+		Version:     1000,
+		Description: "fails halfway",
+		Up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`CREATE TABLE halfway (id INTEGER);`); err != nil {
+				return err
+			}
+			return errors.New("broken")
+		},
+	}
+	if err := applyMigration(db, failing); err == nil {
+		t.Fatal("applyMigration succeeded, want an error")
+	}
+	if exists, err := tableExists(db, "halfway"); err != nil || exists {
+		t.Errorf("table created by the failed migration exists: %v, %v", exists, err)
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := applied[failing.Version]; ok {
+		t.Error("failed migration was recorded as applied")
+	}
+}
+
+// TestGamesShareShowNumber checks that migration 18 drops the unique show
+// number without losing the rows that reference the games
+func TestGamesShareShowNumber(t *testing.T) {
+	db := openTestDatabase(t)
+	if err := ensureSchemaVersionTable(db); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range migrations[:17] {
+		if err := applyMigration(db, m); err != nil {
+			t.Fatalf("applying migration %d: %v", m.Version, err)
+		}
+	}
+	game := func(id, showNum int) GameData {
+		return GameData{ID: id, ShowNum: showNum, AirDate: "1984-09-10", Rounds: []Round{{
+			Name:       roundJeopardy,
+			Categories: []Category{{Name: "CATEGORY"}},
+			Clues:      []Clue{{Position: "J_1_1", Value: "$100", Text: "A clue", CorrectResponse: "an answer"}},
+		}}}
+	}
+	if err := writeSeason(db, SeasonData{ID: "1", Games: []GameData{game(1, 1)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateDatabase(db); err != nil {
+		t.Fatalf("migrateDatabase: %v", err)
+	}
+	var foreignKeys int
+	if err := db.QueryRow(`PRAGMA foreign_keys;`).Scan(&foreignKeys); err != nil || foreignKeys != 1 {
+		t.Errorf("foreign keys = %d after migrating, want 1: %v", foreignKeys, err)
+	}
+	// A pilot listed under the same show number, and a game with none
+	if err := writeSeason(db, SeasonData{ID: "1", Games: []GameData{game(2, 1), game(3, 0), game(4, 0)}}); err != nil {
+		t.Fatalf("storing games sharing a show number: %v", err)
+	}
+	for table, want := range map[string]int{"games": 4, "rounds": 4, "categories": 4, "clues": 4} {
+		var got int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table + `;`).Scan(&got); err != nil {
+			t.Fatalf("counting %s: %v", table, err)
+		}
+		if got != want {
+			t.Errorf("%s has %d rows, want %d", table, got, want)
+		}
+	}
+}
+
+// TestMigrateCopiesFinalJeopardy checks that the Final Jeopardy clue the flat
+// schema stored without a position survives the upgrade, and that a clue
+// with no position to copy it under keeps the legacy tables
+func TestMigrateCopiesFinalJeopardy(t *testing.T) {
+	finalJeopardy := []string{
+		`INSERT INTO categories VALUES ('fJ9kL2mN', '35', 9001, 'Final Jeopardy', 'FINAL CAT');`,
+		`INSERT INTO clues (season_id, game_id, round_name, category, position, value, order_number, text, correct_response)
+			VALUES ('35', 9001, 'Final Jeopardy', 'FINAL CAT', NULL, NULL, NULL, 'A final clue', 'a final answer');`,
+	}
+	tests := []struct {
+		name       string
+		setup      []string
+		clues      []string
+		keepLegacy bool
+	}{
+		{"final jeopardy", finalJeopardy, []string{"9001-FJ", "9001-J_1_1"}, false},
+		{"unpositioned board clue", append(finalJeopardy,
+			`INSERT INTO clues (season_id, game_id, round_name, category, position, text)
+				VALUES ('35', 9001, 'Jeopardy! Round', 'CAT 1', NULL, 'A lost clue');`,
+		), []string{"9001-FJ", "9001-J_1_1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDatabase(t)
+			for _, statement := range append(append([]string{}, preMigrationSchema...), tt.setup...) {
+				if _, err := db.Exec(statement); err != nil {
+					t.Fatalf("setting up: %v", err)
+				}
+			}
+			if err := migrateDatabase(db); err != nil {
+				t.Fatalf("migrateDatabase: %v", err)
+			}
+
+			rows, err := db.Query(`SELECT clue_id FROM clues ORDER BY clue_id;`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			var clues []string
+			for rows.Next() {
+				var id string
+				if err := rows.Scan(&id); err != nil {
+					t.Fatal(err)
+				}
+				clues = append(clues, id)
+			}
+			if !reflect.DeepEqual(clues, tt.clues) {
+				t.Errorf("clues = %q, want %q", clues, tt.clues)
+			}
+
+			var text, response, category string
+			if err := db.QueryRow(`
+				SELECT c.text, c.correct_response, cat.name FROM clues c
+				JOIN categories cat ON cat.category_id = c.category_id
+				WHERE c.clue_id = '9001-FJ';`).Scan(&text, &response, &category); err != nil {
+				t.Fatalf("reading the Final Jeopardy clue: %v", err)
+			}
+			if text != "A final clue" || response != "a final answer" || category != "FINAL CAT" {
+				t.Errorf("Final Jeopardy clue = %q, %q in %q", text, response, category)
+			}
+
+			for _, table := range legacyTables {
+				if exists, err := tableExists(db, table); err != nil || exists != tt.keepLegacy {
+					t.Errorf("%s exists = %v after migrating, want %v: %v", table, exists, tt.keepLegacy, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tlegnard/answer-there/jarchive"
+)
+
+// openReadAPI stores games with the scraper's write path and opens the
+// database read-only through the jarchive package
+func openReadAPI(t *testing.T, games ...GameData) *jarchive.DB {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jeopardy.db")
+	store, err := newSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, game := range games {
+		if err := store.SaveGame("35", game); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := jarchive.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { reader.Close() })
+	return reader
+}
+
+var readAPIGames = []GameData{
+	{
+		ID: 1, ShowNum: 8001, AirDate: "2019-05-01", ParserVersion: parserVersion,
+		Contestants: []Contestant{
+			{PlayerID: "10", Name: "Alice Smith", Nickname: "Alice", Bio: "a teacher"},
+			{PlayerID: "20", Name: "Bob Jones", Nickname: "Bob", Bio: "a lawyer"},
+		},
+		Rounds: []Round{
+			{
+				Name:       roundJeopardy,
+				Categories: []Category{{Name: "HISTORY"}, {Name: "SCIENCE", NameHTML: "<i>SCIENCE</i>"}},
+				Clues: []Clue{
+					{Position: "J_1_1", Value: "$200", OrderNumber: 1, Text: "First", CorrectResponse: "one", CorrectContestant: "Bob",
+						Responses: []Response{{Contestant: "Alice"}, {Contestant: "Bob", Correct: true}}},
+					{Position: "J_2_1", Value: "$1,000", OrderNumber: 2, Text: "Second", CorrectResponse: "two", DailyDouble: true, Wager: 1000,
+						TripleStumper: true, Responses: []Response{{Contestant: "Alice"}}},
+				},
+			},
+			{
+				Name:       roundFinalJeopardy,
+				Categories: []Category{{Name: "FINAL"}},
+				Clues: []Clue{
+					{Position: "FJ", Text: "Last", CorrectResponse: "three", CorrectContestant: "Alice",
+						Responses: []Response{
+							{Contestant: "Alice", Correct: true, Text: "three", Wager: 500},
+							{Contestant: "Bob", Text: "four", Wager: 0},
+						}},
+				},
+			},
+		},
+	},
+	{
+		ID: 2, ShowNum: 8002, AirDate: "2019-05-02", ParserVersion: parserVersion,
+		Contestants: []Contestant{{PlayerID: "20", Name: "Bob Jones", Nickname: "Bobby", Bio: "a returning champion"}},
+	},
+}
+
+func TestReadAPIGetGame(t *testing.T) {
+	reader := openReadAPI(t, readAPIGames...)
+	want := readAPIGames[0]
+
+	for name, get := range map[string]func() (GameData, error){
+		"game id":     func() (GameData, error) { return reader.GetGame(1) },
+		"show number": func() (GameData, error) { return reader.GetGameByShowNumber(8001) },
+		"air date":    func() (GameData, error) { return reader.GetGameByAirDate("2019-05-01") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			game, err := get()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if game.ID != want.ID || game.ShowNum != want.ShowNum || game.AirDate != want.AirDate || game.ParserVersion != parserVersion {
+				t.Errorf("game = %d #%d %s v%d", game.ID, game.ShowNum, game.AirDate, game.ParserVersion)
+			}
+			if !reflect.DeepEqual(game.Contestants, want.Contestants) {
+				t.Errorf("contestants = %+v, want %+v", game.Contestants, want.Contestants)
+			}
+			if !reflect.DeepEqual(game.Rounds, want.Rounds) {
+				t.Errorf("rounds = %+v\nwant %+v", game.Rounds, want.Rounds)
+			}
+		})
+	}
+
+	if _, err := reader.GetGame(99); !errors.Is(err, jarchive.ErrNotFound) {
+		t.Errorf("GetGame(99) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestReadAPIListClues(t *testing.T) {
+	reader := openReadAPI(t, readAPIGames...)
+	tests := []struct {
+		name   string
+		filter jarchive.ClueFilter
+		want   []string
+	}{
+		{"all", jarchive.ClueFilter{}, []string{"J_1_1", "J_2_1", "FJ"}},
+		{"round", jarchive.ClueFilter{Round: "fj"}, []string{"FJ"}},
+		{"category", jarchive.ClueFilter{Category: "science"}, []string{"J_2_1"}},
+		{"value", jarchive.ClueFilter{MinValue: 500}, []string{"J_2_1"}},
+		{"daily double", jarchive.ClueFilter{DailyDouble: true}, []string{"J_2_1"}},
+		{"limit", jarchive.ClueFilter{Limit: 1}, []string{"J_1_1"}},
+		{"other season", jarchive.ClueFilter{SeasonID: "36"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := reader.ListClues(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, record := range records {
+				got = append(got, record.Clue.Position)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListClues(%+v) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadAPIListCluesReadsResponsesInBatches(t *testing.T) {
+	// More clues than are read in one batch, each with a response naming it
+	game := GameData{ID: 3, ShowNum: 8003, AirDate: "2019-05-03"}
+	round := Round{Name: roundJeopardy}
+	for column := 1; column <= 6; column++ {
+		round.Categories = append(round.Categories, Category{Name: fmt.Sprintf("CATEGORY %d", column)})
+		for row := 1; row <= 120; row++ {
+			position := fmt.Sprintf("J_%d_%d", column, row)
+			round.Clues = append(round.Clues, Clue{Position: position, Value: "$200", Text: position,
+				Responses: []Response{{Contestant: position, Correct: true}}})
+		}
+	}
+	game.Rounds = []Round{round}
+	reader := openReadAPI(t, game)
+
+	records, err := reader.ListClues(jarchive.ClueFilter{GameID: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 720 {
+		t.Fatalf("ListClues returned %d clues, want 720", len(records))
+	}
+	for _, record := range records {
+		clue := record.Clue
+		if len(clue.Responses) != 1 || clue.Responses[0].Contestant != clue.Position || clue.CorrectContestant != clue.Position {
+			t.Fatalf("clue %s has responses %+v", clue.Position, clue.Responses)
+		}
+	}
+}
+
+func TestReadAPIGetPlayer(t *testing.T) {
+	reader := openReadAPI(t, readAPIGames...)
+
+	player, err := reader.GetPlayer("20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if player.Name != "Bob Jones" || player.Nickname != "Bobby" || player.Bio != "a returning champion" {
+		t.Errorf("player = %+v, want the latest nickname and bio", player.Contestant)
+	}
+	var games []int
+	for _, appearance := range player.Appearances {
+		games = append(games, appearance.GameID)
+	}
+	if !reflect.DeepEqual(games, []int{1, 2}) || player.Appearances[0].Seat != 2 {
+		t.Errorf("appearances = %+v", player.Appearances)
+	}
+
+	if _, err := reader.GetPlayer("99"); !errors.Is(err, jarchive.ErrNotFound) {
+		t.Errorf("GetPlayer(99) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestOpenMissingDatabase(t *testing.T) {
+	if reader, err := jarchive.Open(filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		reader.Close()
+		t.Error("Open created a database that did not exist")
+	}
+}
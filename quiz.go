@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/tlegnard/answer-there/jarchive"
+)
+
+// quizCategory is a category picked for the quiz
+type quizCategory struct {
+	GameID    int
+	AirDate   string
+	RoundCode string
+	Name      string
+}
+
+// pickQuizCategories chooses random categories that have at least one
+// revealed clue, optionally from one season and round
+func pickQuizCategories(store *sqliteStore, seasonID, roundCode string, count int) ([]quizCategory, error) {
+	query := `
+		SELECT k.game_id, COALESCE(g.air_date, ''), r.round_code, k.name
+		FROM categories k
+		JOIN games g ON g.game_id = k.game_id
+		JOIN rounds r ON r.round_id = k.round_id
+		WHERE EXISTS (SELECT 1 FROM clues c WHERE c.category_id = k.category_id AND COALESCE(c.text, '') != '')`
+	var args []interface{}
+	if seasonID != "" {
+		query += ` AND g.season_id = ?`
+		args = append(args, seasonID)
+	}
+	if roundCode != "" {
+		query += ` AND r.round_code = ?`
+		args = append(args, strings.ToUpper(roundCode))
+	}
+	query += ` ORDER BY RANDOM() LIMIT ?;`
+	args = append(args, count)
+
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []quizCategory
+	for rows.Next() {
+		var category quizCategory
+		if err := rows.Scan(&category.GameID, &category.AirDate, &category.RoundCode, &category.Name); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+var (
+	// questionPrefix matches the "What is" a response is phrased with
+	questionPrefix = regexp.MustCompile(`^(who|what|where|when) (is|are|was|were) `)
+	// articlePrefix matches a leading article, which responses may omit
+	articlePrefix = regexp.MustCompile(`^(a|an|the) `)
+	// optionalPart matches the parenthesized part of a correct response,
+	// such as the first name in "(Edgar Allan) Poe"
+	optionalPart = regexp.MustCompile(`\([^)]*\)`)
+)
+
+// quizText reduces a response to the words that have to match
+func quizText(text string) string {
+	text = comparableText(text)
+	text = questionPrefix.ReplaceAllString(text, "")
+	return articlePrefix.ReplaceAllString(text, "")
+}
+
+// acceptableResponse reports whether a typed response matches the correct
+// response, ignoring case, punctuation, question phrasing, a leading
+// article and any parenthesized part of the correct response
+func acceptableResponse(given, correct string) bool {
+	given = quizText(given)
+	if given == "" {
+		return false
+	}
+	return given == quizText(correct) ||
+		given == quizText(optionalPart.ReplaceAllString(correct, " ")) ||
+		given == quizText(optionalPart.ReplaceAllStringFunc(correct, func(part string) string {
+			return strings.Trim(part, "()")
+		}))
+}
+
+// runQuiz implements the quiz command, which reads clues from random
+// categories and scores typed responses like the show: the clue's value is
+// added for a right response and taken away for a wrong one. An empty
+// response passes.
+//
+//	quiz [-season S] [-round J|DJ|FJ] [-categories N]
+func runQuiz(store *sqliteStore, args []string) {
+	flags := flag.NewFlagSet("quiz", flag.ExitOnError)
+	seasonID := flags.String("season", "", "only pick categories from this season")
+	roundCode := flags.String("round", "", "only pick categories from this round (J, DJ, FJ or TB)")
+	count := flags.Int("categories", 1, "number of categories to play")
+	flags.Parse(args)
+
+	categories, err := pickQuizCategories(store, *seasonID, *roundCode, *count)
+	if err != nil {
+		log.Fatalf("Failed to pick categories: %v", err)
+	}
+	if len(categories) == 0 {
+		log.Fatal("No categories with revealed clues match")
+	}
+
+	input := bufio.NewReader(os.Stdin)
+	score, right, asked := 0, 0, 0
+play:
+	for _, category := range categories {
+		records, err := store.ListClues(jarchive.ClueFilter{GameID: category.GameID, Round: category.RoundCode, Category: category.Name})
+		if err != nil {
+			log.Fatalf("Failed to read clues: %v", err)
+		}
+		fmt.Printf("\n%s (%s, aired %s)\n", category.Name, category.RoundCode, category.AirDate)
+
+		for _, record := range records {
+			clue := record.Clue
+			if clue.Text == "" {
+				continue
+			}
+			value, _ := parseMoney(clue.Value)
+			if clue.DailyDouble {
+				fmt.Printf("\n%s  DAILY DOUBLE\n  %s\n> ", clue.Value, clue.Text)
+			} else {
+				fmt.Printf("\n%s\n  %s\n> ", clue.Value, clue.Text)
+			}
+
+			line, err := input.ReadString('\n')
+			if err != nil && err != io.EOF {
+				log.Fatalf("Failed to read response: %v", err)
+			}
+			given := strings.TrimSpace(line)
+			if err == io.EOF && given == "" {
+				fmt.Println()
+				break play
+			}
+			asked++
+			switch {
+			case given == "":
+				fmt.Printf("  Passed. The correct response: %s\n", clue.CorrectResponse)
+			case acceptableResponse(given, clue.CorrectResponse):
+				right++
+				score += value
+				fmt.Printf("  Correct! (%s)\n", clue.CorrectResponse)
+			default:
+				score -= value
+				fmt.Printf("  No. The correct response: %s\n", clue.CorrectResponse)
+			}
+			if err == io.EOF {
+				break play
+			}
+		}
+	}
+	fmt.Printf("\n%d of %d right, final score %s\n", right, asked, jarchive.FormatMoney(score))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestAcceptableResponse(t *testing.T) {
+	tests := []struct {
+		given   string
+		correct string
+		want    bool
+	}{
+		{"Poe", "(Edgar Allan) Poe", true},
+		{"Edgar Allan Poe", "(Edgar Allan) Poe", true},
+		{"Allan Poe", "(Edgar Allan) Poe", false},
+		{"What is the Nile?", "the Nile", true},
+		{"Who is Poe", "(Edgar Allan) Poe", true},
+		{"What are the Beatles", "The Beatles", true},
+		{"where was Paris", "Paris", true},
+		{"nile", "the Nile", true},
+		{"a Nile", "the Nile", true},
+		{"Nile River", "the Nile", false},
+		{"  THE NILE!  ", "the Nile", true},
+		{"What is", "Paris", false},
+		{"", "Paris", false},
+		{"What is Paris?", "", false},
+		{"Rome", "Paris", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.given+"/"+tt.correct, func(t *testing.T) {
+			if got := acceptableResponse(tt.given, tt.correct); got != tt.want {
+				t.Errorf("acceptableResponse(%q, %q) = %v, want %v", tt.given, tt.correct, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickQuizCategories(t *testing.T) {
+	unrevealed := GameData{ID: 3, ShowNum: 8003, AirDate: "2020-05-01", Rounds: []Round{{
+		Name:       roundJeopardy,
+		Categories: []Category{{Name: "HIDDEN"}},
+		Clues:      []Clue{{Position: "J_1_1", Value: "$200"}},
+	}}}
+	store := openTestStore(t, map[string][]GameData{"35": readAPIGames, "36": {unrevealed}})
+
+	tests := []struct {
+		name      string
+		seasonID  string
+		roundCode string
+		want      map[string]bool
+	}{
+		{"every category with a revealed clue", "", "", map[string]bool{"HISTORY": true, "SCIENCE": true, "FINAL": true}},
+		{"round", "", "fj", map[string]bool{"FINAL": true}},
+		{"season without revealed clues", "36", "", map[string]bool{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			categories, err := pickQuizCategories(store, tt.seasonID, tt.roundCode, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]bool)
+			for _, category := range categories {
+				got[category.Name] = true
+			}
+			if len(got) != len(tt.want) || len(categories) != len(tt.want) {
+				t.Fatalf("pickQuizCategories = %+v, want %v", categories, tt.want)
+			}
+			for name := range tt.want {
+				if !got[name] {
+					t.Errorf("pickQuizCategories = %+v, missing %s", categories, name)
+				}
+			}
+		})
+	}
+
+	categories, err := pickQuizCategories(store, "", "", 1)
+	if err != nil || len(categories) != 1 {
+		t.Errorf("pickQuizCategories with count 1 = %+v, %v", categories, err)
+	}
+}
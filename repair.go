@@ -0,0 +1,456 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Databases written before the normalized schema keep their rows in the
+// legacy_* tables, where re-runs appended duplicate clues and categories with
+// random IDs. repair rebuilds those games from the legacy rows, keeping one
+// clue per (game_id, position) and one category per game, round and column,
+// and writes them through the normal write path.
+
+// legacyTables are dropped once every legacy game has been repaired
+var legacyTables = []string{"legacy_clues", "legacy_categories", "legacy_game_roster", "legacy_gamelist"}
+
+// repairReport describes what repair changed, or would change in a dry run
+type repairReport struct {
+	LegacyGames        int
+	GamesAlreadyStored int
+	ClueRows           int
+	DuplicateClues     int
+	UnpositionedClues  int
+	CategoryRows       int
+	Categories         int
+	GamesRepaired      int
+	GameErrors         []error
+	DroppedLegacy      bool
+}
+
+// legacyClue is one row of legacy_clues
+type legacyClue struct {
+	ID        int
+	GameID    int
+	RoundName string
+	Category  string
+	Clue      Clue
+}
+
+// legacyCategory is one row of legacy_categories
+type legacyCategory struct {
+	GameID    int
+	RoundName string
+	Category  Category
+}
+
+// readLegacyGames reads the games in the legacy tables that are not already
+// in the normalized tables, keyed by game ID, with the season of each
+func readLegacyGames(db *sql.DB, report *repairReport) (map[int]*GameData, map[int]string, error) {
+	games := make(map[int]*GameData)
+	seasons := make(map[int]string)
+
+	rows, err := db.Query(`
+		SELECT season_id, game_id, COALESCE(show_num, 0), COALESCE(air_date, ''), COALESCE(tape_date, ''),
+			COALESCE(era, ''), COALESCE(format, ''), COALESCE(unavailable_fields, '')
+		FROM legacy_gamelist
+		UNION
+		-- clues whose game row was dropped by INSERT OR IGNORE
+		SELECT DISTINCT season_id, game_id, 0, '', '', '', '', ''
+		FROM legacy_clues WHERE game_id NOT IN (SELECT game_id FROM legacy_gamelist)
+		ORDER BY game_id;`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var seasonID, unavailable string
+		var game GameData
+		if err := rows.Scan(&seasonID, &game.ID, &game.ShowNum, &game.AirDate, &game.TapeDate,
+			&game.Era, &game.Format, &unavailable); err != nil {
+			return nil, nil, err
+		}
+		if unavailable != "" {
+			game.Unavailable = strings.Split(unavailable, ",")
+		}
+		report.LegacyGames++
+		games[game.ID] = &game
+		seasons[game.ID] = seasonID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	stored, err := readParserVersions(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	for gameID := range games {
+		// A game already in the normalized tables came from a newer parse
+		if _, ok := stored[gameID]; ok {
+			report.GamesAlreadyStored++
+			delete(games, gameID)
+			delete(seasons, gameID)
+		}
+	}
+	return games, seasons, nil
+}
+
+// readLegacyClues reads legacy clues keeping only the latest row for each
+// (game_id, position)
+func readLegacyClues(db *sql.DB, report *repairReport) ([]legacyClue, error) {
+	rows, err := db.Query(`
+		SELECT id, game_id, round_name, category, COALESCE(position, ''), COALESCE(value, ''),
+			COALESCE(order_number, 0), text, COALESCE(text_html, ''), COALESCE(correct_response, ''),
+			COALESCE(correct_response_html, ''), COALESCE(correct_contestant, '')
+		FROM legacy_clues ORDER BY game_id, id;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	latest := make(map[string]legacyClue)
+	for rows.Next() {
+		var row legacyClue
+		clue := &row.Clue
+		if err := rows.Scan(&row.ID, &row.GameID, &row.RoundName, &row.Category, &clue.Position, &clue.Value,
+			&clue.OrderNumber, &clue.Text, &clue.TextHTML, &clue.CorrectResponse,
+			&clue.CorrectResponseHTML, &clue.CorrectContestant); err != nil {
+			return nil, err
+		}
+		report.ClueRows++
+		if clue.Position == "" {
+			// The flat schema only stored positions on the J and DJ boards,
+			// and each final round holds a single clue
+			clue.Position = boardPosition(row.RoundName, 0, 0)
+		}
+		if clue.Position == "" {
+			report.UnpositionedClues++
+			continue
+		}
+		key := clueID(row.GameID, clue.Position)
+		if _, ok := latest[key]; ok {
+			report.DuplicateClues++
+		}
+		latest[key] = row
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	clues := make([]legacyClue, 0, len(latest))
+	for _, row := range latest {
+		clues = append(clues, row)
+	}
+	sort.Slice(clues, func(i, j int) bool {
+		return clues[i].ID < clues[j].ID
+	})
+	return clues, nil
+}
+
+// readLegacyCategories reads legacy categories in insertion order
+func readLegacyCategories(db *sql.DB, report *repairReport) ([]legacyCategory, error) {
+	rows, err := db.Query(`
+		SELECT game_id, round_name, category_name, COALESCE(category_name_html, '')
+		FROM legacy_categories ORDER BY game_id, rowid;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []legacyCategory
+	for rows.Next() {
+		var row legacyCategory
+		if err := rows.Scan(&row.GameID, &row.RoundName, &row.Category.Name, &row.Category.NameHTML); err != nil {
+			return nil, err
+		}
+		report.CategoryRows++
+		categories = append(categories, row)
+	}
+	return categories, rows.Err()
+}
+
+// readLegacyRosters reads each game's contestants in seat order, once per player
+func readLegacyRosters(db *sql.DB) (map[int][]Contestant, error) {
+	rows, err := db.Query(`
+		SELECT game_id, player_id, name, COALESCE(nickname, ''), COALESCE(bio, '')
+		FROM legacy_game_roster ORDER BY game_id, rowid;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rosters := make(map[int][]Contestant)
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var gameID int
+		var contestant Contestant
+		if err := rows.Scan(&gameID, &contestant.PlayerID, &contestant.Name, &contestant.Nickname, &contestant.Bio); err != nil {
+			return nil, err
+		}
+		key := fmt.Sprintf("%d-%s", gameID, contestant.PlayerID)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		rosters[gameID] = append(rosters[gameID], contestant)
+	}
+	return rosters, rows.Err()
+}
+
+// collapseCategories returns one category per column of a round. A column
+// takes its name from the clues placed in it, falling back to the order the
+// distinct category names were first written in.
+func collapseCategories(rows []legacyCategory, clues []legacyClue) []Category {
+	var categories []Category
+	html := make(map[string]string)
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		if html[row.Category.Name] == "" {
+			html[row.Category.Name] = row.Category.NameHTML
+		}
+		if !seen[row.Category.Name] {
+			seen[row.Category.Name] = true
+			categories = append(categories, row.Category)
+		}
+	}
+
+	for _, row := range clues {
+		column := clueColumn(row.Clue.Position)
+		if column < 1 || row.Category == "" {
+			continue
+		}
+		for len(categories) < column {
+			categories = append(categories, Category{})
+		}
+		categories[column-1] = Category{Name: row.Category, NameHTML: html[row.Category]}
+	}
+	return categories
+}
+
+// buildLegacyGames assembles rounds, categories and contestants for the games
+// being repaired
+func buildLegacyGames(games map[int]*GameData, clues []legacyClue, categories []legacyCategory,
+	rosters map[int][]Contestant, report *repairReport) {
+	type roundKey struct {
+		gameID int
+		name   string
+	}
+	cluesByRound := make(map[roundKey][]legacyClue)
+	for _, row := range clues {
+		cluesByRound[roundKey{row.GameID, row.RoundName}] = append(cluesByRound[roundKey{row.GameID, row.RoundName}], row)
+	}
+	categoriesByRound := make(map[roundKey][]legacyCategory)
+	for _, row := range categories {
+		categoriesByRound[roundKey{row.GameID, row.RoundName}] = append(categoriesByRound[roundKey{row.GameID, row.RoundName}], row)
+	}
+
+	for gameID, game := range games {
+		game.Contestants = rosters[gameID]
+		for _, name := range []string{roundJeopardy, roundDoubleJeopardy, roundFinalJeopardy, roundTiebreaker} {
+			key := roundKey{gameID, name}
+			if len(cluesByRound[key]) == 0 && len(categoriesByRound[key]) == 0 {
+				continue
+			}
+
+			round := Round{Name: name, Categories: collapseCategories(categoriesByRound[key], cluesByRound[key])}
+			report.Categories += len(round.Categories)
+
+			final := name == roundFinalJeopardy || name == roundTiebreaker
+			for _, row := range cluesByRound[key] {
+				clue := row.Clue
+				if clue.CorrectContestant != "" && !final {
+					clue.Responses = []Response{{Contestant: clue.CorrectContestant, Correct: true}}
+				}
+				round.Clues = append(round.Clues, clue)
+			}
+			sort.SliceStable(round.Clues, func(i, j int) bool {
+				a, b := round.Clues[i].Position, round.Clues[j].Position
+				if clueRowNumber(a) != clueRowNumber(b) {
+					return clueRowNumber(a) < clueRowNumber(b)
+				}
+				return clueColumn(a) < clueColumn(b)
+			})
+			game.Rounds = append(game.Rounds, round)
+		}
+	}
+}
+
+// repairDatabase moves deduplicated legacy games into the normalized tables.
+// Legacy tables are dropped only if every game was written and every clue
+// had a position.
+func repairDatabase(db *sql.DB, dryRun, keepLegacy bool) (repairReport, error) {
+	var report repairReport
+
+	exists, err := tableExists(db, "legacy_clues")
+	if err != nil || !exists {
+		return report, err
+	}
+
+	games, seasons, err := readLegacyGames(db, &report)
+	if err != nil {
+		return report, fmt.Errorf("failed to read legacy games: %v", err)
+	}
+	clues, err := readLegacyClues(db, &report)
+	if err != nil {
+		return report, fmt.Errorf("failed to read legacy clues: %v", err)
+	}
+	categories, err := readLegacyCategories(db, &report)
+	if err != nil {
+		return report, fmt.Errorf("failed to read legacy categories: %v", err)
+	}
+	rosters, err := readLegacyRosters(db)
+	if err != nil {
+		return report, fmt.Errorf("failed to read legacy rosters: %v", err)
+	}
+
+	var repairClues []legacyClue
+	for _, row := range clues {
+		if games[row.GameID] != nil {
+			repairClues = append(repairClues, row)
+		}
+	}
+	buildLegacyGames(games, repairClues, categories, rosters, &report)
+
+	if dryRun {
+		return report, nil
+	}
+
+	bySeason := make(map[string][]GameData)
+	var seasonOrder []string
+	var gameIDs []int
+	for gameID := range games {
+		gameIDs = append(gameIDs, gameID)
+	}
+	sort.Ints(gameIDs)
+	for _, gameID := range gameIDs {
+		seasonID := seasons[gameID]
+		if _, ok := bySeason[seasonID]; !ok {
+			seasonOrder = append(seasonOrder, seasonID)
+		}
+		bySeason[seasonID] = append(bySeason[seasonID], *games[gameID])
+	}
+
+	for _, seasonID := range seasonOrder {
+		gameErrors, err := writeGameBatch(db, seasonID, bySeason[seasonID])
+		if err != nil {
+			return report, err
+		}
+		report.GamesRepaired += len(bySeason[seasonID]) - len(gameErrors)
+		report.GameErrors = append(report.GameErrors, gameErrors...)
+	}
+
+	if len(report.GameErrors) > 0 || report.UnpositionedClues > 0 || keepLegacy {
+		return report, nil
+	}
+	if err := dropLegacyTables(db); err != nil {
+		return report, fmt.Errorf("failed to drop legacy tables: %v", err)
+	}
+	report.DroppedLegacy = true
+	return report, nil
+}
+
+// copyLegacyData repairs the legacy tables left by migration 7 so an upgraded
+// database keeps its games. Failures are logged rather than returned so the
+// database still opens and repair can be run by hand.
+func copyLegacyData(db *sql.DB) {
+	report, err := repairDatabase(db, false, false)
+	if err != nil {
+		log.Printf("Could not copy the legacy tables into the normalized schema, run repair: %v", err)
+		return
+	}
+	if report.LegacyGames == 0 && report.ClueRows == 0 {
+		return
+	}
+	log.Printf("Copied %d legacy games into the normalized schema", report.GamesRepaired)
+	for _, gameErr := range report.GameErrors {
+		log.Printf("Failed to copy %v", gameErr)
+	}
+	switch {
+	case len(report.GameErrors) > 0:
+		log.Println("Kept the legacy tables because some games failed, fix them and run repair")
+	case report.UnpositionedClues > 0:
+		log.Printf("Kept the legacy tables because %d clues have no position and were not copied", report.UnpositionedClues)
+	}
+}
+
+// dropLegacyTables removes the legacy tables and recreates the views derived
+// from the normalized tables
+func dropLegacyTables(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var statements []string
+	for _, table := range legacyTables {
+		statements = append(statements, `DROP TABLE IF EXISTS `+table+`;`)
+	}
+	statements = append(statements,
+		`DROP VIEW IF EXISTS contestants;`,
+		`CREATE VIEW contestants AS SELECT player_id, name FROM players;`,
+	)
+	if err := execStatements(statements...)(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// runRepair implements the repair command
+//
+//	repair [-dry-run] [-keep-legacy]
+func runRepair(ctx context.Context, store *sqliteStore, args []string) {
+	flags := flag.NewFlagSet("repair", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "report what would change without writing")
+	keepLegacy := flags.Bool("keep-legacy", false, "keep the legacy tables after repairing")
+	flags.Parse(args)
+
+	// A dry run changes nothing, so only real repairs are recorded
+	var run *runLedger
+	if !*dryRun {
+		run = startRunLedger(store, "repair", args)
+		defer run.finish(ctx)
+	}
+	report, err := repairDatabase(store.db, *dryRun, *keepLegacy)
+	if err != nil {
+		run.fatalf("Repair failed: %v", err)
+	}
+	run.gamesDone(report.GamesRepaired, 0, len(report.GameErrors))
+	if report.LegacyGames == 0 && report.ClueRows == 0 {
+		fmt.Println("No legacy data to repair")
+		return
+	}
+
+	verb := "Repaired"
+	if *dryRun {
+		verb = "Would repair"
+	}
+	fmt.Printf("Legacy games: %d (%d already in the normalized tables)\n", report.LegacyGames, report.GamesAlreadyStored)
+	fmt.Printf("Legacy clue rows: %d, %d duplicates removed, %d without a position skipped\n",
+		report.ClueRows, report.DuplicateClues, report.UnpositionedClues)
+	fmt.Printf("Legacy category rows: %d, collapsed to %d categories in the games to repair\n", report.CategoryRows, report.Categories)
+	if *dryRun {
+		fmt.Printf("%s %d games\n", verb, report.LegacyGames-report.GamesAlreadyStored)
+		return
+	}
+
+	fmt.Printf("%s %d games\n", verb, report.GamesRepaired)
+	for _, gameErr := range report.GameErrors {
+		fmt.Printf("  failed %v\n", gameErr)
+	}
+	switch {
+	case report.DroppedLegacy:
+		fmt.Println("Dropped legacy tables and rebuilt views")
+	case len(report.GameErrors) > 0:
+		fmt.Println("Kept legacy tables because some games failed, fix them and run repair again")
+	case report.UnpositionedClues > 0:
+		fmt.Println("Kept legacy tables because some clues have no position and were not copied")
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Cached game pages are saved as <game_id>_<season_id>_j-archive.html
+var cachedGameFileRegex = regexp.MustCompile(`^(\d+)_(.+)_j-archive\.html$`)
+
+// cachedGame is a game page found in the local HTML cache
+type cachedGame struct {
+	GameID   int
+	SeasonID string
+	Path     string
+}
+
+// findCachedGames lists every cached game page under dataDir, ordered by season and game
+func findCachedGames(dataDir string) ([]cachedGame, error) {
+	paths, err := filepath.Glob(filepath.Join(dataDir, "season_*", "*.html"))
+	if err != nil {
+		return nil, err
+	}
+
+	var games []cachedGame
+	for _, path := range paths {
+		matches := cachedGameFileRegex.FindStringSubmatch(filepath.Base(path))
+		if len(matches) == 0 {
+			continue
+		}
+		gameID, err := strconv.Atoi(matches[1])
+		if err != nil {
+			continue
+		}
+		games = append(games, cachedGame{GameID: gameID, SeasonID: matches[2], Path: path})
+	}
+
+	sort.Slice(games, func(i, j int) bool {
+		if games[i].SeasonID != games[j].SeasonID {
+			return games[i].SeasonID < games[j].SeasonID
+		}
+		return games[i].GameID < games[j].GameID
+	})
+	return games, nil
+}
+
+// needsReparse reports whether a stored game was written by an older parser
+// version. Games that are not in the database are left to scrape.
+func needsReparse(storedVersions map[int]int, gameID int) bool {
+	version, ok := storedVersions[gameID]
+	return ok && version < parserVersion
+}
+
+// unsavedGames counts the games a SaveSeason error reports as not written:
+// one per joined game error, or every game if the write failed as a whole
+func unsavedGames(err error, games int) int {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return len(joined.Unwrap())
+	}
+	return games
+}
+
+// runReparse re-parses cached game pages and replaces games in the database
+// that were written by an older parser version. Cached games that are not in
+// the database are reported, not added. It never touches the network. The
+// outcome is counted in the run ledger.
+func runReparse(ctx context.Context, store PipelineStore, dataDir string, run *runLedger) {
+	cached, err := findCachedGames(dataDir)
+	if err != nil {
+		run.fatalf("Failed to list cached games: %v", err)
+	}
+
+	storedVersions, err := store.ParserVersions()
+	if err != nil {
+		run.fatalf("Failed to read parser versions: %v", err)
+	}
+
+	fmt.Printf("Found %d cached games, current parser version is %d\n", len(cached), parserVersion)
+
+	seasons := make(map[string]*SeasonData)
+	var seasonOrder []string
+	var gameIDs []int
+	skipped, failed := 0, 0
+	var unstored intList
+	failedBySeason := make(map[string]int)
+
+	for _, entry := range cached {
+		if ctx.Err() != nil {
+			log.Printf("Interrupted, writing the %d games parsed so far", len(gameIDs))
+			break
+		}
+		if _, ok := storedVersions[entry.GameID]; !ok {
+			unstored = append(unstored, entry.GameID)
+			continue
+		}
+		if !needsReparse(storedVersions, entry.GameID) {
+			skipped++
+			continue
+		}
+
+		content, err := os.ReadFile(entry.Path)
+		if err != nil {
+			log.Printf("Failed to read %s: %v", entry.Path, err)
+			failed++
+			failedBySeason[entry.SeasonID]++
+			continue
+		}
+		run.gameLoaded(true)
+
+		game, warnings, err := parseGameTableData(string(content))
+		if err != nil {
+			log.Printf("Failed to parse game %d: %v", entry.GameID, err)
+			failed++
+			failedBySeason[entry.SeasonID]++
+			continue
+		}
+		game.ID = entry.GameID
+		game.Warnings = warnings
+
+		season, ok := seasons[entry.SeasonID]
+		if !ok {
+			season = &SeasonData{ID: entry.SeasonID}
+			seasons[entry.SeasonID] = season
+			seasonOrder = append(seasonOrder, entry.SeasonID)
+		}
+		season.Games = append(season.Games, game)
+		gameIDs = append(gameIDs, game.ID)
+	}
+
+	// Writing a game replaces all of its existing rows
+	for _, seasonID := range seasonOrder {
+		season := seasons[seasonID]
+		warnings := 0
+		for _, game := range season.Games {
+			warnings += len(game.Warnings)
+		}
+		unsaved := 0
+		if err := store.SaveSeason(*season); err != nil {
+			log.Printf("Error writing season %s: %v", seasonID, err)
+			unsaved = unsavedGames(err, len(season.Games))
+		}
+		run.seasonDone(seasonID, len(season.Games)-unsaved, warnings, failedBySeason[seasonID]+unsaved)
+		delete(failedBySeason, seasonID)
+	}
+	// Seasons where every game failed before it could be written
+	var failedSeasons []string
+	for seasonID := range failedBySeason {
+		failedSeasons = append(failedSeasons, seasonID)
+	}
+	sort.Strings(failedSeasons)
+	for _, seasonID := range failedSeasons {
+		run.seasonDone(seasonID, 0, 0, failedBySeason[seasonID])
+	}
+
+	fmt.Printf("Reparsed %d games, %d already current, %d failed\n", len(gameIDs), skipped, failed)
+	if len(unstored) > 0 {
+		fmt.Printf("Skipped %d cached games that are not in the database, scrape them to add them: %s\n", len(unstored), unstored.String())
+	}
+}
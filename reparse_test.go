@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNeedsReparse(t *testing.T) {
+	stored := map[int]int{
+		1: parserVersion - 1,
+		2: parserVersion,
+		3: parserVersion + 1,
+		4: 0,
+	}
+	tests := []struct {
+		name   string
+		gameID int
+		want   bool
+	}{
+		{"older parser", 1, true},
+		{"current parser", 2, false},
+		{"newer parser", 3, false},
+		{"unversioned", 4, true},
+		{"not stored", 5, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsReparse(stored, tt.gameID); got != tt.want {
+				t.Errorf("needsReparse(game %d) = %v, want %v", tt.gameID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindCachedGames(t *testing.T) {
+	dataDir := t.TempDir()
+	files := []string{
+		"season_35/9002_35_j-archive.html",
+		"season_35/9001_35_j-archive.html",
+		"season_34/8001_34_j-archive.html",
+		"season_superjeopardy/3000_superjeopardy_j-archive.html",
+		"season_35/notes.html",
+		"season_35/9003_35_j-archive.txt",
+		"elsewhere/7001_36_j-archive.html",
+	}
+	for _, name := range files {
+		path := filepath.Join(dataDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("<html></html>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	games, err := findCachedGames(dataDir)
+	if err != nil {
+		t.Fatalf("findCachedGames: %v", err)
+	}
+	var got []cachedGame
+	for _, game := range games {
+		game.Path, _ = filepath.Rel(dataDir, game.Path)
+		got = append(got, game)
+	}
+	want := []cachedGame{
+		{GameID: 8001, SeasonID: "34", Path: "season_34/8001_34_j-archive.html"},
+		{GameID: 9001, SeasonID: "35", Path: "season_35/9001_35_j-archive.html"},
+		{GameID: 9002, SeasonID: "35", Path: "season_35/9002_35_j-archive.html"},
+		{GameID: 3000, SeasonID: "superjeopardy", Path: "season_superjeopardy/3000_superjeopardy_j-archive.html"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findCachedGames =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestUnsavedGames(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"whole write failed", errors.New("database is locked"), 5},
+		{"one game", errors.Join(errors.New("game 1")), 1},
+		{"several games", errors.Join(errors.New("game 1"), errors.New("game 2")), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unsavedGames(tt.err, 5); got != tt.want {
+				t.Errorf("unsavedGames(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunReparse(t *testing.T) {
+	dataDir := t.TempDir()
+	for _, game := range []cachedGame{{GameID: 1, SeasonID: "35"}, {GameID: 2, SeasonID: "35"}, {GameID: 3, SeasonID: "35"}} {
+		dir := filepath.Join(dataDir, "season_"+game.SeasonID)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		page := testGamePage(testGame{ID: game.GameID, ShowNum: 8000 + game.GameID, AirDate: "2019-05-01"})
+		if err := os.WriteFile(filepath.Join(dir, cachedGameFilename(game.GameID, game.SeasonID)), []byte(page), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := newMemoryStore()
+	stored := SeasonData{ID: "35", Games: []GameData{
+		{ID: 1, ParserVersion: parserVersion - 1},
+		{ID: 2, ParserVersion: parserVersion},
+	}}
+	if err := store.SaveSeason(stored); err != nil {
+		t.Fatal(err)
+	}
+	run := startRunLedger(store, "reparse", nil)
+	runReparse(context.Background(), store, dataDir, run)
+	run.finish(context.Background())
+
+	versions, err := store.ParserVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]int{1: parserVersion, 2: parserVersion}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("parser versions = %v, want %v, with game 3 left unstored", versions, want)
+	}
+	if game := store.games[1]; game.ShowNum != 8001 {
+		t.Errorf("game 1 show number = %d, want 8001 from the cached page", game.ShowNum)
+	}
+	if got := store.Runs()[0]; got.GamesStored != 1 || got.GamesCached != 1 {
+		t.Errorf("run stored %d games from %d cached pages, want 1 and 1", got.GamesStored, got.GamesCached)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/tlegnard/answer-there/jarchive"
+)
+
+type ScoreEvent = jarchive.ScoreEvent
+
+// ScoreMismatch is a difference between a replayed score and the score the page reports
+type ScoreMismatch struct {
+	Round      string
+	Contestant string
+	Computed   int
+	Reported   int
+}
+
+// ScoreTimeline is the result of replaying a game clue by clue
+type ScoreTimeline struct {
+	Contestants []string
+	Events      []ScoreEvent
+	RoundTotals []RoundScore
+	Mismatches  []ScoreMismatch
+	// Unscored lists clues with responses whose value could not be read
+	Unscored []string
+	// Ordered is false when order numbers were unavailable and clues were
+	// replayed in board order, so only round totals are meaningful
+	Ordered bool
+}
+
+// replayScores computes running scores for every contestant after every clue
+// and reconciles the totals with the scores reported on the page
+func replayScores(game GameData) ScoreTimeline {
+	timeline := ScoreTimeline{Ordered: !game.IsUnavailable(fieldOrderNumbers)}
+	scores := make(map[string]int)
+
+	addContestant := func(nickname string) {
+		if _, ok := scores[nickname]; !ok {
+			scores[nickname] = 0
+			timeline.Contestants = append(timeline.Contestants, nickname)
+		}
+	}
+	if len(game.RoundScores) > 0 {
+		for _, score := range game.RoundScores[0].Scores {
+			addContestant(score.Nickname)
+		}
+	} else {
+		for _, contestant := range game.Contestants {
+			addContestant(contestant.Nickname)
+		}
+	}
+
+	record := func(round string, clue Clue, contestant string, delta int) {
+		addContestant(contestant)
+		scores[contestant] += delta
+		snapshot := make([]ContestantScore, 0, len(timeline.Contestants))
+		for _, nickname := range timeline.Contestants {
+			snapshot = append(snapshot, ContestantScore{Nickname: nickname, Score: scores[nickname]})
+		}
+		timeline.Events = append(timeline.Events, ScoreEvent{
+			Round:       round,
+			Position:    clue.Position,
+			OrderNumber: clue.OrderNumber,
+			Contestant:  contestant,
+			Delta:       delta,
+			Scores:      snapshot,
+		})
+	}
+
+	for _, round := range game.Rounds {
+		switch round.Name {
+		case roundJeopardy, roundDoubleJeopardy:
+			clues := make([]Clue, len(round.Clues))
+			copy(clues, round.Clues)
+			if timeline.Ordered {
+				sort.SliceStable(clues, func(i, j int) bool {
+					return clues[i].OrderNumber < clues[j].OrderNumber
+				})
+			}
+
+			for _, clue := range clues {
+				if len(clue.Responses) == 0 {
+					continue
+				}
+				value := clue.Wager
+				if !clue.DailyDouble {
+					var err error
+					if value, err = parseMoney(clue.Value); err != nil {
+						timeline.Unscored = append(timeline.Unscored, round.Name+" "+clue.Position)
+						continue
+					}
+				}
+				for _, response := range clue.Responses {
+					if response.Correct {
+						record(round.Name, clue, response.Contestant, value)
+					} else {
+						record(round.Name, clue, response.Contestant, -value)
+					}
+				}
+			}
+		case roundFinalJeopardy:
+			for _, clue := range round.Clues {
+				for _, response := range clue.Responses {
+					if response.Correct {
+						record(round.Name, clue, response.Contestant, response.Wager)
+					} else {
+						record(round.Name, clue, response.Contestant, -response.Wager)
+					}
+				}
+			}
+		default:
+			// Tiebreakers do not change scores
+			continue
+		}
+
+		totals := RoundScore{Round: round.Name}
+		for _, nickname := range timeline.Contestants {
+			totals.Scores = append(totals.Scores, ContestantScore{Nickname: nickname, Score: scores[nickname]})
+		}
+		timeline.RoundTotals = append(timeline.RoundTotals, totals)
+		timeline.Mismatches = append(timeline.Mismatches, reconcileRound(game, round.Name, scores)...)
+	}
+
+	return timeline
+}
+
+// reconcileRound compares replayed scores with the page's end-of-round scores
+func reconcileRound(game GameData, roundName string, scores map[string]int) []ScoreMismatch {
+	var mismatches []ScoreMismatch
+	for _, reported := range game.RoundScores {
+		if reported.Round != roundName {
+			continue
+		}
+		for _, score := range reported.Scores {
+			if computed := scores[score.Nickname]; computed != score.Score {
+				mismatches = append(mismatches, ScoreMismatch{
+					Round:      roundName,
+					Contestant: score.Nickname,
+					Computed:   computed,
+					Reported:   score.Score,
+				})
+			}
+		}
+	}
+	return mismatches
+}
+
+// Warnings reports mismatches and unscored clues as parse warnings
+func (timeline ScoreTimeline) Warnings() []ParseWarning {
+	var warnings []ParseWarning
+	for _, mismatch := range timeline.Mismatches {
+		warnings = append(warnings, newParseWarning(warnScoreMismatch, "%s: %s replayed to %d but page reports %d",
+			mismatch.Round, mismatch.Contestant, mismatch.Computed, mismatch.Reported))
+	}
+	for _, position := range timeline.Unscored {
+		warnings = append(warnings, newParseWarning(warnScore, "%s: no clue value to score responses", position))
+	}
+	return warnings
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func right(nickname string) Response { return Response{Contestant: nickname, Correct: true} }
+func wrong(nickname string) Response { return Response{Contestant: nickname} }
+
+// played is a revealed regular clue with the given responses
+func played(position, value string, order int, responses ...Response) Clue {
+	return Clue{Position: position, Value: value, OrderNumber: order, Responses: responses}
+}
+
+// dailyDouble is a Daily Double worth value on the board, played for wager
+func dailyDouble(position, value string, wager, order int, response Response) Clue {
+	return Clue{Position: position, Value: value, OrderNumber: order, DailyDouble: true, Wager: wager, Responses: []Response{response}}
+}
+
+// finalWager is a Final Jeopardy response
+func finalWager(nickname string, correct bool, wager int) Response {
+	return Response{Contestant: nickname, Correct: correct, Wager: wager}
+}
+
+// scores lists contestant scores in the order Alice, Bob, Carol
+func scores(alice, bob, carol int) []ContestantScore {
+	return []ContestantScore{{Nickname: "Alice", Score: alice}, {Nickname: "Bob", Score: bob}, {Nickname: "Carol", Score: carol}}
+}
+
+func TestReplayScores(t *testing.T) {
+	contestants := []Contestant{{Nickname: "Alice"}, {Nickname: "Bob"}, {Nickname: "Carol"}}
+	tests := []struct {
+		name         string
+		rounds       []Round
+		reported     []RoundScore
+		unavailable  []string
+		wantDeltas   []int
+		wantTotals   []ContestantScore
+		wantWarnings []string
+	}{
+		{
+			name: "right and wrong responses",
+			rounds: []Round{{Name: roundJeopardy, Clues: []Clue{
+				played("J_1_1", "$200", 1, wrong("Bob"), right("Alice")),
+				played("J_1_2", "$400", 2, right("Carol")),
+			}}},
+			wantDeltas: []int{-200, 200, 400},
+			wantTotals: scores(200, -200, 400),
+		},
+		{
+			name: "replayed in order picked",
+			rounds: []Round{{Name: roundJeopardy, Clues: []Clue{
+				played("J_1_1", "$200", 2, right("Alice")),
+				played("J_2_1", "$200", 3, right("Alice")),
+				played("J_1_2", "$400", 1, wrong("Alice")),
+			}}},
+			wantDeltas: []int{-400, 200, 200},
+			wantTotals: scores(0, 0, 0),
+		},
+		{
+			name: "board order without order numbers",
+			rounds: []Round{{Name: roundJeopardy, Clues: []Clue{
+				played("J_1_1", "$200", 0, right("Alice")),
+				played("J_1_2", "$400", 0, wrong("Bob")),
+			}}},
+			unavailable: []string{fieldOrderNumbers},
+			wantDeltas:  []int{200, -400},
+			wantTotals:  scores(200, -400, 0),
+		},
+		{
+			name: "daily double scores the wager",
+			rounds: []Round{{Name: roundJeopardy, Clues: []Clue{
+				played("J_1_1", "$200", 1, right("Alice")),
+				dailyDouble("J_1_3", "$600", 1000, 2, right("Alice")),
+				dailyDouble("J_2_3", "$600", 500, 3, wrong("Bob")),
+			}}},
+			wantDeltas: []int{200, 1000, -500},
+			wantTotals: scores(1200, -500, 0),
+		},
+		{
+			name: "true daily double",
+			rounds: []Round{{Name: roundDoubleJeopardy, Clues: []Clue{
+				played("DJ_1_4", "$1,600", 1, right("Carol")),
+				dailyDouble("DJ_3_5", "$2,000", 1600, 2, right("Carol")),
+				dailyDouble("DJ_4_5", "$2,000", 3200, 3, wrong("Carol")),
+			}}},
+			wantDeltas: []int{1600, 1600, -3200},
+			wantTotals: scores(0, 0, 0),
+		},
+		{
+			name: "negative scores",
+			rounds: []Round{{Name: roundJeopardy, Clues: []Clue{
+				played("J_1_5", "$1,000", 1, wrong("Bob")),
+				played("J_2_5", "$1,000", 2, wrong("Bob"), wrong("Carol")),
+				played("J_3_1", "$200", 3, right("Bob")),
+			}}},
+			wantDeltas: []int{-1000, -1000, -1000, 200},
+			wantTotals: scores(0, -1800, -1000),
+		},
+		{
+			name: "triple stumper",
+			rounds: []Round{{Name: roundJeopardy, Clues: []Clue{
+				{Position: "J_1_1", Value: "$200", OrderNumber: 1, TripleStumper: true},
+				{Position: "J_1_2", Value: "$400", OrderNumber: 2, TripleStumper: true, Responses: []Response{wrong("Alice")}},
+			}}},
+			wantDeltas: []int{-400},
+			wantTotals: scores(-400, 0, 0),
+		},
+		{
+			name: "final jeopardy wagers",
+			rounds: []Round{
+				{Name: roundJeopardy, Clues: []Clue{
+					played("J_1_5", "$1,000", 1, right("Alice")),
+					played("J_2_5", "$1,000", 2, right("Bob")),
+				}},
+				{Name: roundFinalJeopardy, Clues: []Clue{{Position: "FJ", Responses: []Response{
+					finalWager("Alice", true, 1000), finalWager("Bob", false, 1000), finalWager("Carol", false, 0),
+				}}}},
+			},
+			reported: []RoundScore{
+				{Round: roundJeopardy, Scores: scores(1000, 1000, 0)},
+				{Round: roundFinalJeopardy, Scores: scores(2000, 0, 0)},
+			},
+			wantDeltas: []int{1000, 1000, 1000, -1000, 0},
+			wantTotals: scores(2000, 0, 0),
+		},
+		{
+			name: "tiebreaker does not score",
+			rounds: []Round{
+				{Name: roundFinalJeopardy, Clues: []Clue{{Position: "FJ", Responses: []Response{finalWager("Alice", false, 0)}}}},
+				{Name: roundTiebreaker, Clues: []Clue{{Position: "TB", Responses: []Response{right("Alice")}}}},
+			},
+			wantDeltas: []int{0},
+			wantTotals: scores(0, 0, 0),
+		},
+		{
+			name: "page total does not match",
+			rounds: []Round{{Name: roundJeopardy, Clues: []Clue{
+				played("J_1_1", "$200", 1, right("Alice")),
+			}}},
+			reported:     []RoundScore{{Round: roundJeopardy, Scores: scores(400, 0, 0)}},
+			wantDeltas:   []int{200},
+			wantTotals:   scores(200, 0, 0),
+			wantWarnings: []string{warnScoreMismatch},
+		},
+		{
+			name: "unreadable clue value",
+			rounds: []Round{{Name: roundJeopardy, Clues: []Clue{
+				played("J_1_1", "", 1, right("Alice")),
+				played("J_1_2", "$400", 2, right("Alice")),
+			}}},
+			wantDeltas:   []int{400},
+			wantTotals:   scores(400, 0, 0),
+			wantWarnings: []string{warnScore},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := GameData{Contestants: contestants, Rounds: tt.rounds, RoundScores: tt.reported, Unavailable: tt.unavailable}
+			timeline := replayScores(game)
+
+			var deltas []int
+			for _, event := range timeline.Events {
+				deltas = append(deltas, event.Delta)
+			}
+			if !reflect.DeepEqual(deltas, tt.wantDeltas) {
+				t.Errorf("deltas = %v, want %v", deltas, tt.wantDeltas)
+			}
+			if len(timeline.RoundTotals) == 0 {
+				t.Fatal("no round totals")
+			}
+			if got := timeline.RoundTotals[len(timeline.RoundTotals)-1].Scores; !reflect.DeepEqual(got, tt.wantTotals) {
+				t.Errorf("final totals = %v, want %v", got, tt.wantTotals)
+			}
+			if got := warningCodes(timeline.Warnings()); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("warnings = %v, want codes %v", timeline.Warnings(), tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestReplayScoresSnapshots(t *testing.T) {
+	game := GameData{
+		Contestants: []Contestant{{Nickname: "Alice"}, {Nickname: "Bob"}},
+		Rounds: []Round{{Name: roundJeopardy, Clues: []Clue{
+			played("J_1_1", "$200", 1, wrong("Bob"), right("Alice")),
+		}}},
+	}
+	want := []ScoreEvent{
+		{Round: roundJeopardy, Position: "J_1_1", OrderNumber: 1, Contestant: "Bob", Delta: -200,
+			Scores: []ContestantScore{{Nickname: "Alice", Score: 0}, {Nickname: "Bob", Score: -200}}},
+		{Round: roundJeopardy, Position: "J_1_1", OrderNumber: 1, Contestant: "Alice", Delta: 200,
+			Scores: []ContestantScore{{Nickname: "Alice", Score: 200}, {Nickname: "Bob", Score: -200}}},
+	}
+	if got := replayScores(game).Events; !reflect.DeepEqual(got, want) {
+		t.Errorf("events =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestReconcileRound(t *testing.T) {
+	game := GameData{RoundScores: []RoundScore{
+		{Round: roundJeopardy, Scores: scores(1000, -200, 0)},
+		{Round: roundDoubleJeopardy, Scores: scores(5000, 0, 0)},
+	}}
+	tests := []struct {
+		name   string
+		round  string
+		scores map[string]int
+		want   []ScoreMismatch
+	}{
+		{"matches", roundJeopardy, map[string]int{"Alice": 1000, "Bob": -200}, nil},
+		{"round not reported", roundFinalJeopardy, map[string]int{"Alice": 1}, nil},
+		{"one contestant off", roundJeopardy, map[string]int{"Alice": 1000, "Bob": 200},
+			[]ScoreMismatch{{Round: roundJeopardy, Contestant: "Bob", Computed: 200, Reported: -200}}},
+		{"contestant never scored", roundDoubleJeopardy, map[string]int{},
+			[]ScoreMismatch{{Round: roundDoubleJeopardy, Contestant: "Alice", Computed: 0, Reported: 5000}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reconcileRound(game, tt.round, tt.scores); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reconcileRound = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReplayParsedPage replays a game parsed from a page and reconciles it
+// with the page's score tables
+func TestReplayParsedPage(t *testing.T) {
+	page := standardPage()
+	// Jeopardy!: Alice +$200, Bob -$400 on a triple stumper and Carol +$1,000
+	// on a Daily Double in the $600 row
+	page.Rounds[0].Clues[0].Right = []string{"Alice"}
+	page.Rounds[0].Clues[6].Wrong = []string{"Bob"}
+	page.Rounds[0].Clues[6].Stumper = true
+	page.Rounds[0].Clues[12].Value = "DD: $1,000"
+	page.Rounds[0].Clues[12].Right = []string{"Carol"}
+	// Double Jeopardy!: Alice -$200 on a Daily Double picked first, Bob +$400 picked last
+	page.Rounds[1].Clues[29].Value = "DD: $200"
+	page.Rounds[1].Clues[29].Wrong = []string{"Alice"}
+	page.Rounds[1].Clues[29].Order, page.Rounds[1].Clues[0].Order = "1", "30"
+	page.Rounds[1].Clues[0].Right = []string{"Bob"}
+	page.Rounds[2].Final = []pageFinalResponse{
+		{Nickname: "Alice", Correct: false, Text: "wrong", Wager: "$0"},
+		{Nickname: "Bob", Correct: true, Text: "answer", Wager: "$0"},
+		{Nickname: "Carol", Correct: true, Text: "answer", Wager: "$1,000"},
+	}
+	// The final scores on the page disagree with Carol's wager
+	page.Scores = []pageScores{
+		{Heading: "Scores at the end of the Jeopardy! Round:", Scores: scores(200, -400, 1000)},
+		{Heading: "Scores at the end of the Double Jeopardy! Round:", Scores: scores(0, 0, 1000)},
+		{Heading: "Final scores:", Scores: scores(0, 0, 2200)},
+	}
+
+	game, warnings, err := parseGameTableData(page.String())
+	if err != nil {
+		t.Fatalf("parseGameTableData: %v", err)
+	}
+	want := []ParseWarning{{Code: warnScoreMismatch, Message: "Final Jeopardy: Carol replayed to 2000 but page reports 2200"}}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("warnings = %v, want %v", warnings, want)
+	}
+	if clue := game.Rounds[0].Clues[12]; clue.Value != "$600" || clue.Wager != 1000 {
+		t.Errorf("Daily Double value %q and wager %d, want $600 and 1000", clue.Value, clue.Wager)
+	}
+
+	var deltas []int
+	for _, event := range game.ScoreTimeline {
+		deltas = append(deltas, event.Delta)
+	}
+	if want := []int{200, -400, 1000, -200, 400, 0, 0, 1000}; !reflect.DeepEqual(deltas, want) {
+		t.Errorf("deltas = %v, want %v", deltas, want)
+	}
+}
@@ -1,87 +1,128 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
-	"math/rand"
 	"net/http"
+	"path/filepath"
+	"sync"
 	"time"
 )
 
-func RequestGameData(gameId int) string {
-	url := fmt.Sprintf("https://j-archive.com/showgame.php?game_id=%d", gameId)
-	resp, err := http.Get(url)
+// fetcher downloads J-Archive pages. Requests from every goroutine share one
+// rate limit so raising the concurrency never raises the load on the site.
+type fetcher struct {
+	baseURL string
+	dataDir string
+	delay   time.Duration
+	client  *http.Client
+	run     *runLedger // counts pages and bytes, may be nil
+
+	mu   sync.Mutex
+	last time.Time
+}
 
-	if err != nil {
-		log.Fatalln(err)
+func newFetcher(cfg Config, run *runLedger) *fetcher {
+	return &fetcher{
+		run:     run,
+		baseURL: cfg.BaseURL,
+		dataDir: cfg.DataDir,
+		delay:   time.Duration(cfg.RequestDelay),
+		client:  &http.Client{Timeout: 30 * time.Second},
 	}
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatalln(err)
+// wait blocks until the request delay has passed since the previous
+// request, or until the context is cancelled
+func (f *fetcher) wait(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if next := f.last.Add(f.delay); time.Now().Before(next) {
+		timer := time.NewTimer(time.Until(next))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
-	gameData := string(body)
-
-	return gameData
+	f.last = time.Now()
+	return nil
 }
 
-func RequestSeason(url string) string {
-	resp, err := http.Get(url)
-
+// get fetches a page by its path relative to the base URL. Cancelling the
+// context aborts the request.
+func (f *fetcher) get(ctx context.Context, path string) (string, error) {
+	if err := f.wait(ctx); err != nil {
+		return "", err
+	}
+	url := f.baseURL + "/" + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		log.Fatalln(err)
+		return "", err
 	}
-
-	body, err := ioutil.ReadAll(resp.Body)
+	resp, err := f.client.Do(req)
 	if err != nil {
-		log.Fatalln(err)
+		return "", err
 	}
+	defer resp.Body.Close()
 
-	seasonData := string(body)
-	return seasonData
-}
-
-func RequestSeasonList(url string) string {
-	cacheDir := "data/metadata"
-	filename := "season_list.html"
-
-	cachedContent, err := loadHTMLFromFile(cacheDir, filename)
-	if err == nil {
-		log.Printf("Loaded cached season list from %s", filename)
-		return cachedContent
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching %s: status %d", url, resp.StatusCode)
 	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %v", err)
+	}
+	f.run.pageFetched(len(body))
+	return string(body), nil
+}
 
+// SeasonList returns the page listing every season. It is never cached
+// because the newest season it lists is the one still airing.
+func (f *fetcher) SeasonList(ctx context.Context) (string, error) {
 	log.Printf("Fetching season list from J-Archive")
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	content, err := f.get(ctx, "listseasons.php")
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch season list: %v", err)
 	}
+	return content, nil
+}
 
-	time.Sleep(time.Duration(1+rand.Intn(2)) * time.Second)
+// Season returns the page listing a season's games. It is never cached
+// because new games are added to the current season.
+func (f *fetcher) Season(ctx context.Context, seasonID string) (string, error) {
+	return f.get(ctx, "showseason.php?season="+seasonID)
+}
 
-	resp, err := client.Get(url)
-	if err != nil {
-		log.Fatalf("Failed to fetch season list: %v", err)
-	}
-	defer resp.Body.Close()
+// cachedGameFilename is the name a game page is cached under in its season directory
+func cachedGameFilename(gameID int, seasonID string) string {
+	return fmt.Sprintf("%d_%s_j-archive.html", gameID, seasonID)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Fatalf("Failed to fetch season list. Status: %d", resp.StatusCode)
+// Game returns a game page, from the cache under data/season_<id> if it
+// has been fetched before
+func (f *fetcher) Game(ctx context.Context, gameID int, seasonID string) (string, error) {
+	seasonDir := filepath.Join(f.dataDir, "season_"+seasonID)
+	filename := cachedGameFilename(gameID, seasonID)
+	cachedContent, err := loadHTMLFromFile(seasonDir, filename)
+	if err == nil {
+		log.Printf("Loaded cached game data for game %d from %s", gameID, filename)
+		f.run.gameLoaded(true)
+		return cachedContent, nil
 	}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	log.Printf("Fetching game data for game %d from J-Archive", gameID)
+	gameData, err := f.get(ctx, fmt.Sprintf("showgame.php?game_id=%d", gameID))
 	if err != nil {
-		log.Fatalf("Failed to read response body: %v", err)
+		return "", err
 	}
-
-	content := string(bodyBytes)
-
-	// Save to cache
-	saveErr := saveHTMLToFile(cacheDir, filename, content)
+	f.run.gameLoaded(false)
+	saveErr := saveHTMLToFile(seasonDir, filename, gameData)
 	if saveErr != nil {
-		log.Printf("Error saving season list: %v", saveErr)
+		log.Printf("Error saving game data: %v", saveErr)
 	}
-
-	return content
+	return gameData, nil
 }
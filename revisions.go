@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tlegnard/answer-there/jarchive"
+)
+
+// J-Archive volunteers correct clues after a game is first posted. When a
+// game that is already stored is written again, every field that changed is
+// recorded in clue_revisions before the old rows are replaced. A game stored
+// by a different parser version may differ only because the parser changed,
+// so its revisions are recorded with the parser as their source.
+
+// Clue fields tracked in clue_revisions
+const (
+	revisionText            = "text"
+	revisionCorrectResponse = "correct_response"
+	revisionResponses       = "responses"
+)
+
+var revisionFields = []string{revisionText, revisionCorrectResponse, revisionResponses}
+
+// Sources of a revision
+const (
+	revisionSourceArchive = "archive" // J-Archive changed the page
+	revisionSourceParser  = "parser"  // the game was re-parsed by another parser version
+)
+
+// ClueRevision is one changed field of a clue
+type ClueRevision struct {
+	GameID   int
+	ClueID   string
+	Field    string
+	OldValue string
+	NewValue string
+	Source   string
+	// OldParserVersion produced OldValue and is 0 if unknown; ParserVersion produced NewValue
+	OldParserVersion int
+	ParserVersion    int
+	RevisedAt        time.Time
+}
+
+// revisionSource tells a J-Archive edit from a parser change by the parser
+// versions that produced the stored and the new game
+func revisionSource(storedVersion, newVersion int) string {
+	if storedVersion != newVersion {
+		return revisionSourceParser
+	}
+	return revisionSourceArchive
+}
+
+// revisableFields returns the tracked fields of a clue by name
+func revisableFields(clue Clue) map[string]string {
+	return map[string]string{
+		revisionText:            clue.Text,
+		revisionCorrectResponse: clue.CorrectResponse,
+		revisionResponses:       responseSummary(clue.Responses),
+	}
+}
+
+// responseSummary writes responses as one comparable line, e.g.
+// "Alice right; Bob wrong" or "Alice: Paris, $1,000, right" for Final Jeopardy
+func responseSummary(responses []Response) string {
+	parts := make([]string, len(responses))
+	for i, response := range responses {
+		result := "wrong"
+		if response.Correct {
+			result = "right"
+		}
+		if response.Text == "" && response.Wager == 0 {
+			parts[i] = response.Contestant + " " + result
+			continue
+		}
+		parts[i] = fmt.Sprintf("%s: %s, %s, %s", response.Contestant, response.Text, jarchive.FormatMoney(response.Wager), result)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// readStoredClues reads the tracked fields of the clues already stored for a
+// game, keyed by clue ID. It runs inside the write transaction.
+func readStoredClues(stmts *gameStatements, gameID int) (map[string]Clue, error) {
+	clues := make(map[string]Clue)
+
+	rows, err := stmts.selectClues.Query(gameID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var cID string
+		var clue Clue
+		if err := rows.Scan(&cID, &clue.Text, &clue.CorrectResponse); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		clues[cID] = clue
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = stmts.selectResponses.Query(gameID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var cID string
+		var response Response
+		if err := rows.Scan(&cID, &response.Contestant, &response.Correct); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		clue := clues[cID]
+		clue.Responses = append(clue.Responses, response)
+		clues[cID] = clue
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Final Jeopardy and tiebreaker clues are positioned by their round code
+	rows, err = stmts.selectFinalJeopardy.Query(gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var roundCode string
+		var response Response
+		if err := rows.Scan(&roundCode, &response.Contestant, &response.Correct, &response.Text, &response.Wager); err != nil {
+			return nil, err
+		}
+		cID := clueID(gameID, roundCode)
+		clue := clues[cID]
+		clue.Responses = append(clue.Responses, response)
+		clues[cID] = clue
+	}
+	return clues, rows.Err()
+}
+
+// diffClues compares the stored clues of a game with a new parse of it. A
+// clue missing on either side compares as empty.
+func diffClues(stored map[string]Clue, game GameData) []ClueRevision {
+	var revisions []ClueRevision
+	seen := make(map[string]bool)
+
+	compare := func(cID string, before, after Clue) {
+		oldFields, newFields := revisableFields(before), revisableFields(after)
+		for _, field := range revisionFields {
+			if oldFields[field] != newFields[field] {
+				revisions = append(revisions, ClueRevision{
+					GameID:   game.ID,
+					ClueID:   cID,
+					Field:    field,
+					OldValue: oldFields[field],
+					NewValue: newFields[field],
+				})
+			}
+		}
+	}
+
+	for _, round := range game.Rounds {
+		for _, clue := range round.Clues {
+			cID := clueID(game.ID, clue.Position)
+			seen[cID] = true
+			compare(cID, stored[cID], clue)
+		}
+	}
+
+	var removed []string
+	for cID := range stored {
+		if !seen[cID] {
+			removed = append(removed, cID)
+		}
+	}
+	sort.Strings(removed)
+	for _, cID := range removed {
+		compare(cID, stored[cID], Clue{})
+	}
+	return revisions
+}
+
+// writeRevisions records how the clues of an already stored game differ from
+// the version about to replace them. It must run before the clues are deleted.
+func writeRevisions(stmts *gameStatements, game GameData) error {
+	stored, err := readStoredClues(stmts, game.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read stored clues: %v", err)
+	}
+	// A game written for the first time has no history
+	if len(stored) == 0 {
+		return nil
+	}
+	var storedVersion int
+	if err := stmts.selectParserVersion.QueryRow(game.ID).Scan(&storedVersion); err != nil {
+		return fmt.Errorf("failed to read stored parser version: %v", err)
+	}
+
+	source := revisionSource(storedVersion, game.ParserVersion)
+	revisedAt := time.Now().UTC()
+	for _, revision := range diffClues(stored, game) {
+		_, err := stmts.insertRevision.Exec(
+			game.ID,
+			revision.ClueID,
+			revision.Field,
+			revision.OldValue,
+			revision.NewValue,
+			source,
+			storedVersion,
+			game.ParserVersion,
+			revisedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert revision into clue_revisions table: %v", err)
+		}
+	}
+	return nil
+}
+
+// GameHistory returns every recorded change to a game's clues, oldest first
+func (s *sqliteStore) GameHistory(gameID int) ([]ClueRevision, error) {
+	rows, err := s.db.Query(`
+		SELECT game_id, clue_id, field, COALESCE(old_value, ''), COALESCE(new_value, ''),
+			source, COALESCE(old_parser_version, 0), parser_version, revised_at
+		FROM clue_revisions WHERE game_id = ? ORDER BY id;`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []ClueRevision
+	for rows.Next() {
+		var revision ClueRevision
+		if err := rows.Scan(&revision.GameID, &revision.ClueID, &revision.Field, &revision.OldValue, &revision.NewValue,
+			&revision.Source, &revision.OldParserVersion, &revision.ParserVersion, &revision.RevisedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// runHistory implements the history command
+//
+//	history <game_id>
+func runHistory(store *sqliteStore, args []string) {
+	if len(args) != 1 {
+		log.Fatal("Usage: history <game_id>")
+	}
+	gameID, err := strconv.Atoi(args[0])
+	if err != nil {
+		log.Fatalf("Invalid game ID %q", args[0])
+	}
+
+	revisions, err := store.GameHistory(gameID)
+	if err != nil {
+		log.Fatalf("Failed to read history: %v", err)
+	}
+	if len(revisions) == 0 {
+		fmt.Printf("No changes recorded for game %d\n", gameID)
+		return
+	}
+
+	var last time.Time
+	for _, revision := range revisions {
+		if !revision.RevisedAt.Equal(last) {
+			when := revision.RevisedAt.Local().Format("2006-01-02 15:04:05")
+			if revision.Source == revisionSourceParser {
+				fmt.Printf("%s  parser v%d -> v%d, changed by the parser\n", when, revision.OldParserVersion, revision.ParserVersion)
+			} else {
+				fmt.Printf("%s  parser v%d\n", when, revision.ParserVersion)
+			}
+			last = revision.RevisedAt
+		}
+		fmt.Printf("  %s %s\n", revision.ClueID, revision.Field)
+		fmt.Printf("    - %s\n", revision.OldValue)
+		fmt.Printf("    + %s\n", revision.NewValue)
+	}
+}
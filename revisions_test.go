@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRevisionSource(t *testing.T) {
+	tests := []struct {
+		stored, parsed int
+		want           string
+	}{
+		{5, 5, revisionSourceArchive},
+		{4, 5, revisionSourceParser},
+		{6, 5, revisionSourceParser},
+		{0, 5, revisionSourceParser}, // stored before parser versions were recorded
+	}
+	for _, tt := range tests {
+		if got := revisionSource(tt.stored, tt.parsed); got != tt.want {
+			t.Errorf("revisionSource(%d, %d) = %q, want %q", tt.stored, tt.parsed, got, tt.want)
+		}
+	}
+}
+
+func TestDiffClues(t *testing.T) {
+	stored := map[string]Clue{
+		"1-J_1_1": {Text: "old text", CorrectResponse: "same", Responses: []Response{{Contestant: "Alice", Correct: true}}},
+		"1-J_2_1": {Text: "removed"},
+	}
+	game := GameData{ID: 1, Rounds: []Round{{Name: roundJeopardy, Clues: []Clue{
+		{Position: "J_1_1", Text: "new text", CorrectResponse: "same", Responses: []Response{{Contestant: "Bob", Correct: true}}},
+		{Position: "J_3_1", Text: "added"},
+	}}}}
+
+	var got [][3]string
+	for _, revision := range diffClues(stored, game) {
+		got = append(got, [3]string{revision.ClueID, revision.Field, revision.OldValue + " -> " + revision.NewValue})
+	}
+	want := [][3]string{
+		{"1-J_1_1", revisionText, "old text -> new text"},
+		{"1-J_1_1", revisionResponses, "Alice right -> Bob right"},
+		{"1-J_3_1", revisionText, " -> added"},
+		{"1-J_2_1", revisionText, "removed -> "},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffClues = %q\nwant %q", got, want)
+	}
+}
+
+func TestWriteRevisionsRecordsSource(t *testing.T) {
+	store, err := newSQLiteStore(filepath.Join(t.TempDir(), "jeopardy.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	version := func(parser int, text string) GameData {
+		return GameData{ID: 1, ShowNum: 8001, AirDate: "2019-05-01", ParserVersion: parser, Rounds: []Round{{
+			Name:       roundJeopardy,
+			Categories: []Category{{Name: "HISTORY"}},
+			Clues:      []Clue{{Position: "J_1_1", Value: "$200", Text: text}},
+		}}}
+	}
+	// The first write has no history, the second is a J-Archive edit and the
+	// third a re-parse by a newer parser
+	for _, game := range []GameData{version(5, "first"), version(5, "edited"), version(6, "re-parsed")} {
+		if err := store.SaveGame("35", game); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revisions, err := store.GameHistory(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("recorded %d revisions, want 2: %+v", len(revisions), revisions)
+	}
+	tests := []struct {
+		revision       ClueRevision
+		source         string
+		old, new       string
+		oldVer, newVer int
+	}{
+		{revisions[0], revisionSourceArchive, "first", "edited", 5, 5},
+		{revisions[1], revisionSourceParser, "edited", "re-parsed", 5, 6},
+	}
+	for _, tt := range tests {
+		r := tt.revision
+		if r.Source != tt.source || r.OldValue != tt.old || r.NewValue != tt.new || r.OldParserVersion != tt.oldVer || r.ParserVersion != tt.newVer {
+			t.Errorf("revision = %s %q -> %q v%d -> v%d, want %s %q -> %q v%d -> v%d",
+				r.Source, r.OldValue, r.NewValue, r.OldParserVersion, r.ParserVersion,
+				tt.source, tt.old, tt.new, tt.oldVer, tt.newVer)
+		}
+	}
+}
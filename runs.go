@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Run statuses. A run whose process was killed stays running.
+const (
+	runRunning     = "running"
+	runCompleted   = "completed"
+	runInterrupted = "interrupted"
+	runFailed      = "failed"
+)
+
+// Run is a row of the runs ledger, recording what one run of a command that
+// changes the dataset did: scrape, retry-failed, reparse, repair or import
+type Run struct {
+	RunID           int64      `json:"run_id"`
+	Command         string     `json:"command"`
+	Args            []string   `json:"args"`
+	StartedAt       time.Time  `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+	Status          string     `json:"status"`
+	Seasons         []string   `json:"seasons"`
+	GamesFetched    int        `json:"games_fetched"`
+	GamesCached     int        `json:"games_cached"`
+	BytesDownloaded int64      `json:"bytes_downloaded"`
+	GamesStored     int        `json:"games_stored"`
+	ParseWarnings   int        `json:"parse_warnings"`
+	Failures        int        `json:"failures"`
+	ParserVersion   int        `json:"parser_version"`
+}
+
+// runLedger counts what a run does and keeps its row in the runs table up
+// to date. The fetcher's workers update it concurrently. A nil ledger
+// counts nothing.
+type runLedger struct {
+	store PipelineStore
+
+	mu  sync.Mutex
+	run Run
+}
+
+// startRun records the start of a run
+func startRun(store PipelineStore, command string, args []string) (*runLedger, error) {
+	ledger := &runLedger{store: store, run: Run{
+		Command:       command,
+		Args:          append([]string{}, args...),
+		StartedAt:     time.Now().UTC(),
+		Status:        runRunning,
+		ParserVersion: parserVersion,
+	}}
+	runID, err := store.StartRun(ledger.run)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record run: %v", err)
+	}
+	ledger.run.RunID = runID
+	return ledger, nil
+}
+
+// startRunLedger starts recording a run, carrying on without a ledger if
+// the run cannot be recorded
+func startRunLedger(store PipelineStore, command string, args []string) *runLedger {
+	ledger, err := startRun(store, command, args)
+	if err != nil {
+		log.Printf("Not recording this run: %v", err)
+		return nil
+	}
+	return ledger
+}
+
+// pageFetched counts bytes downloaded from J-Archive
+func (l *runLedger) pageFetched(bytes int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.run.BytesDownloaded += int64(bytes)
+}
+
+// gameLoaded counts a game page, either downloaded or read from the cache
+func (l *runLedger) gameLoaded(cached bool) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cached {
+		l.run.GamesCached++
+	} else {
+		l.run.GamesFetched++
+	}
+}
+
+// seasonDone counts the outcome of scraping a season's games and saves the
+// run, so a run that crashes later still records its progress
+func (l *runLedger) seasonDone(seasonID string, stored, warnings, failures int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	if !containsString(l.run.Seasons, seasonID) {
+		l.run.Seasons = append(l.run.Seasons, seasonID)
+	}
+	l.mu.Unlock()
+	l.gamesDone(stored, warnings, failures)
+}
+
+// gamesDone counts the outcome of writing games and saves the run
+func (l *runLedger) gamesDone(stored, warnings, failures int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.run.GamesStored += stored
+	l.run.ParseWarnings += warnings
+	l.run.Failures += failures
+	l.mu.Unlock()
+
+	if err := l.save(); err != nil {
+		log.Printf("Error saving run %d: %v", l.run.RunID, err)
+	}
+}
+
+// finish records the end of the run and must be deferred. The run is
+// completed when the command returns, interrupted if the context was
+// cancelled, and failed if the command panics, in which case the panic
+// carries on once the run is saved.
+func (l *runLedger) finish(ctx context.Context) {
+	if l == nil {
+		return
+	}
+	if r := recover(); r != nil {
+		l.end(runFailed)
+		panic(r)
+	}
+	if ctx.Err() != nil {
+		l.end(runInterrupted)
+		return
+	}
+	l.end(runCompleted)
+}
+
+// fatalf records the run as failed and exits like log.Fatalf, which skips
+// the deferred finish
+func (l *runLedger) fatalf(format string, args ...interface{}) {
+	l.end(runFailed)
+	log.Fatalf(format, args...)
+}
+
+// end records the run as finished with status
+func (l *runLedger) end(status string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	finishedAt := time.Now().UTC()
+	l.run.FinishedAt = &finishedAt
+	l.run.Status = status
+	l.mu.Unlock()
+
+	if err := l.save(); err != nil {
+		log.Printf("Error saving run %d: %v", l.run.RunID, err)
+	}
+}
+
+// save writes the counts to the run's row
+func (l *runLedger) save() error {
+	l.mu.Lock()
+	run := l.run
+	run.Seasons = append([]string{}, l.run.Seasons...)
+	l.mu.Unlock()
+	return l.store.SaveRun(run)
+}
+
+func (s *sqliteStore) StartRun(run Run) (int64, error) {
+	argsJSON, err := json.Marshal(run.Args)
+	if err != nil {
+		return 0, err
+	}
+	result, err := s.db.Exec(`
+		INSERT INTO runs (command, args, started_at, status, seasons, games_fetched, games_cached,
+			bytes_downloaded, games_stored, parse_warnings, failures, parser_version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		run.Command, string(argsJSON), run.StartedAt, run.Status, strings.Join(run.Seasons, ","),
+		run.GamesFetched, run.GamesCached, run.BytesDownloaded, run.GamesStored, run.ParseWarnings,
+		run.Failures, run.ParserVersion)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (s *sqliteStore) SaveRun(run Run) error {
+	var finishedAt interface{}
+	if run.FinishedAt != nil {
+		finishedAt = *run.FinishedAt
+	}
+	_, err := s.db.Exec(`
+		UPDATE runs SET finished_at = ?, status = ?, seasons = ?, games_fetched = ?, games_cached = ?,
+			bytes_downloaded = ?, games_stored = ?, parse_warnings = ?, failures = ?
+		WHERE run_id = ?;`,
+		finishedAt, run.Status, strings.Join(run.Seasons, ","), run.GamesFetched, run.GamesCached,
+		run.BytesDownloaded, run.GamesStored, run.ParseWarnings, run.Failures, run.RunID)
+	return err
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// readRuns returns the most recent runs, newest first, or every run if limit is 0
+func readRuns(db *sql.DB, limit int) ([]Run, error) {
+	query := `
+		SELECT run_id, command, args, started_at, finished_at, status, seasons, games_fetched, games_cached,
+			bytes_downloaded, games_stored, parse_warnings, failures, parser_version
+		FROM runs ORDER BY run_id DESC`
+	var queryArgs []interface{}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		queryArgs = append(queryArgs, limit)
+	}
+	return queryRuns(db, query+`;`, queryArgs...)
+}
+
+// readRun returns one run, or nil if there is no such run
+func readRun(db *sql.DB, runID int64) (*Run, error) {
+	runs, err := queryRuns(db, `
+		SELECT run_id, command, args, started_at, finished_at, status, seasons, games_fetched, games_cached,
+			bytes_downloaded, games_stored, parse_warnings, failures, parser_version
+		FROM runs WHERE run_id = ?;`, runID)
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return &runs[0], nil
+}
+
+func queryRuns(db *sql.DB, query string, args ...interface{}) ([]Run, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []Run
+	for rows.Next() {
+		var (
+			run        Run
+			argsJSON   string
+			seasons    string
+			finishedAt sql.NullTime
+		)
+		if err := rows.Scan(&run.RunID, &run.Command, &argsJSON, &run.StartedAt, &finishedAt, &run.Status, &seasons,
+			&run.GamesFetched, &run.GamesCached, &run.BytesDownloaded, &run.GamesStored, &run.ParseWarnings,
+			&run.Failures, &run.ParserVersion); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(argsJSON), &run.Args); err != nil {
+			return nil, fmt.Errorf("run %d has invalid arguments: %v", run.RunID, err)
+		}
+		if seasons != "" {
+			run.Seasons = strings.Split(seasons, ",")
+		}
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// elapsed is how long the run took, or has been running
+func (r Run) elapsed() time.Duration {
+	end := time.Now()
+	if r.FinishedAt != nil {
+		end = *r.FinishedAt
+	}
+	return end.Sub(r.StartedAt).Round(time.Second)
+}
+
+// commandLine is the command as it was typed, without the global flags
+func (r Run) commandLine() string {
+	return strings.TrimSpace(r.Command + " " + strings.Join(r.Args, " "))
+}
+
+// formatBytes shows a byte count in the largest unit that keeps it above one
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, suffix := float64(bytes)/unit, "KMGT"
+	i := 0
+	for value >= unit && i < len(suffix)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, suffix[i])
+}
+
+// printRun prints every field of a run
+func printRun(run Run) {
+	fmt.Printf("Run %d: %s\n", run.RunID, run.commandLine())
+	fmt.Printf("Status:           %s\n", run.Status)
+	fmt.Printf("Started:          %s\n", run.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	if run.FinishedAt != nil {
+		fmt.Printf("Finished:         %s (%s)\n", run.FinishedAt.Local().Format("2006-01-02 15:04:05"), run.elapsed())
+	}
+	fmt.Printf("Parser version:   %d\n", run.ParserVersion)
+	fmt.Printf("Seasons:          %d %s\n", len(run.Seasons), strings.Join(run.Seasons, ", "))
+	fmt.Printf("Games fetched:    %d\n", run.GamesFetched)
+	fmt.Printf("Games from cache: %d\n", run.GamesCached)
+	fmt.Printf("Downloaded:       %s\n", formatBytes(run.BytesDownloaded))
+	fmt.Printf("Games stored:     %d\n", run.GamesStored)
+	fmt.Printf("Parse warnings:   %d\n", run.ParseWarnings)
+	fmt.Printf("Failures:         %d\n", run.Failures)
+}
+
+// runRuns implements the runs command, which lists the recorded scrape
+// runs or shows one of them in full
+//
+//	runs [-limit N] [-json]
+//	runs show [-json] RUN_ID
+func runRuns(db *sql.DB, args []string) {
+	if len(args) > 0 && args[0] == "show" {
+		flags := flag.NewFlagSet("runs show", flag.ExitOnError)
+		asJSON := flags.Bool("json", false, "print the run as JSON")
+		flags.Parse(args[1:])
+		if flags.NArg() != 1 {
+			log.Fatal("Usage: runs show [-json] RUN_ID")
+		}
+		runID, err := strconv.ParseInt(flags.Arg(0), 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid run ID %q", flags.Arg(0))
+		}
+
+		run, err := readRun(db, runID)
+		if err != nil {
+			log.Fatalf("Failed to read run: %v", err)
+		}
+		if run == nil {
+			log.Fatalf("No run %d", runID)
+		}
+		if *asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(run); err != nil {
+				log.Fatalf("Failed to write run: %v", err)
+			}
+			return
+		}
+		printRun(*run)
+		return
+	}
+
+	flags := flag.NewFlagSet("runs", flag.ExitOnError)
+	limit := flags.Int("limit", 20, "number of recent runs to list, 0 for all")
+	asJSON := flags.Bool("json", false, "print the runs as JSON")
+	flags.Parse(args)
+
+	runs, err := readRuns(db, *limit)
+	if err != nil {
+		log.Fatalf("Failed to read runs: %v", err)
+	}
+	if *asJSON {
+		if runs == nil {
+			runs = []Run{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(runs); err != nil {
+			log.Fatalf("Failed to write runs: %v", err)
+		}
+		return
+	}
+	if len(runs) == 0 {
+		fmt.Println("No runs recorded yet")
+		return
+	}
+
+	fmt.Printf("%5s  %-19s %9s  %-11s %7s %7s %9s %7s %8s %6s  %s\n",
+		"Run", "Started", "Duration", "Status", "Fetched", "Cached", "Download", "Stored", "Warnings", "Failed", "Command")
+	for _, run := range runs {
+		fmt.Printf("%5d  %-19s %9s  %-11s %7d %7d %9s %7d %8d %6d  %s\n",
+			run.RunID, run.StartedAt.Local().Format("2006-01-02 15:04:05"), run.elapsed(), run.Status,
+			run.GamesFetched, run.GamesCached, formatBytes(run.BytesDownloaded), run.GamesStored,
+			run.ParseWarnings, run.Failures, run.commandLine())
+	}
+}
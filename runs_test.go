@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRunLedgerFinish(t *testing.T) {
+	tests := []struct {
+		name   string
+		cancel bool
+		panics bool
+		want   string
+	}{
+		{"returns", false, false, runCompleted},
+		{"cancelled", true, false, runInterrupted},
+		{"panics", false, true, runFailed},
+		{"panics after cancel", true, true, runFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryStore()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			recovered := func() (r interface{}) {
+				defer func() { r = recover() }()
+				run := startRunLedger(store, "scrape", nil)
+				defer run.finish(ctx)
+				run.seasonDone("35", 2, 1, 0)
+				if tt.cancel {
+					cancel()
+				}
+				if tt.panics {
+					panic("boom")
+				}
+				return nil
+			}()
+			if tt.panics && recovered != "boom" {
+				t.Errorf("recovered %v, want the panic to carry on past finish", recovered)
+			}
+
+			runs := store.Runs()
+			if len(runs) != 1 {
+				t.Fatalf("got %d runs, want 1", len(runs))
+			}
+			run := runs[0]
+			if run.Status != tt.want {
+				t.Errorf("status = %q, want %q", run.Status, tt.want)
+			}
+			if run.FinishedAt == nil {
+				t.Error("finished_at not set")
+			}
+			if run.GamesStored != 2 || run.ParseWarnings != 1 {
+				t.Errorf("counts = %d stored, %d warnings, want 2 and 1", run.GamesStored, run.ParseWarnings)
+			}
+		})
+	}
+}
+
+func TestNilRunLedger(t *testing.T) {
+	var run *runLedger
+	run.pageFetched(10)
+	run.gameLoaded(true)
+	run.seasonDone("35", 1, 0, 0)
+	run.gamesDone(1, 0, 0)
+	run.finish(context.Background())
+}
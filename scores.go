@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// parseMoney converts page amounts such as "$1,000", "-$400" and "DD: $2,000" to integers
+func parseMoney(text string) (int, error) {
+	cleaned := strings.TrimSpace(text)
+	cleaned = strings.TrimPrefix(cleaned, "DD:")
+	cleaned = strings.NewReplacer("$", "", ",", "", " ", "").Replace(cleaned)
+	if cleaned == "" {
+		return 0, fmt.Errorf("no amount in %q", text)
+	}
+	amount, err := strconv.Atoi(cleaned)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", text)
+	}
+	return amount, nil
+}
+
+// parseResponses reads who responded to a clue and whether they were right.
+// Final Jeopardy responses also carry the response text and wager.
+func parseResponses(clueHtml *goquery.Selection, roundName string) ([]Response, bool, []ParseWarning) {
+	var responses []Response
+	var warnings []ParseWarning
+	tripleStumper := false
+	final := roundName == roundFinalJeopardy || roundName == roundTiebreaker
+
+	clueHtml.Find("td.clue_text td.right, td.clue_text td.wrong").Each(func(_ int, responseHtml *goquery.Selection) {
+		name := normalizeText(responseHtml.Text())
+		if strings.Contains(strings.ToLower(name), "stumper") {
+			tripleStumper = true
+			return
+		}
+
+		response := Response{
+			Contestant: name,
+			Correct:    responseHtml.HasClass("right"),
+		}
+
+		if final {
+			response.Text = normalizeText(responseHtml.Next().Text())
+			if wagerText := responseHtml.Parent().Next().Find("td").First().Text(); strings.TrimSpace(wagerText) != "" {
+				wager, err := parseMoney(wagerText)
+				if err != nil {
+					warnings = append(warnings, newParseWarning(warnWager, "%s: %s: %v", roundName, name, err))
+				}
+				response.Wager = wager
+			}
+		}
+
+		responses = append(responses, response)
+	})
+
+	return responses, tripleStumper, warnings
+}
+
+// fillDailyDoubleValues gives Daily Doubles the board value of their row,
+// since the page only shows the wager
+func fillDailyDoubleValues(round *Round) {
+	rowValues := make(map[string]string)
+	for _, clue := range round.Clues {
+		if !clue.DailyDouble && clue.Value != "" {
+			rowValues[clueRow(clue.Position)] = clue.Value
+		}
+	}
+	for i, clue := range round.Clues {
+		if clue.DailyDouble && clue.Value == "" {
+			round.Clues[i].Value = rowValues[clueRow(clue.Position)]
+		}
+	}
+}
+
+// clueRow returns the row from a clue position such as J_3_2
+func clueRow(position string) string {
+	if i := strings.LastIndex(position, "_"); i >= 0 {
+		return position[i+1:]
+	}
+	return ""
+}
+
+// parseRoundScores reads the score tables shown after each round
+func parseRoundScores(doc *goquery.Document) ([]RoundScore, []ParseWarning) {
+	var roundScores []RoundScore
+	var warnings []ParseWarning
+
+	doc.Find("h3").Each(func(_ int, headingHtml *goquery.Selection) {
+		heading := headingHtml.Text()
+		var roundName string
+		switch {
+		case strings.Contains(heading, "end of the Jeopardy! Round"):
+			roundName = roundJeopardy
+		case strings.Contains(heading, "end of the Double Jeopardy! Round"):
+			roundName = roundDoubleJeopardy
+		case strings.Contains(heading, "Final scores"):
+			roundName = roundFinalJeopardy
+		default:
+			return
+		}
+
+		scoreTable := headingHtml.NextFiltered("table")
+		nicknames := scoreTable.Find("td.score_player_nickname")
+		amounts := scoreTable.Find("td.score_positive, td.score_negative")
+		if nicknames.Length() == 0 || nicknames.Length() != amounts.Length() {
+			warnings = append(warnings, newParseWarning(warnScore, "%s: found %d nicknames and %d scores",
+				roundName, nicknames.Length(), amounts.Length()))
+			return
+		}
+
+		roundScore := RoundScore{Round: roundName}
+		nicknames.Each(func(i int, nicknameHtml *goquery.Selection) {
+			amountText := amounts.Eq(i).Text()
+			score, err := parseMoney(amountText)
+			if err != nil {
+				warnings = append(warnings, newParseWarning(warnScore, "%s: %v", roundName, err))
+			}
+			roundScore.Scores = append(roundScore.Scores, ContestantScore{
+				Nickname: normalizeText(nicknameHtml.Text()),
+				Score:    score,
+			})
+		})
+		roundScores = append(roundScores, roundScore)
+	})
+
+	return roundScores, warnings
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    int
+		wantErr bool
+	}{
+		{"$200", 200, false},
+		{"$1,000", 1000, false},
+		{"-$400", -400, false},
+		{"DD: $2,000", 2000, false},
+		{" $12,400 ", 12400, false},
+		{"$0", 0, false},
+		{"", 0, true},
+		{"DD:", 0, true},
+		{"$2OO", 0, true},
+		{"True Daily Double", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := parseMoney(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMoney(%q) error = %v, want error %v", tt.text, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseMoney(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+// clueCell parses a clue cell of a fixture page
+func clueCell(t *testing.T, body string) *goquery.Selection {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><body><table><tr>" + body + "</tr></table></body></html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc.Find("td.clue").First()
+}
+
+func TestParseResponses(t *testing.T) {
+	tests := []struct {
+		name         string
+		clue         pageClue
+		want         []Response
+		wantStumper  bool
+		wantWarnings []string
+	}{
+		{"nobody responded", pageClue{Text: "clue", Value: "$200"}, nil, false, nil},
+		{"right", pageClue{Text: "clue", Value: "$200", Right: []string{"Alice"}},
+			[]Response{{Contestant: "Alice", Correct: true}}, false, nil},
+		{"wrong then right", pageClue{Text: "clue", Value: "$200", Wrong: []string{"Bob", "Carol"}, Right: []string{"Alice"}},
+			[]Response{{Contestant: "Bob"}, {Contestant: "Carol"}, {Contestant: "Alice", Correct: true}}, false, nil},
+		{"triple stumper", pageClue{Text: "clue", Value: "$200", Wrong: []string{"Bob"}, Stumper: true},
+			[]Response{{Contestant: "Bob"}}, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses, stumper, warnings := parseResponses(clueCell(t, tt.clue.html("J_1_1")), roundJeopardy)
+			if !reflect.DeepEqual(responses, tt.want) {
+				t.Errorf("responses = %+v, want %+v", responses, tt.want)
+			}
+			if stumper != tt.wantStumper {
+				t.Errorf("triple stumper = %v, want %v", stumper, tt.wantStumper)
+			}
+			if got := warningCodes(warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("warnings = %v, want codes %v", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestParseFinalResponses(t *testing.T) {
+	tests := []struct {
+		name         string
+		responses    []pageFinalResponse
+		want         []Response
+		wantWarnings []string
+	}{
+		{"wagers", []pageFinalResponse{
+			{Nickname: "Alice", Correct: true, Text: "What is Ohio?", Wager: "$3,000"},
+			{Nickname: "Bob", Correct: false, Text: "What is Iowa?", Wager: "$0"},
+		}, []Response{
+			{Contestant: "Alice", Correct: true, Text: "What is Ohio?", Wager: 3000},
+			{Contestant: "Bob", Text: "What is Iowa?"},
+		}, nil},
+		{"missing wager", []pageFinalResponse{{Nickname: "Alice", Text: "What is Utah?"}},
+			[]Response{{Contestant: "Alice", Text: "What is Utah?"}}, nil},
+		{"unreadable wager", []pageFinalResponse{{Nickname: "Alice", Correct: true, Text: "What is Utah?", Wager: "everything"}},
+			[]Response{{Contestant: "Alice", Correct: true, Text: "What is Utah?"}}, []string{warnWager}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := fixturePage{Rounds: []pageRound{{Code: "FJ", Final: tt.responses}}}
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(page.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			responses, _, warnings := parseResponses(doc.Find("table.final_round td.clue").First(), roundFinalJeopardy)
+			if !reflect.DeepEqual(responses, tt.want) {
+				t.Errorf("responses = %+v, want %+v", responses, tt.want)
+			}
+			if got := warningCodes(warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("warnings = %v, want codes %v", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestParseRoundScores(t *testing.T) {
+	tests := []struct {
+		name         string
+		page         string
+		want         []RoundScore
+		wantWarnings []string
+	}{
+		{"every round", fixturePage{Scores: []pageScores{
+			{Heading: "Scores at the end of the Jeopardy! Round:", Scores: scores(1000, -200, 0)},
+			{Heading: "Scores at the end of the Double Jeopardy! Round:", Scores: scores(5000, 3000, 1200)},
+			{Heading: "Final scores:", Scores: scores(8000, 0, 2400)},
+			{Heading: "Game dynamics:"},
+		}}.String(), []RoundScore{
+			{Round: roundJeopardy, Scores: scores(1000, -200, 0)},
+			{Round: roundDoubleJeopardy, Scores: scores(5000, 3000, 1200)},
+			{Round: roundFinalJeopardy, Scores: scores(8000, 0, 2400)},
+		}, nil},
+		{"no score tables", fixturePage{}.String(), nil, nil},
+		{"missing score", `<html><body><h3>Final scores:</h3><table><tr>
+			<td class="score_player_nickname">Alice</td><td class="score_player_nickname">Bob</td></tr>
+			<tr><td class="score_positive">$1,000</td></tr></table></body></html>`, nil, []string{warnScore}},
+		{"unreadable score", `<html><body><h3>Final scores:</h3><table><tr>
+			<td class="score_player_nickname">Alice</td></tr>
+			<tr><td class="score_positive">lots</td></tr></table></body></html>`,
+			[]RoundScore{{Round: roundFinalJeopardy, Scores: []ContestantScore{{Nickname: "Alice"}}}}, []string{warnScore}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.page))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, warnings := parseRoundScores(doc)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRoundScores = %+v, want %+v", got, tt.want)
+			}
+			if codes := warningCodes(warnings); !reflect.DeepEqual(codes, tt.wantWarnings) {
+				t.Errorf("warnings = %v, want codes %v", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestFillDailyDoubleValues(t *testing.T) {
+	round := Round{Name: roundJeopardy, Clues: []Clue{
+		{Position: "J_1_3", Value: "$600"},
+		{Position: "J_2_3", DailyDouble: true, Wager: 1000},
+		{Position: "J_3_4", DailyDouble: true, Wager: 500},
+		{Position: "J_4_1", DailyDouble: true, Value: "$200", Wager: 300},
+	}}
+	fillDailyDoubleValues(&round)
+
+	var got []string
+	for _, clue := range round.Clues {
+		got = append(got, clue.Value)
+	}
+	// The $800 row has no other revealed clue to take the value from
+	if want := []string{"$600", "$600", "", "$200"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("values = %q, want %q", got, want)
+	}
+}
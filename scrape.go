@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// processGame handles a single game, reporting a failure at any stage as a
+// *gameError. A game whose fetch is aborted by cancelling the context is
+// dropped without a failure so the next run picks it up again.
+func processGame(ctx context.Context, pages *fetcher, gameID int, seasonID string, wg *sync.WaitGroup, results chan<- GameData, errors chan<- *gameError) {
+	defer wg.Done()
+
+	// Recover from panics
+	defer func() {
+		if r := recover(); r != nil {
+			errors <- &gameError{GameID: gameID, SeasonID: seasonID, Stage: failurePanic, Err: fmt.Errorf("%v", r)}
+		}
+	}()
+
+	gameData, err := pages.Game(ctx, gameID, seasonID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		errors <- &gameError{GameID: gameID, SeasonID: seasonID, Stage: failureFetch, Err: err}
+		return
+	}
+	game, warnings, err := parseGameTableData(gameData)
+	if err != nil {
+		errors <- &gameError{GameID: gameID, SeasonID: seasonID, Stage: failureParse, Err: err}
+		return
+	}
+	game.ID = gameID
+	game.Warnings = warnings
+	for _, warning := range warnings {
+		log.Printf("Game %d: %s", gameID, warning)
+	}
+
+	results <- game
+}
+
+func readSeasonsFile(filename string) ([]string, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Split content by newlines and filter empty lines
+	var seasons []string
+	for _, season := range strings.Split(string(content), "\n") {
+		if trimmed := strings.TrimSpace(season); trimmed != "" {
+			seasons = append(seasons, trimmed)
+		}
+	}
+	return seasons, nil
+}
+
+// scrapeSeasonList returns the seasons in the seasons file, or every season
+// J-Archive lists if there is no seasons file, and the current season, the
+// newest one J-Archive lists
+func scrapeSeasonList(ctx context.Context, cfg Config, pages *fetcher) (seasons []string, current string, err error) {
+	seasons, err = readSeasonsFile(cfg.SeasonsFile)
+	fromFile := err == nil
+	if fromFile && len(seasons) == 0 {
+		return nil, "", fmt.Errorf("%s lists no seasons", cfg.SeasonsFile)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Error reading %s: %v. Falling back to web scraping.", cfg.SeasonsFile, err)
+	}
+
+	seasonListHTML, err := pages.SeasonList(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	listed, err := GetSeasonList(seasonListHTML)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse season list: %v", err)
+	}
+	if len(listed) == 0 {
+		return nil, "", fmt.Errorf("the J-Archive season list has no seasons")
+	}
+	current = newestFirst(listed)[0]
+
+	if fromFile {
+		fmt.Printf("Using seasons from %s\n", cfg.SeasonsFile)
+		return seasons, current, nil
+	}
+	return listed, current, nil
+}
+
+// scrapeGames fetches, parses and stores games of one season and returns
+// the IDs of the games whose rows were committed. Once the context is
+// cancelled no more games are started; the ones already parsed are still
+// stored and the state is saved before it returns. The outcome is counted
+// in the run ledger.
+func scrapeGames(ctx context.Context, cfg Config, store PipelineStore, pages *fetcher, state *ProcessingState, run *runLedger, seasonID string, gameIDs []int) []int {
+	var seasonData SeasonData
+	seasonData.ID = seasonID
+
+	// Create channels for results and errors
+	results := make(chan GameData, len(gameIDs))
+	errors := make(chan *gameError, len(gameIDs))
+
+	// Process games concurrently with worker pool
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, cfg.Concurrency)
+
+launch:
+	for _, gameID := range gameIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case semaphore <- struct{}{}: // Acquire semaphore
+		case <-ctx.Done():
+			break launch
+		}
+		wg.Add(1)
+
+		go func(gID int) {
+			processGame(ctx, pages, gID, seasonID, &wg, results, errors)
+			<-semaphore // Release semaphore
+		}(gameID)
+	}
+
+	// Start a goroutine to close channels when all games are processed
+	go func() {
+		wg.Wait()
+		close(results)
+		close(errors)
+	}()
+
+	// Collect results and errors
+	var processedGames []GameData
+	var failures []*gameError
+
+	// Process results as they come in
+	for game := range results {
+		processedGames = append(processedGames, game)
+
+		// Print progress
+		fmt.Printf("\rProcessed %d/%d games", len(processedGames), len(gameIDs))
+	}
+	// The results channel closes after every game has finished, so all errors are buffered
+	for failure := range errors {
+		failures = append(failures, failure)
+	}
+
+	seasonData.Games = processedGames
+
+	// Write season data to database if we have processed games
+	if len(seasonData.Games) > 0 {
+		storeFailures, rest := gameErrors(store.SaveSeason(seasonData))
+		failures = append(failures, storeFailures...)
+		for _, err := range rest {
+			log.Printf("\nError writing season %s: %v", seasonID, err)
+		}
+		if len(rest) > 0 {
+			// The season was not written, so none of its games are stored
+			for _, game := range seasonData.Games {
+				failures = append(failures, &gameError{GameID: game.ID, SeasonID: seasonID, Stage: failureStore, Err: rest[0]})
+			}
+		}
+	}
+
+	failed := make(map[int]bool, len(failures))
+	failedGames := make([]int, 0, len(failures))
+	for _, failure := range failures {
+		failed[failure.GameID] = true
+		failedGames = append(failedGames, failure.GameID)
+	}
+	var storedGames []int
+	warnings := 0
+	for _, game := range processedGames {
+		if failed[game.ID] {
+			continue
+		}
+		storedGames = append(storedGames, game.ID)
+		warnings += len(game.Warnings)
+		if !containsInt(state.SeasonProgress[seasonID], game.ID) {
+			state.SeasonProgress[seasonID] = append(state.SeasonProgress[seasonID], game.ID)
+		}
+	}
+
+	if err := store.RecordFailures(failures); err != nil {
+		log.Printf("\nError recording failed games for season %s: %v", seasonID, err)
+	}
+	if err := store.ClearFailures(storedGames); err != nil {
+		log.Printf("\nError clearing failed games for season %s: %v", seasonID, err)
+	}
+
+	// Failures from earlier runs stand for the games that were not tried now
+	for _, gameID := range state.FailedGames[seasonID] {
+		if !containsInt(gameIDs, gameID) {
+			failedGames = append(failedGames, gameID)
+		}
+	}
+	sort.Ints(failedGames)
+	state.FailedGames[seasonID] = failedGames
+	if err := saveProcessingState(*state, cfg.StateFile); err != nil {
+		log.Printf("\nError saving final state for season %s: %v", seasonID, err)
+	}
+
+	run.seasonDone(seasonID, len(storedGames), warnings, len(failures))
+
+	fmt.Printf("\nSeason %s: Successfully processed %d games, Failed %d games\n",
+		seasonID, len(storedGames), len(failures))
+	printFailures(failures)
+	if ctx.Err() != nil {
+		fmt.Printf("Interrupted: %d games of season %s left for the next run\n",
+			len(gameIDs)-len(storedGames)-len(failures), seasonID)
+	}
+	return storedGames
+}
+
+// containsInt reports whether values contains value
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// runScrape implements the scrape command. With no targets it fetches
+// every season in the seasons file, or every season J-Archive lists;
+// targets pick games by game ID, show number, air date or recency instead.
+//
+//	scrape [-game ID,...] [-shows N[-M]] [-since DATE] [-until DATE] [-latest N] [-refresh-catalog]
+func runScrape(ctx context.Context, cfg Config, args []string) {
+	flags := flag.NewFlagSet("scrape", flag.ExitOnError)
+	var targets scrapeTargets
+	targets.register(flags)
+	refresh := flags.Bool("refresh-catalog", false, "fetch every season page again instead of using the stored catalog")
+	flags.Parse(args)
+	if err := targets.validate(); err != nil {
+		log.Fatalf("Invalid target: %v", err)
+	}
+
+	lock := lockRun(cfg)
+	defer lock.Release()
+	store := openStore(cfg)
+	defer store.Close()
+	run := startRunLedger(store, "scrape", args)
+	defer run.finish(ctx)
+	pages := newFetcher(cfg, run)
+
+	// Load or initialize processing state
+	state, err := loadProcessingState(cfg.StateFile)
+	if err != nil {
+		run.fatalf("Failed to load processing state: %v", err)
+	}
+
+	seasonsList, current, err := scrapeSeasonList(ctx, cfg, pages)
+	if err != nil {
+		run.fatalf("%v", err)
+	}
+	catalog, err := newSeasonCatalog(store, pages, seasonsList, current)
+	if err != nil {
+		run.fatalf("%v", err)
+	}
+
+	if !targets.empty() {
+		scrapeTargetedGames(ctx, cfg, store, catalog, &state, run, targets, *refresh)
+		return
+	}
+	if err := scrapeSeasons(ctx, cfg, store, catalog, &state, run, seasonsList); err != nil {
+		run.fatalf("%v", err)
+	}
+}
+
+// scrapeSeasons scrapes the games of each season that are not stored yet,
+// skipping seasons recorded as complete
+func scrapeSeasons(ctx context.Context, cfg Config, store PipelineStore, catalog *seasonCatalog, state *ProcessingState, run *runLedger, seasonsList []string) error {
+	// Resume from what the database holds rather than the state file
+	completed, err := store.CompletedSeasons()
+	if err != nil {
+		return fmt.Errorf("failed to read completed seasons: %v", err)
+	}
+	stored, err := store.ParserVersions()
+	if err != nil {
+		return fmt.Errorf("failed to read stored games: %v", err)
+	}
+	// The current season keeps gaining games, so it is never complete
+	current := catalog.current
+
+	fmt.Printf("Found %d seasons to process\n", len(seasonsList))
+
+	// Process each season
+	for _, seasonID := range seasonsList {
+		if ctx.Err() != nil {
+			break
+		}
+		if completed[seasonID] && seasonID != current {
+			fmt.Printf("Skipping completed season %s\n", seasonID)
+			continue
+		}
+
+		fmt.Printf("\nProcessing Season: %s\n", seasonID)
+		games, err := catalog.fetch(ctx, seasonID)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+
+		var pending []int
+		for _, game := range games {
+			if _, ok := stored[game.GameID]; !ok {
+				pending = append(pending, game.GameID)
+			}
+		}
+		fmt.Printf("Found %d games in Season %s, %d not stored yet\n", len(games), seasonID, len(pending))
+
+		var done []int
+		if len(pending) > 0 {
+			done = scrapeGames(ctx, cfg, store, catalog.pages, state, run, seasonID, pending)
+		}
+		// A season is complete once every game it lists has been committed
+		if len(games) > 0 && len(done) == len(pending) && seasonID != current {
+			if err := store.CompleteSeason(seasonID, len(games)); err != nil {
+				log.Printf("Error recording season %s as complete: %v", seasonID, err)
+			}
+			state.LastCompletedSeason = seasonID
+			if err := saveProcessingState(*state, cfg.StateFile); err != nil {
+				log.Printf("\nError saving final state for season %s: %v", seasonID, err)
+			}
+		}
+
+		// Optional delay between seasons to be nice to the server
+		select {
+		case <-time.After(time.Duration(cfg.SeasonDelay)):
+		case <-ctx.Done():
+		}
+	}
+
+	if ctx.Err() != nil {
+		fmt.Println("\nInterrupted, run scrape again to resume")
+		return nil
+	}
+	fmt.Println("\nFinished processing all seasons")
+	return nil
+}
+
+// scrapeTargetedGames resolves targets through the season catalog and runs
+// the selected games through the normal pipeline, season by season. Games
+// are fetched again even if an earlier scrape stored them.
+func scrapeTargetedGames(ctx context.Context, cfg Config, store PipelineStore, catalog *seasonCatalog, state *ProcessingState, run *runLedger, targets scrapeTargets, refresh bool) {
+	selected, missing := resolveTargets(ctx, catalog, targets, refresh)
+	for _, gameID := range missing {
+		log.Printf("Game %d is not listed in any season", gameID)
+	}
+	if len(selected) == 0 {
+		fmt.Println("No games match the targets")
+		return
+	}
+
+	bySeason := make(map[string][]int)
+	var seasons []string
+	for _, game := range selected {
+		if _, ok := bySeason[game.SeasonID]; !ok {
+			seasons = append(seasons, game.SeasonID)
+		}
+		bySeason[game.SeasonID] = append(bySeason[game.SeasonID], game.GameID)
+	}
+
+	fmt.Printf("Scraping %d games from %d seasons\n", len(selected), len(seasons))
+	for _, seasonID := range seasons {
+		if ctx.Err() != nil {
+			break
+		}
+		fmt.Printf("\nProcessing Season: %s (%d games)\n", seasonID, len(bySeason[seasonID]))
+		scrapeGames(ctx, cfg, store, catalog.pages, state, run, seasonID, bySeason[seasonID])
+	}
+	if ctx.Err() != nil {
+		fmt.Println("\nInterrupted before every targeted game was scraped")
+		return
+	}
+	fmt.Println("\nFinished processing targeted games")
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testGame is a game served by testArchive
+type testGame struct {
+	ID      int
+	ShowNum int
+	AirDate string
+}
+
+// testArchive serves season lists, season pages and game pages in the
+// layout J-Archive uses, counting the requests for each page
+type testArchive struct {
+	seasons map[string][]testGame // newest season first in order
+	order   []string
+
+	mu      sync.Mutex
+	hits    map[string]int
+	failing map[int]bool
+}
+
+func newTestArchive(t *testing.T, order []string, seasons map[string][]testGame) (*testArchive, *httptest.Server) {
+	t.Helper()
+	archive := &testArchive{seasons: seasons, order: order, hits: make(map[string]int), failing: make(map[int]bool)}
+	server := httptest.NewServer(archive)
+	t.Cleanup(server.Close)
+	return archive, server
+}
+
+func (a *testArchive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	a.hits[r.URL.RequestURI()]++
+	a.mu.Unlock()
+
+	var page strings.Builder
+	switch r.URL.Path {
+	case "/listseasons.php":
+		for _, seasonID := range a.order {
+			fmt.Fprintf(&page, `<tr><td><a href="showseason.php?season=%s">Season %s</a></td></tr>`, seasonID, seasonID)
+		}
+	case "/showseason.php":
+		games, ok := a.seasons[r.URL.Query().Get("season")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		for _, game := range games {
+			fmt.Fprintf(&page, `<tr><td><a href="showgame.php?game_id=%d">#%d, aired&nbsp;%s</a></td></tr>`, game.ID, game.ShowNum, game.AirDate)
+		}
+	case "/showgame.php":
+		var gameID int
+		fmt.Sscan(r.URL.Query().Get("game_id"), &gameID)
+		a.mu.Lock()
+		failing := a.failing[gameID]
+		a.mu.Unlock()
+		game, ok := a.game(gameID)
+		if !ok || failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		page.WriteString(testGamePage(game))
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	fmt.Fprintf(w, "<html><body><table>%s</table></body></html>", page.String())
+}
+
+func (a *testArchive) game(gameID int) (testGame, bool) {
+	for _, games := range a.seasons {
+		for _, game := range games {
+			if game.ID == gameID {
+				return game, true
+			}
+		}
+	}
+	return testGame{}, false
+}
+
+func (a *testArchive) requests(uri string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.hits[uri]
+}
+
+func (a *testArchive) setFailing(gameID int, failing bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.failing[gameID] = failing
+}
+
+// testGamePage is a one-category game page with a single revealed clue
+func testGamePage(game testGame) string {
+	return fmt.Sprintf(`</table><title>J! Archive - Show #%[1]d, aired %[2]s</title>
+<div id="game_title"><h1>Show #%[1]d - %[2]s</h1></div>
+<div id="game_comments"></div>
+<table id="contestants_table"><tr><td><p class="contestants"><a href="showplayer.php?player_id=1">Alice Smith</a>, a teacher</p></td></tr></table>
+<div id="jeopardy_round"><table class="round">
+<tr><td class="category"><table><tr><td class="category_name">CATEGORY</td></tr></table></td></tr>
+<tr><td class="clue"><table>
+<tr><td class="clue_header"><table><tr><td class="clue_value">$200</td><td class="clue_order_number"><a>1</a></td></tr></table></td></tr>
+<tr><td id="clue_J_1_1" class="clue_text">Clue of game %[3]d</td></tr>
+<tr><td id="clue_J_1_1_r" class="clue_text"><em class="correct_response">answer</em><table><tr><td class="right">Alice</td></tr></table></td></tr>
+</table></td></tr>
+</table></div><table>`, game.ShowNum, game.AirDate, game.ID)
+}
+
+// testConfig points the pipeline at server with no delays and a temporary data directory
+func testConfig(t *testing.T, server *httptest.Server) Config {
+	dir := t.TempDir()
+	return Config{
+		DB:          filepath.Join(dir, "jeopardy.db"),
+		DataDir:     filepath.Join(dir, "data"),
+		StateFile:   filepath.Join(dir, "processing_state.json"),
+		SeasonsFile: filepath.Join(dir, "seasons.txt"),
+		Concurrency: 2,
+		BaseURL:     server.URL,
+	}
+}
+
+// scrapeOnce runs a whole-season scrape of the seasons listed in the seasons file into store
+func scrapeOnce(t *testing.T, cfg Config, store PipelineStore, seasons []string) {
+	t.Helper()
+	if err := os.WriteFile(cfg.SeasonsFile, []byte(strings.Join(seasons, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state, err := loadProcessingState(cfg.StateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := startRunLedger(store, "scrape", nil)
+	pages := newFetcher(cfg, run)
+	seasonsList, current, err := scrapeSeasonList(context.Background(), cfg, pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog, err := newSeasonCatalog(store, pages, seasonsList, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := scrapeSeasons(context.Background(), cfg, store, catalog, &state, run, seasonsList); err != nil {
+		t.Fatalf("scrapeSeasons: %v", err)
+	}
+	run.finish(context.Background())
+}
+
+func storedGameIDs(t *testing.T, store PipelineStore) []int {
+	t.Helper()
+	versions, err := store.ParserVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for id := range versions {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+var testSeasons = map[string][]testGame{
+	"36": {{ID: 3, ShowNum: 8003, AirDate: "2020-01-01"}},
+	"35": {{ID: 1, ShowNum: 8001, AirDate: "2019-05-01"}, {ID: 2, ShowNum: 8002, AirDate: "2019-05-02"}},
+}
+
+func TestScrapeSeasons(t *testing.T) {
+	archive, server := newTestArchive(t, []string{"36", "35"}, testSeasons)
+	cfg := testConfig(t, server)
+	store := newMemoryStore()
+
+	scrapeOnce(t, cfg, store, []string{"35", "36"})
+
+	if got, want := storedGameIDs(t, store), []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored games %v, want %v", got, want)
+	}
+	game := store.Season("35").Games[0]
+	if game.ShowNum != 8001 || len(game.Rounds) != 1 || game.Rounds[0].Clues[0].Text != "Clue of game 1" {
+		t.Errorf("stored game 1 = %+v", game)
+	}
+	completed, _ := store.CompletedSeasons()
+	if !reflect.DeepEqual(completed, map[string]bool{"35": true}) {
+		t.Errorf("completed seasons %v, want only 35; 36 is the current season", completed)
+	}
+	catalog, _ := store.Catalog()
+	if len(catalog["35"]) != 2 || len(catalog["36"]) != 1 || catalog["35"][1].ShowNum != 8002 {
+		t.Errorf("catalog %+v", catalog)
+	}
+	runs := store.Runs()
+	if len(runs) != 1 || runs[0].Status != runCompleted || runs[0].GamesStored != 3 || runs[0].GamesFetched != 3 {
+		t.Errorf("runs %+v", runs)
+	}
+
+	// The next run skips the completed season and finds nothing new in the current one
+	scrapeOnce(t, cfg, store, []string{"35", "36"})
+	if n := archive.requests("/showseason.php?season=35"); n != 1 {
+		t.Errorf("season 35 page fetched %d times, want once", n)
+	}
+	if n := archive.requests("/showseason.php?season=36"); n != 2 {
+		t.Errorf("season 36 page fetched %d times, want on every run", n)
+	}
+	for _, gameID := range []int{1, 2, 3} {
+		if n := archive.requests(fmt.Sprintf("/showgame.php?game_id=%d", gameID)); n != 1 {
+			t.Errorf("game %d fetched %d times, want once", gameID, n)
+		}
+	}
+	if runs := store.Runs(); len(runs) != 2 || runs[1].GamesStored != 0 {
+		t.Errorf("second run %+v", runs[len(runs)-1])
+	}
+}
+
+func TestScrapeSeasonsRecordsFailures(t *testing.T) {
+	archive, server := newTestArchive(t, []string{"36", "35"}, testSeasons)
+	cfg := testConfig(t, server)
+	store := newMemoryStore()
+
+	archive.setFailing(2, true)
+	scrapeOnce(t, cfg, store, []string{"35"})
+
+	if got, want := storedGameIDs(t, store), []int{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored games %v, want %v", got, want)
+	}
+	failures, _ := store.Failures()
+	if len(failures) != 1 || failures[0].GameID != 2 || failures[0].Stage != failureFetch || failures[0].Attempts != 1 {
+		t.Errorf("failures %+v", failures)
+	}
+	if completed, _ := store.CompletedSeasons(); completed["35"] {
+		t.Error("season 35 completed with a game missing")
+	}
+	if runs := store.Runs(); runs[0].Failures != 1 || runs[0].GamesStored != 1 {
+		t.Errorf("run %+v", runs[0])
+	}
+
+	// Once the game can be fetched the season completes and the failure is cleared
+	archive.setFailing(2, false)
+	scrapeOnce(t, cfg, store, []string{"35"})
+	if failures, _ := store.Failures(); len(failures) != 0 {
+		t.Errorf("failures %+v after the game was stored", failures)
+	}
+	if completed, _ := store.CompletedSeasons(); !completed["35"] {
+		t.Error("season 35 not completed once every game was stored")
+	}
+	if n := archive.requests("/showgame.php?game_id=1"); n != 1 {
+		t.Errorf("stored game 1 fetched %d times, want once", n)
+	}
+}
+
+func TestScrapeSeasonList(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        *string // nil for no seasons file
+		listed      []string
+		want        []string
+		wantCurrent string
+		wantErr     bool
+	}{
+		{"no seasons file", nil, []string{"36", "35"}, []string{"36", "35"}, "36", false},
+		{"seasons file", ptr("34\n\n 35 \n"), []string{"36", "35", "34"}, []string{"34", "35"}, "36", false},
+		{"current season is the newest listed", ptr("35\n"), []string{"9", "10"}, []string{"35"}, "10", false},
+		{"empty seasons file", ptr("\n  \n"), []string{"36"}, nil, "", true},
+		{"empty season list", nil, nil, nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seasons := make(map[string][]testGame)
+			for _, seasonID := range tt.listed {
+				seasons[seasonID] = nil
+			}
+			_, server := newTestArchive(t, tt.listed, seasons)
+			cfg := testConfig(t, server)
+			if tt.file != nil {
+				if err := os.WriteFile(cfg.SeasonsFile, []byte(*tt.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, current, err := scrapeSeasonList(context.Background(), cfg, newFetcher(cfg, nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("scrapeSeasonList error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) || current != tt.wantCurrent {
+				t.Errorf("scrapeSeasonList = %v current %q, want %v current %q", got, current, tt.want, tt.wantCurrent)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string { return &s }
+
+func TestScrapeTargetedGames(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets scrapeTargets
+		want    []int
+	}{
+		{"game id", scrapeTargets{GameIDs: intList{2}}, []int{2}},
+		{"show range", scrapeTargets{Shows: "8002-8003"}, []int{2, 3}},
+		{"air date", scrapeTargets{Since: "2019-05-02", Until: "2019-12-31"}, []int{2}},
+		{"latest", scrapeTargets{Latest: 1}, []int{3}},
+		{"unlisted game", scrapeTargets{GameIDs: intList{99}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, server := newTestArchive(t, []string{"36", "35"}, testSeasons)
+			cfg := testConfig(t, server)
+			store := newMemoryStore()
+			if err := tt.targets.validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			state, err := loadProcessingState(cfg.StateFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages := newFetcher(cfg, nil)
+			catalog, err := newSeasonCatalog(store, pages, []string{"35", "36"}, "36")
+			if err != nil {
+				t.Fatal(err)
+			}
+			scrapeTargetedGames(context.Background(), cfg, store, catalog, &state, nil, tt.targets, false)
+
+			if got := storedGameIDs(t, store); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stored games %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
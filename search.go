@@ -0,0 +1,420 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The full-text index needs SQLite built with FTS5, which go-sqlite3 only
+// includes with the sqlite_fts5 build tag. Without it search falls back to
+// scanning the clues with LIKE, which is slower and does not rank results.
+const searchTable = "clue_search"
+
+// searchStaleSetting is set when games were written by a binary without
+// FTS5 while the index existed, so the index must be rebuilt
+const searchStaleSetting = "search_index_stale"
+
+// searchAvailable reports whether the linked SQLite supports FTS5
+func searchAvailable(q queryer) (bool, error) {
+	var used bool
+	err := q.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5');`).Scan(&used)
+	return used, err
+}
+
+// ensureSearchIndex creates the full-text index over clue text, correct
+// responses and category names and fills it from the existing rows, or
+// rebuilds it if it went stale. It is a no-op when FTS5 is unavailable.
+func ensureSearchIndex(db *sql.DB) error {
+	available, err := searchAvailable(db)
+	if err != nil || !available {
+		return err
+	}
+
+	exists, err := tableExists(db, searchTable)
+	if err != nil {
+		return err
+	}
+	stale, err := readSetting(db, searchStaleSetting)
+	if err != nil {
+		return err
+	}
+	if exists && stale == "" {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if exists {
+		log.Println("Rebuilding stale search index")
+		if _, err := tx.Exec(`DELETE FROM clue_search;`); err != nil {
+			return err
+		}
+	} else {
+		// remove_diacritics folds "Pokémon" and "Pokemon" to the same token
+		if _, err := tx.Exec(`
+			CREATE VIRTUAL TABLE clue_search USING fts5 (
+				clue_id UNINDEXED,
+				game_id UNINDEXED,
+				text,
+				correct_response,
+				category,
+				tokenize = 'unicode61 remove_diacritics 2'
+			);
+		`); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO clue_search (clue_id, game_id, text, correct_response, category)
+		SELECT c.clue_id, c.game_id, c.text, c.correct_response, k.name
+		FROM clues c
+		LEFT JOIN categories k ON k.category_id = c.category_id;
+	`); err != nil {
+		return err
+	}
+	if err := writeSetting(tx, searchStaleSetting, ""); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// prepareSearchIndexForWrite reports whether a write should also update the
+// search index. If the index exists but this binary cannot open it, the index
+// is marked stale so an FTS5 build rebuilds it on startup.
+func prepareSearchIndexForWrite(tx *sql.Tx) (bool, error) {
+	exists, err := tableExists(tx, searchTable)
+	if err != nil || !exists {
+		return false, err
+	}
+	available, err := searchAvailable(tx)
+	if err != nil {
+		return false, err
+	}
+	if !available {
+		return false, writeSetting(tx, searchStaleSetting, "1")
+	}
+	return true, nil
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// readSetting returns a value from the settings table, or "" if it is not set
+func readSetting(q queryer, key string) (string, error) {
+	var value string
+	err := q.QueryRow(`SELECT value FROM settings WHERE key = ?;`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+func writeSetting(e execer, key, value string) error {
+	_, err := e.Exec(`
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value;`, key, value)
+	return err
+}
+
+func tableExists(q queryer, name string) (bool, error) {
+	var count int
+	err := q.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = ?;`, name).Scan(&count)
+	return count > 0, err
+}
+
+// searchFilters narrows full-text results
+type searchFilters struct {
+	Season   string
+	Round    string // round code: J, DJ, FJ or TB
+	MinValue int
+	MaxValue int
+	From     string
+	To       string
+	Limit    int
+}
+
+// searchResult is one matching clue with highlighted snippets
+type searchResult struct {
+	ClueID   string `json:"clue_id"`
+	AirDate  string `json:"air_date"`
+	SeasonID string `json:"season_id"`
+	Round    string `json:"round"`
+	Value    int    `json:"value,omitempty"` // 0 when the clue has no value
+	Category string `json:"category"`
+	Text     string `json:"text"`
+	Response string `json:"response"`
+}
+
+// searchClues finds clues whose text, correct response or category match
+// every word and quoted phrase in the query. It uses the full-text index when
+// this binary has FTS5 and scans the clues otherwise.
+func searchClues(db *sql.DB, query string, filters searchFilters, highlightStart, highlightEnd string) ([]searchResult, error) {
+	// The index may have been built by a binary compiled with FTS5
+	available, err := searchAvailable(db)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := tableExists(db, searchTable)
+	if err != nil {
+		return nil, err
+	}
+	if !available || !exists {
+		return scanClues(db, query, filters, highlightStart, highlightEnd)
+	}
+
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	sqlQuery := `
+		SELECT c.clue_id, COALESCE(g.air_date, ''), g.season_id, r.name, c.value,
+			highlight(clue_search, 4, ?, ?),
+			snippet(clue_search, 2, ?, ?, '…', 24),
+			snippet(clue_search, 3, ?, ?, '…', 12)
+		FROM clue_search s
+		JOIN clues c ON c.clue_id = s.clue_id
+		JOIN games g ON g.game_id = c.game_id
+		JOIN rounds r ON r.round_id = c.round_id
+		WHERE clue_search MATCH ?`
+	args := []interface{}{
+		highlightStart, highlightEnd,
+		highlightStart, highlightEnd,
+		highlightStart, highlightEnd,
+		match,
+	}
+	where, filterArgs := filters.where()
+	sqlQuery += where + ` ORDER BY rank LIMIT ?;`
+	args = append(append(args, filterArgs...), filters.Limit)
+	return querySearchResults(db, sqlQuery, args...)
+}
+
+// where returns the conditions for the filters, each starting with AND
+func (filters searchFilters) where() (string, []interface{}) {
+	var where string
+	var args []interface{}
+	if filters.Season != "" {
+		where += ` AND g.season_id = ?`
+		args = append(args, filters.Season)
+	}
+	if filters.Round != "" {
+		where += ` AND r.round_code = ?`
+		args = append(args, strings.ToUpper(filters.Round))
+	}
+	if filters.MinValue > 0 {
+		where += ` AND c.value >= ?`
+		args = append(args, filters.MinValue)
+	}
+	if filters.MaxValue > 0 {
+		where += ` AND c.value <= ?`
+		args = append(args, filters.MaxValue)
+	}
+	if filters.From != "" {
+		where += ` AND g.air_date >= ?`
+		args = append(args, filters.From)
+	}
+	if filters.To != "" {
+		where += ` AND g.air_date <= ?`
+		args = append(args, filters.To)
+	}
+	return where, args
+}
+
+func querySearchResults(db *sql.DB, sqlQuery string, args ...interface{}) ([]searchResult, error) {
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []searchResult
+	for rows.Next() {
+		var result searchResult
+		var value sql.NullInt64
+		var category, text, response sql.NullString
+		if err := rows.Scan(&result.ClueID, &result.AirDate, &result.SeasonID, &result.Round, &value,
+			&category, &text, &response); err != nil {
+			return nil, err
+		}
+		result.Value = int(value.Int64)
+		result.Category = category.String
+		result.Text = text.String
+		result.Response = response.String
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// searchTermRegex splits a query into quoted phrases and single words
+var searchTermRegex = regexp.MustCompile(`"([^"]*)"|(\S+)`)
+
+// searchTerms returns the phrases and words of a query in lower case. FTS5
+// operators have no meaning without the index, so AND is dropped and every
+// other term must match.
+func searchTerms(query string) []string {
+	var terms []string
+	for _, match := range searchTermRegex.FindAllStringSubmatch(query, -1) {
+		term := match[1]
+		if match[2] != "" {
+			if match[2] == "AND" {
+				continue
+			}
+			term = strings.Trim(match[2], `"*`)
+		}
+		if term = normalizeText(strings.ToLower(term)); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// ftsQuery turns a query into quoted FTS5 phrases so that input such as
+// AT&T, don't or an unbalanced quote is searched for rather than read as FTS5
+// syntax. As in searchTerms AND is dropped and every other term must match. A
+// word ending in * still matches as a prefix.
+func ftsQuery(query string) string {
+	var phrases []string
+	for _, match := range searchTermRegex.FindAllStringSubmatch(query, -1) {
+		term, prefix := match[1], false
+		if match[2] != "" {
+			if match[2] == "AND" {
+				continue
+			}
+			prefix = strings.HasSuffix(match[2], "*")
+			term = strings.Trim(match[2], `"*`)
+		}
+		if strings.TrimSpace(term) == "" {
+			continue
+		}
+		phrase := `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+		if prefix {
+			phrase += "*"
+		}
+		phrases = append(phrases, phrase)
+	}
+	return strings.Join(phrases, " ")
+}
+
+// likePattern matches text containing term anywhere, escaping LIKE wildcards
+func likePattern(term string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+	return "%" + escaped + "%"
+}
+
+// scanClues searches without the full-text index. Every term must appear in
+// the clue text, correct response or category, newest games first. Like
+// SQLite's LIKE it ignores case for ASCII letters only.
+func scanClues(db *sql.DB, query string, filters searchFilters, highlightStart, highlightEnd string) ([]searchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	sqlQuery := `
+		SELECT c.clue_id, COALESCE(g.air_date, ''), g.season_id, r.name, c.value, k.name, c.text, c.correct_response
+		FROM clues c
+		JOIN games g ON g.game_id = c.game_id
+		JOIN rounds r ON r.round_id = c.round_id
+		LEFT JOIN categories k ON k.category_id = c.category_id
+		WHERE 1 = 1`
+	var args []interface{}
+	for _, term := range terms {
+		sqlQuery += ` AND (c.text LIKE ? ESCAPE '\' OR c.correct_response LIKE ? ESCAPE '\' OR k.name LIKE ? ESCAPE '\')`
+		pattern := likePattern(term)
+		args = append(args, pattern, pattern, pattern)
+	}
+	where, filterArgs := filters.where()
+	sqlQuery += where + ` ORDER BY g.air_date DESC, c.clue_id LIMIT ?;`
+	args = append(append(args, filterArgs...), filters.Limit)
+
+	results, err := querySearchResults(db, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Category = highlightTerms(results[i].Category, terms, highlightStart, highlightEnd)
+		results[i].Text = highlightTerms(results[i].Text, terms, highlightStart, highlightEnd)
+		results[i].Response = highlightTerms(results[i].Response, terms, highlightStart, highlightEnd)
+	}
+	return results, nil
+}
+
+// highlightTerms wraps every case-insensitive occurrence of the terms in text
+func highlightTerms(text string, terms []string, start, end string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	// Longer terms first so a phrase wins over a word inside it
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	regex := regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+	return regex.ReplaceAllStringFunc(text, func(match string) string {
+		return start + match + end
+	})
+}
+
+// runSearch implements the search command
+//
+//	search [-season S] [-round J|DJ|FJ|TB] [-value N] [-min-value N] [-max-value N] [-from DATE] [-to DATE] [-limit N] query
+func runSearch(db *sql.DB, args []string) {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	var filters searchFilters
+	var value int
+	flags.StringVar(&filters.Season, "season", "", "only search this season")
+	flags.StringVar(&filters.Round, "round", "", "only search this round (J, DJ, FJ or TB)")
+	flags.IntVar(&value, "value", 0, "only clues with exactly this value")
+	flags.IntVar(&filters.MinValue, "min-value", 0, "only clues worth at least this much")
+	flags.IntVar(&filters.MaxValue, "max-value", 0, "only clues worth at most this much")
+	flags.StringVar(&filters.From, "from", "", "only games aired on or after this date (YYYY-MM-DD)")
+	flags.StringVar(&filters.To, "to", "", "only games aired on or before this date (YYYY-MM-DD)")
+	flags.IntVar(&filters.Limit, "limit", 20, "maximum number of results")
+	flags.Parse(args)
+
+	if value > 0 {
+		filters.MinValue, filters.MaxValue = value, value
+	}
+
+	query := strings.Join(flags.Args(), " ")
+	if query == "" {
+		log.Fatal(`Usage: search [flags] <query>, e.g. search -season 40 '"holy roman" empire'`)
+	}
+
+	// Bold matches on a terminal, bracket them when output is redirected
+	highlightStart, highlightEnd := "[", "]"
+	if info, err := os.Stdout.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		highlightStart, highlightEnd = "\033[1m", "\033[0m"
+	}
+
+	results, err := searchClues(db, query, filters, highlightStart, highlightEnd)
+	if err != nil {
+		log.Fatalf("Search failed: %v", err)
+	}
+
+	for _, result := range results {
+		value := ""
+		if result.Value > 0 {
+			value = fmt.Sprintf("$%d", result.Value)
+		}
+		fmt.Printf("%s  season %s  %s %s  %s  (%s)\n", result.AirDate, result.SeasonID, result.Round, value, result.Category, result.ClueID)
+		fmt.Printf("    %s\n", result.Text)
+		fmt.Printf("    -> %s\n\n", result.Response)
+	}
+	fmt.Printf("%d results\n", len(results))
+}
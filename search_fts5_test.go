@@ -0,0 +1,174 @@
+//go:build sqlite_fts5
+
+package main
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+// searchGames are the games the full-text tests search
+var searchGames = []GameData{
+	{ID: 1, ShowNum: 100, AirDate: "2019-05-01", Rounds: []Round{{
+		Name:       roundJeopardy,
+		Categories: []Category{{Name: "HISTORY"}, {Name: "COMPANIES"}, {Name: "VIDEO GAMES"}},
+		Clues: []Clue{
+			{Position: "J_1_1", Value: "$200", Text: "Voltaire said it was neither holy, nor Roman, nor an empire", CorrectResponse: "the Holy Roman Empire"},
+			{Position: "J_2_1", Value: "$200", Text: "Ma Bell was the nickname of this company", CorrectResponse: "AT&T"},
+			{Position: "J_3_1", Value: "$200", Text: "Gotta catch 'em all in this franchise", CorrectResponse: "Pokémon"},
+			{Position: "J_1_2", Value: "$400", Text: "Don't forget this empire, the empire on which the sun never set, was an empire", CorrectResponse: "the British Empire"},
+		},
+	}}},
+	{ID: 2, ShowNum: 200, AirDate: "2020-05-01", Rounds: []Round{{
+		Name:       roundDoubleJeopardy,
+		Categories: []Category{{Name: "EMPIRES"}},
+		Clues:      []Clue{{Position: "DJ_1_1", Value: "$800", Text: "Its capital was Constantinople", CorrectResponse: "the Byzantine Empire"}},
+	}}},
+}
+
+// openSearchDatabase returns a migrated database holding games, indexed for search
+func openSearchDatabase(t *testing.T, games []GameData) *sql.DB {
+	t.Helper()
+	db := openTestDatabase(t)
+	if err := migrateDatabase(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writeGameBatch(db, "35", games); err != nil {
+		t.Fatal(err)
+	}
+	// The index is filled from the rows written before it existed
+	if err := ensureSearchIndex(db); err != nil {
+		t.Fatalf("ensureSearchIndex: %v", err)
+	}
+	return db
+}
+
+// searchIDs runs a search and returns the clue IDs found
+func searchIDs(t *testing.T, db *sql.DB, query string, filters searchFilters) []string {
+	t.Helper()
+	if filters.Limit == 0 {
+		filters.Limit = 10
+	}
+	results, err := searchClues(db, query, filters, "[", "]")
+	if err != nil {
+		t.Fatalf("searchClues(%q): %v", query, err)
+	}
+	var ids []string
+	for _, result := range results {
+		ids = append(ids, result.ClueID)
+	}
+	return ids
+}
+
+func TestSearchIndexAvailable(t *testing.T) {
+	db := openTestDatabase(t)
+	available, err := searchAvailable(db)
+	if err != nil || !available {
+		t.Fatalf("searchAvailable = %v, %v; built with sqlite_fts5 it must be true", available, err)
+	}
+}
+
+func TestSearchClues(t *testing.T) {
+	db := openSearchDatabase(t, searchGames)
+	tests := []struct {
+		name    string
+		query   string
+		filters searchFilters
+		want    []string
+	}{
+		{"ranked by relevance", "empire", searchFilters{}, []string{"1-J_1_2", "1-J_1_1", "2-DJ_1_1"}},
+		{"phrase", `"holy roman"`, searchFilters{}, []string{"1-J_1_1"}},
+		{"every term must match", "empire constantinople", searchFilters{}, []string{"2-DJ_1_1"}},
+		{"AND is implied", "empire AND constantinople", searchFilters{}, []string{"2-DJ_1_1"}},
+		{"category", "companies", searchFilters{}, []string{"1-J_2_1"}},
+		{"diacritics folded", "pokemon", searchFilters{}, []string{"1-J_3_1"}},
+		{"diacritics kept", "pokémon", searchFilters{}, []string{"1-J_3_1"}},
+		{"prefix", "constantin*", searchFilters{}, []string{"2-DJ_1_1"}},
+		{"ampersand", "AT&T", searchFilters{}, []string{"1-J_2_1"}},
+		{"apostrophe", "don't", searchFilters{}, []string{"1-J_1_2"}},
+		{"unbalanced quote", `"byzantine`, searchFilters{}, []string{"2-DJ_1_1"}},
+		{"operator is a word", "empire OR nothing", searchFilters{}, nil},
+		{"parenthesis", "(empire", searchFilters{}, []string{"1-J_1_2", "1-J_1_1", "2-DJ_1_1"}},
+		{"punctuation only", "&", searchFilters{}, nil},
+		{"no terms", `"" AND`, searchFilters{}, nil},
+		{"round filter", "empire", searchFilters{Round: "dj"}, []string{"2-DJ_1_1"}},
+		{"value filter", "empire", searchFilters{MaxValue: 200}, []string{"1-J_1_1"}},
+		{"date filter", "empire", searchFilters{From: "2020-01-01"}, []string{"2-DJ_1_1"}},
+		{"limit", "empire", searchFilters{Limit: 1}, []string{"1-J_1_2"}},
+		{"no match", "zebra", searchFilters{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchIDs(t, db, tt.query, tt.filters); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("searchClues(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	results, err := searchClues(db, "pokemon", searchFilters{Limit: 1}, "[", "]")
+	if err != nil || len(results) != 1 {
+		t.Fatalf("searchClues(pokemon) = %v, %v", results, err)
+	}
+	if results[0].Response != "[Pokémon]" || results[0].Category != "VIDEO GAMES" || results[0].Value != 200 {
+		t.Errorf("searchClues(pokemon) = %+v", results[0])
+	}
+}
+
+func TestSearchIndexFollowsWrites(t *testing.T) {
+	db := openSearchDatabase(t, searchGames[:1])
+
+	// New games are indexed as they are written
+	if _, err := writeGameBatch(db, "36", searchGames[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := searchIDs(t, db, "constantinople", searchFilters{}), []string{"2-DJ_1_1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after writing game 2 found %q, want %q", got, want)
+	}
+
+	// Rewriting a game replaces its entries
+	rewritten := searchGames[1]
+	rewritten.Rounds = []Round{{
+		Name:       roundDoubleJeopardy,
+		Categories: []Category{{Name: "EMPIRES"}},
+		Clues:      []Clue{{Position: "DJ_1_1", Value: "$800", Text: "Its capital was Istanbul", CorrectResponse: "the Ottoman Empire"}},
+	}}
+	if _, err := writeGameBatch(db, "36", []GameData{rewritten}); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchIDs(t, db, "constantinople", searchFilters{}); got != nil {
+		t.Errorf("old text still found: %q", got)
+	}
+	if got, want := searchIDs(t, db, "istanbul", searchFilters{}), []string{"2-DJ_1_1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rewritten text found %q, want %q", got, want)
+	}
+	var entries int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM clue_search WHERE game_id = 2;`).Scan(&entries); err != nil || entries != 1 {
+		t.Errorf("game 2 has %d index entries, want 1: %v", entries, err)
+	}
+}
+
+func TestEnsureSearchIndexRebuildsStaleIndex(t *testing.T) {
+	db := openSearchDatabase(t, searchGames)
+
+	// A binary without FTS5 wrote this game and could not index it
+	if _, err := db.Exec(`DELETE FROM clue_search WHERE game_id = 2;`); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSetting(db, searchStaleSetting, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ensureSearchIndex(db); err != nil {
+		t.Fatalf("ensureSearchIndex: %v", err)
+	}
+	if got, want := searchIDs(t, db, "constantinople", searchFilters{}), []string{"2-DJ_1_1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after rebuilding found %q, want %q", got, want)
+	}
+	var entries int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM clue_search;`).Scan(&entries); err != nil || entries != 5 {
+		t.Errorf("index has %d entries, want 5: %v", entries, err)
+	}
+	if stale, err := readSetting(db, searchStaleSetting); err != nil || stale != "" {
+		t.Errorf("stale setting = %q, %v after rebuilding", stale, err)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"empire", []string{"empire"}},
+		{`"Holy Roman" Empire`, []string{"holy roman", "empire"}},
+		{"rome AND empire", []string{"rome", "empire"}},
+		{"pok* \"\"", []string{"pok"}},
+		{`  spaced   "out  phrase" `, []string{"spaced", "out phrase"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := searchTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("searchTerms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFTSQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"empire", `"empire"`},
+		{`"Holy Roman" Empire`, `"Holy Roman" "Empire"`},
+		{"rome AND empire", `"rome" "empire"`},
+		{"rome OR empire", `"rome" "OR" "empire"`},
+		{"AT&T", `"AT&T"`},
+		{"don't", `"don't"`},
+		{`"unbalanced`, `"unbalanced"`},
+		{`say "cheese`, `"say" "cheese"`},
+		{`a"b`, `"a""b"`},
+		{"pok*", `"pok"*`},
+		{"NEAR(a b)", `"NEAR(a" "b)"`},
+		{`"" * AND`, ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := ftsQuery(tt.query); got != tt.want {
+			t.Errorf("ftsQuery(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightTerms(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  string
+	}{
+		{"The Holy Roman Empire", []string{"empire"}, "The Holy Roman [Empire]"},
+		{"The Holy Roman Empire", []string{"roman", "holy roman"}, "The [Holy Roman] Empire"},
+		{"Rome, rome, ROME", []string{"rome"}, "[Rome], [rome], [ROME]"},
+		{"100% (a.b)", []string{"(a.b)"}, "100% [(a.b)]"},
+		{"nothing here", []string{"else"}, "nothing here"},
+	}
+	for _, tt := range tests {
+		if got := highlightTerms(tt.text, tt.terms, "[", "]"); got != tt.want {
+			t.Errorf("highlightTerms(%q, %q) = %q, want %q", tt.text, tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestLikePattern(t *testing.T) {
+	tests := []struct{ term, want string }{
+		{"rome", "%rome%"},
+		{"100%", `%100\%%`},
+		{"a_b", `%a\_b%`},
+		{`c:\d`, `%c:\\d%`},
+	}
+	for _, tt := range tests {
+		if got := likePattern(tt.term); got != tt.want {
+			t.Errorf("likePattern(%q) = %q, want %q", tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestScanClues(t *testing.T) {
+	db := openTestDatabase(t)
+	if err := migrateDatabase(db); err != nil {
+		t.Fatal(err)
+	}
+	games := []GameData{
+		{ID: 1, ShowNum: 100, AirDate: "2019-05-01", Rounds: []Round{{
+			Name:       roundJeopardy,
+			Categories: []Category{{Name: "HISTORY"}, {Name: "100% SCIENCE"}},
+			Clues: []Clue{
+				{Position: "J_1_1", Value: "$200", Text: "Voltaire said it was neither holy, nor Roman, nor an empire", CorrectResponse: "the Holy Roman Empire"},
+				{Position: "J_2_1", Value: "$200", Text: "H2O", CorrectResponse: "water"},
+			},
+		}}},
+		{ID: 2, ShowNum: 200, AirDate: "2020-05-01", Rounds: []Round{{
+			Name:       roundDoubleJeopardy,
+			Categories: []Category{{Name: "EMPIRES"}},
+			Clues:      []Clue{{Position: "DJ_1_1", Value: "$800", Text: "Its capital was Constantinople", CorrectResponse: "the Byzantine Empire"}},
+		}}},
+	}
+	if _, err := writeGameBatch(db, "35", games); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		filters searchFilters
+		want    []string
+	}{
+		{"word in response, newest first", "empire", searchFilters{}, []string{"2-DJ_1_1", "1-J_1_1"}},
+		{"phrase", `"holy roman"`, searchFilters{}, []string{"1-J_1_1"}},
+		{"every term must match", "empire constantinople", searchFilters{}, []string{"2-DJ_1_1"}},
+		{"category", "science", searchFilters{}, []string{"1-J_2_1"}},
+		{"wildcard is literal", "100%", searchFilters{}, []string{"1-J_2_1"}},
+		{"round filter", "empire", searchFilters{Round: "j"}, []string{"1-J_1_1"}},
+		{"value filter", "empire", searchFilters{MinValue: 500}, []string{"2-DJ_1_1"}},
+		{"date filter", "empire", searchFilters{To: "2019-12-31"}, []string{"1-J_1_1"}},
+		{"no match", "zebra", searchFilters{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filters.Limit = 10
+			results, err := scanClues(db, tt.query, tt.filters, "[", "]")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, result := range results {
+				got = append(got, result.ClueID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scanClues(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	results, err := scanClues(db, "water", searchFilters{Limit: 1}, "[", "]")
+	if err != nil || len(results) != 1 {
+		t.Fatalf("scanClues(water) = %v, %v", results, err)
+	}
+	if results[0].Response != "[water]" || results[0].Category != "100% SCIENCE" || results[0].Round != roundJeopardy || results[0].Value != 200 {
+		t.Errorf("scanClues(water) = %+v", results[0])
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tlegnard/answer-there/jarchive"
+)
+
+// The serve command exposes the read API in query.go as read-only JSON
+// endpoints:
+//
+//	GET /games?season=S&from=DATE&to=DATE
+//	GET /games/{game_id}
+//	GET /clues?game=ID&season=S&category=NAME&round=J&min_value=N&max_value=N&daily_double=1&triple_stumper=1&limit=N
+//	GET /players/{player_id}
+//	GET /search?q=QUERY&season=S&round=J&limit=N
+//	GET /stats
+
+// errBadRequest marks errors caused by the request rather than the server
+var errBadRequest = errors.New("bad request")
+
+// apiHandler serves one endpoint, returning the value to write as JSON
+type apiHandler func(r *http.Request) (interface{}, error)
+
+// ServeHTTP writes the handler's result, mapping errors to status codes
+func (handler apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	value, err := handler(r)
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, jarchive.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, errBadRequest):
+			status = http.StatusBadRequest
+		default:
+			log.Printf("%s %s: %v", r.Method, r.URL, err)
+		}
+		w.WriteHeader(status)
+		value = map[string]string{"error": err.Error()}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		log.Printf("%s %s: failed to write response: %v", r.Method, r.URL, err)
+	}
+}
+
+// queryInt reads an optional integer query parameter
+func queryInt(r *http.Request, name string) (int, error) {
+	text := r.URL.Query().Get(name)
+	if text == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number: %w", name, errBadRequest)
+	}
+	return value, nil
+}
+
+// queryBool reads an optional boolean query parameter such as daily_double=1
+func queryBool(r *http.Request, name string) (bool, error) {
+	text := r.URL.Query().Get(name)
+	if text == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(text)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false: %w", name, errBadRequest)
+	}
+	return value, nil
+}
+
+// newAPIMux routes the endpoints to the store
+func newAPIMux(store *sqliteStore) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.Handle("/games", apiHandler(func(r *http.Request) (interface{}, error) {
+		query := r.URL.Query()
+		return store.ListGames(jarchive.GameFilter{SeasonID: query.Get("season"), From: query.Get("from"), To: query.Get("to")})
+	}))
+
+	mux.Handle("/games/", apiHandler(func(r *http.Request) (interface{}, error) {
+		gameID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/games/"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid game ID: %w", errBadRequest)
+		}
+		return store.GetGame(gameID)
+	}))
+
+	mux.Handle("/clues", apiHandler(func(r *http.Request) (interface{}, error) {
+		query := r.URL.Query()
+		filter := jarchive.ClueFilter{SeasonID: query.Get("season"), Category: query.Get("category"), Round: query.Get("round")}
+		var err error
+		for name, target := range map[string]*int{
+			"game":      &filter.GameID,
+			"min_value": &filter.MinValue,
+			"max_value": &filter.MaxValue,
+			"limit":     &filter.Limit,
+		} {
+			if *target, err = queryInt(r, name); err != nil {
+				return nil, err
+			}
+		}
+		if filter.DailyDouble, err = queryBool(r, "daily_double"); err != nil {
+			return nil, err
+		}
+		if filter.TripleStumper, err = queryBool(r, "triple_stumper"); err != nil {
+			return nil, err
+		}
+		// Without any filter this would return every clue in the database
+		if filter.Limit == 0 {
+			filter.Limit = 100
+		}
+		return store.ListClues(filter)
+	}))
+
+	mux.Handle("/players/", apiHandler(func(r *http.Request) (interface{}, error) {
+		return store.GetPlayer(strings.TrimPrefix(r.URL.Path, "/players/"))
+	}))
+
+	mux.Handle("/search", apiHandler(func(r *http.Request) (interface{}, error) {
+		query := r.URL.Query()
+		if query.Get("q") == "" {
+			return nil, fmt.Errorf("missing q: %w", errBadRequest)
+		}
+		filters := searchFilters{Season: query.Get("season"), Round: query.Get("round")}
+		var err error
+		if filters.Limit, err = queryInt(r, "limit"); err != nil {
+			return nil, err
+		}
+		if filters.Limit == 0 {
+			filters.Limit = 20
+		}
+		return searchClues(store.db, query.Get("q"), filters, "<mark>", "</mark>")
+	}))
+
+	mux.Handle("/stats", apiHandler(func(r *http.Request) (interface{}, error) {
+		return databaseStats(store.db, true)
+	}))
+	return mux
+}
+
+// runServe implements the serve command
+//
+//	serve [-addr HOST:PORT]
+func runServe(ctx context.Context, store *sqliteStore, args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	flags.Parse(args)
+
+	server := &http.Server{Addr: *addr, Handler: newAPIMux(store)}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		// Let requests in progress finish before the database is closed
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down the server: %v", err)
+		}
+	}()
+
+	log.Printf("Serving the database on http://%s", *addr)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-stopped
+	log.Printf("Server stopped")
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// openTestStore opens a migrated store holding the games of each season
+func openTestStore(t *testing.T, seasons map[string][]GameData) *sqliteStore {
+	t.Helper()
+	store, err := newSQLiteStore(filepath.Join(t.TempDir(), "jeopardy.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	seasonIDs := make([]string, 0, len(seasons))
+	for seasonID := range seasons {
+		seasonIDs = append(seasonIDs, seasonID)
+	}
+	sort.Strings(seasonIDs)
+	for _, seasonID := range seasonIDs {
+		for _, game := range seasons[seasonID] {
+			if err := store.SaveGame(seasonID, game); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return store
+}
+
+func TestAPI(t *testing.T) {
+	// A board of more clues than the default limit
+	board := GameData{ID: 3, ShowNum: 8003, AirDate: "2019-05-03"}
+	round := Round{Name: roundJeopardy}
+	for column := 1; column <= 6; column++ {
+		round.Categories = append(round.Categories, Category{Name: fmt.Sprintf("CATEGORY %d", column)})
+		for row := 1; row <= 20; row++ {
+			position := fmt.Sprintf("J_%d_%d", column, row)
+			round.Clues = append(round.Clues, Clue{Position: position, Value: "$200", Text: position, CorrectResponse: "a response"})
+		}
+	}
+	board.Rounds = []Round{round}
+	store := openTestStore(t, map[string][]GameData{"35": append([]GameData{board}, readAPIGames...)})
+	server := httptest.NewServer(newAPIMux(store))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		// check inspects the decoded body of a successful response
+		check func(t *testing.T, body []byte)
+	}{
+		{"games", "GET", "/games?from=2019-05-02", http.StatusOK, func(t *testing.T, body []byte) {
+			var games []json.RawMessage
+			decode(t, body, &games)
+			if len(games) != 2 {
+				t.Errorf("listed %d games, want 2", len(games))
+			}
+		}},
+		{"game", "GET", "/games/1", http.StatusOK, func(t *testing.T, body []byte) {
+			var game GameData
+			decode(t, body, &game)
+			if game.ID != 1 || game.ShowNum != 8001 || len(game.Rounds) != 2 {
+				t.Errorf("game = %+v", game)
+			}
+		}},
+		{"missing game", "GET", "/games/99", http.StatusNotFound, nil},
+		{"game ID not a number", "GET", "/games/first", http.StatusBadRequest, nil},
+		{"default clue limit", "GET", "/clues", http.StatusOK, func(t *testing.T, body []byte) {
+			var clues []json.RawMessage
+			decode(t, body, &clues)
+			if len(clues) != 100 {
+				t.Errorf("listed %d clues, want the default limit of 100", len(clues))
+			}
+		}},
+		{"clue filters", "GET", "/clues?game=1&daily_double=1&limit=5", http.StatusOK, func(t *testing.T, body []byte) {
+			var clues []struct {
+				GameID int  `json:"game_id"`
+				Clue   Clue `json:"clue"`
+			}
+			decode(t, body, &clues)
+			if len(clues) != 1 || clues[0].GameID != 1 || clues[0].Clue.Position != "J_2_1" {
+				t.Errorf("clues = %s, want game 1 J_2_1", body)
+			}
+		}},
+		{"clue limit not a number", "GET", "/clues?limit=ten", http.StatusBadRequest, nil},
+		{"clue flag not a boolean", "GET", "/clues?triple_stumper=maybe", http.StatusBadRequest, nil},
+		{"player", "GET", "/players/20", http.StatusOK, func(t *testing.T, body []byte) {
+			if !strings.Contains(string(body), "Bob Jones") {
+				t.Errorf("player = %s", body)
+			}
+		}},
+		{"missing player", "GET", "/players/99", http.StatusNotFound, nil},
+		{"search without a query", "GET", "/search", http.StatusBadRequest, nil},
+		{"stats", "GET", "/stats", http.StatusOK, func(t *testing.T, body []byte) {
+			var stats dbStats
+			decode(t, body, &stats)
+			if stats.Games != 3 || stats.Clues != 123 || len(stats.BySeason) != 1 {
+				t.Errorf("stats = %+v", stats)
+			}
+		}},
+		{"read only", "POST", "/games", http.StatusMethodNotAllowed, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+			var body json.RawMessage
+			if err := json.NewDecoder(response.Body).Decode(&body); err != nil && tt.wantStatus != http.StatusMethodNotAllowed {
+				t.Fatalf("%s %s: reading body: %v", tt.method, tt.path, err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("%s %s = %d %s, want %d", tt.method, tt.path, response.StatusCode, body, tt.wantStatus)
+			}
+			if tt.check != nil {
+				tt.check(t, body)
+			} else if tt.wantStatus != http.StatusMethodNotAllowed {
+				var apiError struct {
+					Error string `json:"error"`
+				}
+				if decode(t, body, &apiError); apiError.Error == "" {
+					t.Errorf("%s %s error body = %s", tt.method, tt.path, body)
+				}
+			}
+		})
+	}
+}
+
+// decode unmarshals a response body
+func decode(t *testing.T, body []byte, value interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(body, value); err != nil {
+		t.Fatalf("decoding %s: %v", body, err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// dbStats summarizes what the database holds
+type dbStats struct {
+	Seasons        int           `json:"seasons"`
+	Games          int           `json:"games"`
+	FirstAirDate   string        `json:"first_air_date"`
+	LastAirDate    string        `json:"last_air_date"`
+	Clues          int           `json:"clues"`
+	RevealedClues  int           `json:"revealed_clues"`
+	DailyDoubles   int           `json:"daily_doubles"`
+	TripleStumpers int           `json:"triple_stumpers"`
+	Categories     int           `json:"categories"`
+	Players        int           `json:"players"`
+	BySeason       []seasonStats `json:"by_season,omitempty"`
+}
+
+// seasonStats counts the games and clues stored for one season
+type seasonStats struct {
+	SeasonID     string `json:"season_id"`
+	Games        int    `json:"games"`
+	Clues        int    `json:"clues"`
+	FirstAirDate string `json:"first_air_date"`
+	LastAirDate  string `json:"last_air_date"`
+}
+
+// databaseStats counts the contents of the database, optionally per season
+func databaseStats(db *sql.DB, bySeason bool) (dbStats, error) {
+	var stats dbStats
+	err := db.QueryRow(`
+		SELECT COUNT(DISTINCT season_id), COUNT(*), COALESCE(MIN(air_date), ''), COALESCE(MAX(air_date), '')
+		FROM games;`).Scan(&stats.Seasons, &stats.Games, &stats.FirstAirDate, &stats.LastAirDate)
+	if err != nil {
+		return stats, err
+	}
+	err = db.QueryRow(`
+		SELECT COUNT(*),
+			COALESCE(SUM(COALESCE(text, '') != ''), 0),
+			COALESCE(SUM(daily_double), 0),
+			COALESCE(SUM(triple_stumper), 0)
+		FROM clues;`).Scan(&stats.Clues, &stats.RevealedClues, &stats.DailyDoubles, &stats.TripleStumpers)
+	if err != nil {
+		return stats, err
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM categories;`).Scan(&stats.Categories); err != nil {
+		return stats, err
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM players;`).Scan(&stats.Players); err != nil {
+		return stats, err
+	}
+	if !bySeason {
+		return stats, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT g.season_id, COUNT(DISTINCT g.game_id), COUNT(c.clue_id),
+			COALESCE(MIN(g.air_date), ''), COALESCE(MAX(g.air_date), '')
+		FROM games g
+		LEFT JOIN clues c ON c.game_id = g.game_id
+		GROUP BY g.season_id
+		ORDER BY MIN(g.air_date), g.season_id;`)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var season seasonStats
+		if err := rows.Scan(&season.SeasonID, &season.Games, &season.Clues, &season.FirstAirDate, &season.LastAirDate); err != nil {
+			return stats, err
+		}
+		stats.BySeason = append(stats.BySeason, season)
+	}
+	return stats, rows.Err()
+}
+
+// percent formats part as a percentage of total
+func percent(part, total int) string {
+	if total == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(part)/float64(total))
+}
+
+// runStats implements the stats command
+//
+//	stats [-seasons] [-json]
+func runStats(db *sql.DB, args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	bySeason := flags.Bool("seasons", false, "also break the counts down by season")
+	asJSON := flags.Bool("json", false, "print the statistics as JSON")
+	flags.Parse(args)
+
+	stats, err := databaseStats(db, *bySeason)
+	if err != nil {
+		log.Fatalf("Failed to read statistics: %v", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(stats); err != nil {
+			log.Fatalf("Failed to write statistics: %v", err)
+		}
+		return
+	}
+
+	fmt.Printf("Seasons:          %d\n", stats.Seasons)
+	fmt.Printf("Games:            %d (%s to %s)\n", stats.Games, stats.FirstAirDate, stats.LastAirDate)
+	fmt.Printf("Categories:       %d\n", stats.Categories)
+	fmt.Printf("Clues:            %d (%s revealed)\n", stats.Clues, percent(stats.RevealedClues, stats.Clues))
+	fmt.Printf("Daily Doubles:    %d\n", stats.DailyDoubles)
+	fmt.Printf("Triple stumpers:  %d (%s of revealed clues)\n", stats.TripleStumpers, percent(stats.TripleStumpers, stats.RevealedClues))
+	fmt.Printf("Players:          %d\n", stats.Players)
+
+	if len(stats.BySeason) > 0 {
+		fmt.Printf("\n%-16s %6s %7s  %s\n", "Season", "Games", "Clues", "Aired")
+		for _, season := range stats.BySeason {
+			fmt.Printf("%-16s %6d %7d  %s to %s\n", season.SeasonID, season.Games, season.Clues, season.FirstAirDate, season.LastAirDate)
+		}
+	}
+}
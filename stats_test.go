@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDatabaseStats(t *testing.T) {
+	empty := openTestStore(t, nil)
+	stats, err := databaseStats(empty.db, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(stats, dbStats{}) {
+		t.Errorf("databaseStats of an empty database = %+v", stats)
+	}
+
+	later := GameData{ID: 3, ShowNum: 9001, AirDate: "2020-05-01", Rounds: []Round{{
+		Name:       roundJeopardy,
+		Categories: []Category{{Name: "HIDDEN"}},
+		Clues:      []Clue{{Position: "J_1_1", Value: "$200"}},
+	}}}
+	store := openTestStore(t, map[string][]GameData{"35": readAPIGames, "36": {later}})
+
+	stats, err = databaseStats(store.db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := dbStats{
+		Seasons:        2,
+		Games:          3,
+		FirstAirDate:   "2019-05-01",
+		LastAirDate:    "2020-05-01",
+		Clues:          4,
+		RevealedClues:  3,
+		DailyDoubles:   1,
+		TripleStumpers: 1,
+		Categories:     4,
+		Players:        2,
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("databaseStats = %+v, want %+v", stats, want)
+	}
+
+	stats, err = databaseStats(store.db, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want.BySeason = []seasonStats{
+		{SeasonID: "35", Games: 2, Clues: 3, FirstAirDate: "2019-05-01", LastAirDate: "2019-05-02"},
+		{SeasonID: "36", Games: 1, Clues: 1, FirstAirDate: "2020-05-01", LastAirDate: "2020-05-01"},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("databaseStats by season = %+v, want %+v", stats, want)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	for _, tt := range []struct {
+		part, total int
+		want        string
+	}{{0, 0, "0%"}, {1, 3, "33.3%"}, {4, 4, "100.0%"}} {
+		if got := percent(tt.part, tt.total); got != tt.want {
+			t.Errorf("percent(%d, %d) = %q, want %q", tt.part, tt.total, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/tlegnard/answer-there/jarchive"
+)
+
+// Store is where parsed games are written
+type Store interface {
+	// SaveGame writes one game, replacing any rows already stored for it
+	SaveGame(seasonID string, game GameData) error
+	// SaveSeason writes every game in a season
+	SaveSeason(season SeasonData) error
+	// SavePlayers records players independently of any game
+	SavePlayers(players []Contestant) error
+	Close() error
+}
+
+// PipelineStore is the Store that scrape, retry-failed and reparse write
+// through, which also keeps their progress. The pipeline only talks to this
+// interface so the SQLite backend can be swapped for memoryStore.
+type PipelineStore interface {
+	Store
+	// ParserVersions returns the parser version each stored game was written with
+	ParserVersions() (map[int]int, error)
+	// RecordFailures records games that could not be scraped, counting the attempts
+	RecordFailures(failures []*gameError) error
+	// ClearFailures forgets the failures of games that have since been stored
+	ClearFailures(gameIDs []int) error
+	// Failures lists the games that failed and have not been stored since
+	Failures() ([]GameFailure, error)
+	// CompleteSeason records that every game a season lists is stored
+	CompleteSeason(seasonID string, games int) error
+	// CompletedSeasons returns the seasons recorded as complete
+	CompletedSeasons() (map[string]bool, error)
+	// Catalog returns the games each season page listed when it was last fetched
+	Catalog() (map[string][]catalogGame, error)
+	// SaveCatalogSeason replaces the catalogued games of a season
+	SaveCatalogSeason(seasonID string, games []catalogGame) error
+	// StartRun adds a run to the runs ledger and returns its ID
+	StartRun(run Run) (int64, error)
+	// SaveRun updates a run in the runs ledger
+	SaveRun(run Run) error
+}
+
+// sqliteStore is the PipelineStore backed by jeopardy.db. It also serves the
+// read API of the jarchive package. Commands that query the dataset in SQL,
+// such as serve, quiz, export, import and doctor, take a *sqliteStore rather
+// than a Store since they have no other backend.
+type sqliteStore struct {
+	*jarchive.DB
+	db *sql.DB
+}
+
+// newSQLiteStore opens the database and brings its schema up to date
+func newSQLiteStore(dbName string) (*sqliteStore, error) {
+	db, err := openDatabase(dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %v", err)
+	}
+	if err := migrateDatabase(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %v", err)
+	}
+	if err := ensureSearchIndex(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create search index: %v", err)
+	}
+	return &sqliteStore{DB: jarchive.New(db), db: db}, nil
+}
+
+func (s *sqliteStore) SaveGame(seasonID string, game GameData) error {
+	gameErrors, err := writeGameBatch(s.db, seasonID, []GameData{game})
+	if err != nil {
+		return err
+	}
+	if len(gameErrors) > 0 {
+		return gameErrors[0]
+	}
+	return nil
+}
+
+func (s *sqliteStore) SaveSeason(season SeasonData) error {
+	return writeSeason(s.db, season)
+}
+
+func (s *sqliteStore) SavePlayers(players []Contestant) error {
+	return writePlayers(s.db, players)
+}
+
+func (s *sqliteStore) ParserVersions() (map[int]int, error) {
+	return readParserVersions(s.db)
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
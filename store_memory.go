@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryStore is a PipelineStore that keeps everything in memory, for tests and dry runs
+type memoryStore struct {
+	mu      sync.Mutex
+	games   map[int]GameData
+	seasons map[int]string // game ID to season ID
+	players map[string]Contestant
+	failed  map[int]GameFailure
+	done    map[string]int // completed season ID to its number of games
+	catalog map[string][]catalogGame
+	runs    []Run
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		games:   make(map[int]GameData),
+		seasons: make(map[int]string),
+		players: make(map[string]Contestant),
+		failed:  make(map[int]GameFailure),
+		done:    make(map[string]int),
+		catalog: make(map[string][]catalogGame),
+	}
+}
+
+func (s *memoryStore) SaveGame(seasonID string, game GameData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.games[game.ID] = game
+	s.seasons[game.ID] = seasonID
+	for _, contestant := range game.Contestants {
+		if contestant.PlayerID != "" {
+			s.players[contestant.PlayerID] = contestant
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) SaveSeason(season SeasonData) error {
+	for _, game := range season.Games {
+		if err := s.SaveGame(season.ID, game); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) SavePlayers(players []Contestant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, player := range players {
+		if player.PlayerID != "" {
+			s.players[player.PlayerID] = player
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) ParserVersions() (map[int]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := make(map[int]int, len(s.games))
+	for id, game := range s.games {
+		versions[id] = game.ParserVersion
+	}
+	return versions, nil
+}
+
+func (s *memoryStore) RecordFailures(failures []*gameError) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, failure := range failures {
+		recorded, ok := s.failed[failure.GameID]
+		if !ok {
+			recorded.FirstFailedAt = now
+		}
+		recorded.GameID = failure.GameID
+		recorded.SeasonID = failure.SeasonID
+		recorded.Stage = failure.Stage
+		recorded.Reason = failure.Err.Error()
+		recorded.Attempts++
+		recorded.LastFailedAt = now
+		s.failed[failure.GameID] = recorded
+	}
+	return nil
+}
+
+func (s *memoryStore) ClearFailures(gameIDs []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, gameID := range gameIDs {
+		delete(s.failed, gameID)
+	}
+	return nil
+}
+
+func (s *memoryStore) Failures() ([]GameFailure, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures := make([]GameFailure, 0, len(s.failed))
+	for _, failure := range s.failed {
+		failures = append(failures, failure)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].SeasonID != failures[j].SeasonID {
+			return failures[i].SeasonID < failures[j].SeasonID
+		}
+		return failures[i].GameID < failures[j].GameID
+	})
+	return failures, nil
+}
+
+func (s *memoryStore) CompleteSeason(seasonID string, games int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.done[seasonID] = games
+	return nil
+}
+
+func (s *memoryStore) CompletedSeasons() (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	completed := make(map[string]bool, len(s.done))
+	for seasonID := range s.done {
+		completed[seasonID] = true
+	}
+	return completed, nil
+}
+
+func (s *memoryStore) Catalog() (map[string][]catalogGame, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	catalog := make(map[string][]catalogGame, len(s.catalog))
+	for seasonID, games := range s.catalog {
+		catalog[seasonID] = append([]catalogGame(nil), games...)
+	}
+	return catalog, nil
+}
+
+func (s *memoryStore) SaveCatalogSeason(seasonID string, games []catalogGame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.catalog[seasonID] = append([]catalogGame(nil), games...)
+	return nil
+}
+
+func (s *memoryStore) StartRun(run Run) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run.RunID = int64(len(s.runs) + 1)
+	s.runs = append(s.runs, run)
+	return run.RunID, nil
+}
+
+func (s *memoryStore) SaveRun(run Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if run.RunID < 1 || run.RunID > int64(len(s.runs)) {
+		return fmt.Errorf("no run %d", run.RunID)
+	}
+	s.runs[run.RunID-1] = run
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+// Runs returns the recorded runs, oldest first
+func (s *memoryStore) Runs() []Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Run(nil), s.runs...)
+}
+
+// Season returns the stored games of a season ordered by game ID
+func (s *memoryStore) Season(seasonID string) SeasonData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	season := SeasonData{ID: seasonID}
+	for id, game := range s.games {
+		if s.seasons[id] == seasonID {
+			season.Games = append(season.Games, game)
+		}
+	}
+	sort.Slice(season.Games, func(i, j int) bool {
+		return season.Games[i].ID < season.Games[j].ID
+	})
+	return season
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tlegnard/answer-there/jarchive"
+)
+
+// Board dimensions for the Jeopardy! and Double Jeopardy! rounds
+const (
+	expectedCategories  = 6
+	expectedCluesPerCat = 5
+)
+
+// Warning codes recorded with each ParseWarning
+const (
+	warnShowNumber      = "show_number"
+	warnOrderNumber     = "order_number"
+	warnRoundCount      = "round_count"
+	warnBoardSize       = "board_size"
+	warnOrderSequence   = "order_sequence"
+	warnContestantCount = "contestant_count"
+	warnWager           = "wager"
+	warnScore           = "score"
+	warnScoreMismatch   = "score_mismatch"
+)
+
+// ParseWarning describes a problem found while parsing a game page that
+// did not prevent the game from being parsed.
+type ParseWarning = jarchive.ParseWarning
+
+func newParseWarning(code string, format string, args ...interface{}) ParseWarning {
+	return ParseWarning{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// validateGame checks a parsed game against the structure expected for its
+// era and format. Fields recorded as unavailable are not checked.
+func validateGame(game GameData) []ParseWarning {
+	var warnings []ParseWarning
+	format := formatFor(game.Format)
+
+	if game.IsUnavailable(fieldShowNumber) && game.Era == eraModern && format.Name == formatStandard.Name {
+		warnings = append(warnings, newParseWarning(warnShowNumber, "no show number found"))
+	}
+
+	mainRounds := 0
+	for _, round := range game.Rounds {
+		if round.Name != roundTiebreaker {
+			mainRounds++
+		}
+	}
+	if format.Rounds > 0 && mainRounds != format.Rounds {
+		warnings = append(warnings, newParseWarning(warnRoundCount, "expected %d rounds, found %d", format.Rounds, mainRounds))
+	}
+
+	if format.Contestants > 0 && len(game.Contestants) != format.Contestants {
+		warnings = append(warnings, newParseWarning(warnContestantCount, "expected %d contestants, found %d", format.Contestants, len(game.Contestants)))
+	}
+
+	for _, round := range game.Rounds {
+		if round.Name != roundJeopardy && round.Name != roundDoubleJeopardy {
+			continue
+		}
+
+		if !game.IsUnavailable(fieldFullBoard) &&
+			(len(round.Categories) != expectedCategories || len(round.Clues) != expectedCategories*expectedCluesPerCat) {
+			warnings = append(warnings, newParseWarning(warnBoardSize, "%s: expected %dx%d board, found %d categories and %d clues",
+				round.Name, expectedCategories, expectedCluesPerCat, len(round.Categories), len(round.Clues)))
+		}
+
+		if !game.IsUnavailable(fieldOrderNumbers) {
+			warnings = append(warnings, validateOrderNumbers(round)...)
+		}
+	}
+
+	return warnings
+}
+
+// validateOrderNumbers checks that the revealed clues in a round are numbered 1..N with no gaps or repeats
+func validateOrderNumbers(round Round) []ParseWarning {
+	var warnings []ParseWarning
+
+	var orderNumbers []int
+	for _, clue := range round.Clues {
+		if clue.OrderNumber > 0 {
+			orderNumbers = append(orderNumbers, clue.OrderNumber)
+		}
+	}
+	sort.Ints(orderNumbers)
+
+	for i, orderNumber := range orderNumbers {
+		if orderNumber != i+1 {
+			warnings = append(warnings, newParseWarning(warnOrderSequence, "%s: order numbers are not 1..%d, expected %d but found %d",
+				round.Name, len(orderNumbers), i+1, orderNumber))
+			break
+		}
+	}
+
+	return warnings
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/tlegnard/answer-there/jarchive"
+)
+
+// pageClue is a clue cell of a fixture game page. A clue with no text is
+// left unrevealed.
+type pageClue struct {
+	Text  string
+	Value string // "$200", or "DD: $1,000" for a Daily Double
+	Order string
+	Right []string
+	Wrong []string
+	// Stumper adds the Triple Stumper cell shown when nobody was right
+	Stumper bool
+}
+
+// pageFinalResponse is a contestant's Final Jeopardy response on a fixture page
+type pageFinalResponse struct {
+	Nickname string
+	Correct  bool
+	Text     string
+	Wager    string
+}
+
+// pageRound is a round of a fixture game page. Regular rounds list their
+// clues in board order, row by row.
+type pageRound struct {
+	Code       string // J, DJ, FJ or TB
+	Categories int
+	Clues      []pageClue
+	Final      []pageFinalResponse
+}
+
+// pageScores is a score table shown under a heading such as
+// "Scores at the end of the Jeopardy! Round:"
+type pageScores struct {
+	Heading string
+	Scores  []ContestantScore
+}
+
+// fixturePage is a game page in the layout J-Archive uses for modern games
+type fixturePage struct {
+	Title       string
+	Contestants []string
+	Rounds      []pageRound
+	Scores      []pageScores
+}
+
+func (p fixturePage) String() string {
+	var page strings.Builder
+	fmt.Fprintf(&page, `<html><head><title>J! Archive - %s</title></head><body>`, p.Title)
+	fmt.Fprintf(&page, `<div id="game_title"><h1>%s</h1></div><div id="game_comments"></div>`, p.Title)
+	page.WriteString(`<table id="contestants_table"><tr><td>`)
+	for i, name := range p.Contestants {
+		fmt.Fprintf(&page, `<p class="contestants"><a href="showplayer.php?player_id=%d">%s</a>, a teacher</p>`, i+1, name)
+	}
+	page.WriteString(`</td></tr></table>`)
+
+	for _, round := range p.Rounds {
+		switch round.Code {
+		case "J", "DJ":
+			div := "jeopardy_round"
+			if round.Code == "DJ" {
+				div = "double_jeopardy_round"
+			}
+			fmt.Fprintf(&page, `<div id="%s"><table class="round"><tr>`, div)
+			for c := 1; c <= round.Categories; c++ {
+				fmt.Fprintf(&page, `<td class="category"><table><tr><td class="category_name">CATEGORY %d</td></tr></table></td>`, c)
+			}
+			page.WriteString(`</tr>`)
+			for i, clue := range round.Clues {
+				if i%round.Categories == 0 {
+					page.WriteString(`<tr>`)
+				}
+				position := fmt.Sprintf("%s_%d_%d", round.Code, i%round.Categories+1, i/round.Categories+1)
+				page.WriteString(clue.html(position))
+				if i%round.Categories == round.Categories-1 || i == len(round.Clues)-1 {
+					page.WriteString(`</tr>`)
+				}
+			}
+			page.WriteString(`</table></div>`)
+		default:
+			fmt.Fprintf(&page, `<div id="final_jeopardy_round"><table class="final_round">`+
+				`<tr><td class="category"><table><tr><td class="category_name">FINAL</td></tr></table></td></tr>`+
+				`<tr><td class="clue"><table><tr><td id="clue_%[1]s" class="clue_text">The final clue</td></tr>`+
+				`<tr><td id="clue_%[1]s_r" class="clue_text"><em class="correct_response">the answer</em><table>`, round.Code)
+			for _, response := range round.Final {
+				class := "wrong"
+				if response.Correct {
+					class = "right"
+				}
+				fmt.Fprintf(&page, `<tr><td class="%s">%s</td><td>%s</td></tr><tr><td>%s</td></tr>`,
+					class, response.Nickname, response.Text, response.Wager)
+			}
+			page.WriteString(`</table></td></tr></table></td></tr></table></div>`)
+		}
+	}
+
+	for _, scores := range p.Scores {
+		fmt.Fprintf(&page, `<h3>%s</h3><table><tr>`, scores.Heading)
+		for _, score := range scores.Scores {
+			fmt.Fprintf(&page, `<td class="score_player_nickname">%s</td>`, score.Nickname)
+		}
+		page.WriteString(`</tr><tr>`)
+		for _, score := range scores.Scores {
+			class := "score_positive"
+			if score.Score < 0 {
+				class = "score_negative"
+			}
+			fmt.Fprintf(&page, `<td class="%s">%s</td>`, class, jarchive.FormatMoney(score.Score))
+		}
+		page.WriteString(`</tr></table>`)
+	}
+	page.WriteString(`</body></html>`)
+	return page.String()
+}
+
+func (c pageClue) html(position string) string {
+	if c.Text == "" {
+		return `<td class="clue"></td>`
+	}
+	value := fmt.Sprintf(`<td class="clue_value">%s</td>`, c.Value)
+	if strings.HasPrefix(c.Value, "DD:") {
+		value = fmt.Sprintf(`<td class="clue_value_daily_double">%s</td>`, c.Value)
+	}
+	var responses strings.Builder
+	for _, name := range c.Wrong {
+		fmt.Fprintf(&responses, `<td class="wrong">%s</td>`, name)
+	}
+	for _, name := range c.Right {
+		fmt.Fprintf(&responses, `<td class="right">%s</td>`, name)
+	}
+	if c.Stumper {
+		responses.WriteString(`<td class="wrong">Triple Stumper</td>`)
+	}
+	return fmt.Sprintf(`<td class="clue"><table>`+
+		`<tr><td class="clue_header"><table><tr>%s<td class="clue_order_number"><a>%s</a></td></tr></table></td></tr>`+
+		`<tr><td id="clue_%[3]s" class="clue_text">%[4]s</td></tr>`+
+		`<tr><td id="clue_%[3]s_r" class="clue_text"><em class="correct_response">answer</em><table><tr>%[5]s</tr></table></td></tr>`+
+		`</table></td>`, value, c.Order, position, c.Text, responses.String())
+}
+
+// fullBoard returns the 30 clues of a regular round, numbered in board order
+// and worth base per row, with no responses
+func fullBoard(base int) []pageClue {
+	var clues []pageClue
+	for row := 1; row <= expectedCluesPerCat; row++ {
+		for col := 1; col <= expectedCategories; col++ {
+			clues = append(clues, pageClue{
+				Text:  fmt.Sprintf("Clue %d-%d", col, row),
+				Value: jarchive.FormatMoney(base * row),
+				Order: fmt.Sprint(len(clues) + 1),
+			})
+		}
+	}
+	return clues
+}
+
+// standardPage is a complete modern game page that parses without warnings
+func standardPage() fixturePage {
+	return fixturePage{
+		Title:       "Show #8000, aired 2019-05-01",
+		Contestants: []string{"Alice Smith", "Bob Jones", "Carol White"},
+		Rounds: []pageRound{
+			{Code: "J", Categories: expectedCategories, Clues: fullBoard(200)},
+			{Code: "DJ", Categories: expectedCategories, Clues: fullBoard(400)},
+			{Code: "FJ"},
+		},
+	}
+}
+
+// warningCodes returns the sorted codes of warnings
+func warningCodes(warnings []ParseWarning) []string {
+	var codes []string
+	for _, warning := range warnings {
+		codes = append(codes, warning.Code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func TestParseGameTableDataWarnings(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(page *fixturePage)
+		want   []string
+	}{
+		{"clean page", func(page *fixturePage) {}, nil},
+		{"duplicate order number", func(page *fixturePage) {
+			page.Rounds[0].Clues[4].Order = "3"
+		}, []string{warnOrderSequence}},
+		{"missing order number", func(page *fixturePage) {
+			page.Rounds[1].Clues[0].Order = ""
+		}, []string{warnOrderSequence}},
+		{"unparsable order number", func(page *fixturePage) {
+			page.Rounds[0].Clues[29].Order = "thirty"
+		}, []string{warnOrderNumber}},
+		{"missing category", func(page *fixturePage) {
+			page.Rounds[0].Categories = 5
+			page.Rounds[0].Clues = page.Rounds[0].Clues[:25]
+		}, []string{warnBoardSize}},
+		{"missing row", func(page *fixturePage) {
+			page.Rounds[1].Clues = page.Rounds[1].Clues[:24]
+		}, []string{warnBoardSize}},
+		{"unparsable Daily Double wager", func(page *fixturePage) {
+			page.Rounds[0].Clues[7].Value = "DD: $l,000"
+		}, []string{warnWager}},
+		{"unparsable clue value", func(page *fixturePage) {
+			page.Rounds[0].Clues[0].Value = "$2OO"
+			page.Rounds[0].Clues[0].Right = []string{"Alice"}
+		}, []string{warnScore}},
+		{"unparsable Final Jeopardy wager", func(page *fixturePage) {
+			page.Rounds[2].Final = []pageFinalResponse{{Nickname: "Alice", Correct: true, Text: "answer", Wager: "all of it"}}
+		}, []string{warnWager}},
+		{"missing Final Jeopardy", func(page *fixturePage) {
+			page.Rounds = page.Rounds[:2]
+		}, []string{warnRoundCount}},
+		{"missing contestant", func(page *fixturePage) {
+			page.Contestants = page.Contestants[:2]
+		}, []string{warnContestantCount}},
+		{"missing show number", func(page *fixturePage) {
+			page.Title = "aired 2019-05-01"
+		}, []string{warnShowNumber}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := standardPage()
+			tt.modify(&page)
+			game, warnings, err := parseGameTableData(page.String())
+			if err != nil {
+				t.Fatalf("parseGameTableData: %v", err)
+			}
+			if game.Era != eraModern {
+				t.Errorf("era = %s, want %s", game.Era, eraModern)
+			}
+			if got := warningCodes(warnings); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("warnings = %v, want codes %v", warnings, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGameTableDataNoRounds(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+	}{
+		{"empty page", ""},
+		{"no round tables", fixturePage{Title: "Show #8000, aired 2019-05-01", Contestants: []string{"Alice Smith"}}.String()},
+		{"error page", "<html><body><p>ERROR: No game 99999 in database.</p></body></html>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := parseGameTableData(tt.page); err == nil {
+				t.Error("parseGameTableData succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestValidateOrderNumbers(t *testing.T) {
+	round := func(orders ...int) Round {
+		r := Round{Name: roundJeopardy}
+		for _, order := range orders {
+			r.Clues = append(r.Clues, Clue{OrderNumber: order})
+		}
+		return r
+	}
+	tests := []struct {
+		name  string
+		round Round
+		want  []string
+	}{
+		{"in sequence", round(3, 1, 2), nil},
+		{"unrevealed clues skipped", round(1, 0, 2, 0), nil},
+		{"empty round", round(), nil},
+		{"repeat", round(1, 2, 2), []string{warnOrderSequence}},
+		{"gap", round(1, 2, 4), []string{warnOrderSequence}},
+		{"not starting at one", round(2, 3), []string{warnOrderSequence}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := warningCodes(validateOrderNumbers(tt.round)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateOrderNumbers = %v, want codes %v", got, tt.want)
+			}
+		})
+	}
+}